@@ -1,29 +1,52 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"time"
 
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/leaderelection"
+	"github.com/SAP/stewardci-core/pkg/logging"
 	"github.com/SAP/stewardci-core/pkg/metrics"
 	"github.com/SAP/stewardci-core/pkg/runctl"
+	"github.com/SAP/stewardci-core/pkg/server"
+	"github.com/SAP/stewardci-core/pkg/sharding"
 	"github.com/SAP/stewardci-core/pkg/signals"
+	"github.com/SAP/stewardci-core/pkg/tracing"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 var kubeconfig string
+var listenAddress string
+var loggingConfig = logging.NewConfig()
+var tracingConfig = tracing.NewConfig()
+var scopeConfig = k8s.NewScopeConfig()
+var configSource = controllerconfig.NewSource("steward-run-controller")
+var leaderElectionConfig = leaderelection.NewConfig("steward-run-controller")
+var shardingConfig = sharding.NewConfig("steward-run-controller")
 
-// Time to wait until the next resync takes place.
+// Default controller configuration. The resync period is the time to wait
+// until the next resync takes place.
 // Resync is only required if events got lost or if the controller restarted (and missed events).
-const resyncPeriod = 30 * time.Second
+var controllerConfig = controllerconfig.NewConfig(30 * time.Second)
 
 func init() {
-	log.SetFlags(log.Ldate | log.Ltime | log.LUTC | log.Lshortfile)
-
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "path to Kubernetes config file")
+	flag.StringVar(&listenAddress, "listen-address", server.DefaultAddress, "address of the metrics and health endpoints")
+	leaderElectionConfig.AddFlags(flag.CommandLine)
+	shardingConfig.AddFlags(flag.CommandLine)
+	loggingConfig.AddFlags(flag.CommandLine)
+	tracingConfig.AddFlags(flag.CommandLine)
+	configSource.AddFlags(flag.CommandLine)
+	scopeConfig.AddFlags(flag.CommandLine)
+	controllerConfig.AddFlags(flag.CommandLine)
+	controllerConfig.AddRunFlags(flag.CommandLine)
 	flag.Parse()
 }
 
@@ -31,43 +54,121 @@ func init() {
 //TODO: Rename "/cmd/controller" folder to "pipeline_run"
 
 func main() {
+	loggers, err := logging.New(loggingConfig)
+	if err != nil {
+		log.Fatalf("Error configuring logging: %s", err.Error())
+	}
+	logger := loggers.Component(logging.ComponentRunController)
+	defer logger.Sync()
+
+	shutdownTracing, err := tracing.Setup(tracingConfig, "steward-run-controller", logger)
+	if err != nil {
+		logger.Fatalw("Error setting up tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warnw("Error flushing traces", "error", err)
+		}
+	}()
+
 	// creates the in-cluster config
 	var config *rest.Config
-	var err error
 	if kubeconfig == "" {
-		log.Printf("In cluster")
+		logger.Info("In cluster")
 		config, err = rest.InClusterConfig()
 		if err != nil {
-			log.Printf("Hint: You can use parameter '-kubeconfig' for local testing. See --help")
-			panic(err.Error())
+			logger.Info("Hint: You can use parameter '-kubeconfig' for local testing. See --help")
+			logger.Fatalw("Error loading in-cluster config", "error", err)
 		}
 	} else {
-		log.Printf("Outside cluster")
+		logger.Info("Outside cluster")
 		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 		if err != nil {
-			panic(err.Error())
+			logger.Fatalw("Error loading kubeconfig", "error", err)
 		}
 	}
-	log.Printf("Create Factory (resync period: %s)", resyncPeriod.String())
-	factory := k8s.NewClientFactory(config, resyncPeriod)
+	logger.Info("Load configuration")
+	kubernetesClientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		logger.Fatalw("Error creating Kubernetes client", "error", err)
+	}
+	configStore := controllerconfig.NewStore(kubernetesClientset.CoreV1(), configSource, controllerConfig,
+		loggers.Component(logging.ComponentConfig))
+	if err = configStore.Load(); err != nil {
+		logger.Fatalw("Error loading configuration", "error", err)
+	}
+	startConfig := configStore.Get()
+
+	logger.Infow("Create Factory", "resyncPeriod", startConfig.ResyncPeriod.String())
+	scope, err := k8s.NewScope(scopeConfig, kubernetesClientset.CoreV1(), startConfig.ResyncPeriod)
+	if err != nil {
+		logger.Fatalw("Error creating scope", "error", err)
+	}
+	factory, err := k8s.NewClientFactory(config, startConfig.ResyncPeriod, scope)
+	if err != nil {
+		logger.Fatalw("Error creating client factory", "error", err)
+	}
 
-	log.Printf("Provide metrics")
+	logger.Info("Provide metrics")
+	srv := server.NewServer(listenAddress, loggers.Component(logging.ComponentServer))
 	metrics := metrics.NewMetrics()
-	metrics.StartServer()
+	if err = metrics.Register(srv.Registry()); err != nil {
+		logger.Fatalw("Error registering metrics", "error", err)
+	}
+
+	shards, err := sharding.NewShards(kubernetesClientset.CoordinationV1beta1(), shardingConfig, loggers.Component(logging.ComponentSharding))
+	if err != nil {
+		logger.Fatalw("Error creating shards", "error", err)
+	}
 
-	log.Printf("Create Controller")
-	pipelineRunFetcher := k8s.NewPipelineRunFetcher(factory)
-	controller := runctl.NewController(factory, pipelineRunFetcher, metrics)
+	logger.Info("Create Controller")
+	pipelineRunFetcher := k8s.NewListerPipelineRunFetcher(factory)
+	controller := runctl.NewController(factory, pipelineRunFetcher, metrics, loggers, configStore, scope, shards)
+	srv.AddReadinessCheck("informers", controller.CheckReadiness)
+	srv.AddLivenessCheck("workqueue", controller.CheckLiveness)
 
-	log.Printf("Create Signal Handler")
+	logger.Info("Create Signal Handler")
 	stopCh := signals.SetupSignalHandler()
 
-	log.Printf("Start Informer")
+	logger.Info("Start server")
+	go func() {
+		if err := srv.Run(stopCh); err != nil {
+			logger.Fatalw("Error running server", "error", err)
+		}
+	}()
+
+	logger.Info("Watch configuration")
+	go configStore.Run(stopCh)
+
+	logger.Info("Start Informer")
+	go scope.Run(stopCh)
 	factory.StewardInformerFactory().Start(stopCh)
 	factory.TektonInformerFactory().Start(stopCh)
 
-	log.Printf("Run controller")
-	if err = controller.Run(2, stopCh); err != nil {
-		log.Fatalf("Error running controller: %s", err.Error())
+	// with sharding all replicas process pipeline runs, so that no leader
+	// is elected
+	shardsResult := make(chan error, 1)
+	if shardingConfig.Enabled {
+		leaderElectionConfig.Enabled = false
+		logger.Info("Join shards")
+		go func() {
+			shardsResult <- shards.Run(stopCh)
+		}()
+	} else {
+		shardsResult <- nil
+	}
+
+	logger.Info("Run controller")
+	err = leaderelection.Run(factory, leaderElectionConfig, loggers.Component(logging.ComponentLeaderElection), stopCh, func(stopCh <-chan struct{}) error {
+		return controller.Run(startConfig.Threadiness, stopCh)
+	})
+	if err != nil {
+		logger.Fatalw("Error running controller", "error", err)
+	}
+	// wait until the shards are handed over
+	if err = <-shardsResult; err != nil {
+		logger.Fatalw("Error running shards", "error", err)
 	}
 }
@@ -1,67 +1,143 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"time"
 
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/leaderelection"
+	"github.com/SAP/stewardci-core/pkg/logging"
+	"github.com/SAP/stewardci-core/pkg/server"
 	"github.com/SAP/stewardci-core/pkg/signals"
 	tenantctl "github.com/SAP/stewardci-core/pkg/tenantctl"
+	"github.com/SAP/stewardci-core/pkg/tracing"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 var kubeconfig string
+var listenAddress string
+var loggingConfig = logging.NewConfig()
+var tracingConfig = tracing.NewConfig()
+var scopeConfig = k8s.NewScopeConfig()
+var configSource = controllerconfig.NewSource("steward-tenant-controller")
+var leaderElectionConfig = leaderelection.NewConfig("steward-tenant-controller")
 
-// Time to wait until the next resync takes place.
+// Default controller configuration. The resync period is the time to wait
+// until the next resync takes place.
 // Resync is only required if events got lost or if the controller restarted (and missed events).
-const resyncPeriod = 5 * time.Minute
+var controllerConfig = controllerconfig.NewConfig(5 * time.Minute)
 
 func init() {
-	log.SetFlags(log.Ldate | log.Ltime | log.LUTC | log.Lshortfile)
-
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "path to Kubernetes config file")
+	flag.StringVar(&listenAddress, "listen-address", server.DefaultAddress, "address of the metrics and health endpoints")
+	leaderElectionConfig.AddFlags(flag.CommandLine)
+	loggingConfig.AddFlags(flag.CommandLine)
+	tracingConfig.AddFlags(flag.CommandLine)
+	configSource.AddFlags(flag.CommandLine)
+	scopeConfig.AddFlags(flag.CommandLine)
+	controllerConfig.AddFlags(flag.CommandLine)
 	flag.Parse()
 }
 
 func main() {
+	loggers, err := logging.New(loggingConfig)
+	if err != nil {
+		log.Fatalf("Error configuring logging: %s", err.Error())
+	}
+	logger := loggers.Component(logging.ComponentTenantController)
+	defer logger.Sync()
+
+	shutdownTracing, err := tracing.Setup(tracingConfig, "steward-tenant-controller", logger)
+	if err != nil {
+		logger.Fatalw("Error setting up tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warnw("Error flushing traces", "error", err)
+		}
+	}()
+
 	// creates the in-cluster config
 	var config *rest.Config
-	var err error
 	if kubeconfig == "" {
-		log.Printf("In cluster")
+		logger.Info("In cluster")
 		config, err = rest.InClusterConfig()
 		if err != nil {
-			log.Printf("Hint: You can use parameter '-kubeconfig' for local testing. See --help")
-			panic(err.Error())
+			logger.Info("Hint: You can use parameter '-kubeconfig' for local testing. See --help")
+			logger.Fatalw("Error loading in-cluster config", "error", err)
 		}
 	} else {
-		log.Printf("Outside cluster")
+		logger.Info("Outside cluster")
 		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 		if err != nil {
-			panic(err.Error())
+			logger.Fatalw("Error loading kubeconfig", "error", err)
 		}
 	}
-	log.Printf("Create Factory (resync period: %s)", resyncPeriod.String())
-	factory := k8s.NewClientFactory(config, resyncPeriod)
+	logger.Info("Load configuration")
+	kubernetesClientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		logger.Fatalw("Error creating Kubernetes client", "error", err)
+	}
+	configStore := controllerconfig.NewStore(kubernetesClientset.CoreV1(), configSource, controllerConfig,
+		loggers.Component(logging.ComponentConfig))
+	if err = configStore.Load(); err != nil {
+		logger.Fatalw("Error loading configuration", "error", err)
+	}
+	startConfig := configStore.Get()
 
-	log.Printf("Provide metrics")
+	logger.Infow("Create Factory", "resyncPeriod", startConfig.ResyncPeriod.String())
+	scope, err := k8s.NewScope(scopeConfig, kubernetesClientset.CoreV1(), startConfig.ResyncPeriod)
+	if err != nil {
+		logger.Fatalw("Error creating scope", "error", err)
+	}
+	factory, err := k8s.NewClientFactory(config, startConfig.ResyncPeriod, scope)
+	if err != nil {
+		logger.Fatalw("Error creating client factory", "error", err)
+	}
+	scope.RestrictTenantInformer(factory.StewardInformerFactory())
+
+	logger.Info("Provide metrics")
+	srv := server.NewServer(listenAddress, loggers.Component(logging.ComponentServer))
 	metrics := tenantctl.NewMetrics()
-	metrics.StartServer()
+	if err = metrics.Register(srv.Registry()); err != nil {
+		logger.Fatalw("Error registering metrics", "error", err)
+	}
 
-	log.Printf("Create Controller")
-	controller := tenantctl.NewController(factory, k8s.NewTenantFetcher(factory), metrics)
+	logger.Info("Create Controller")
+	controller := tenantctl.NewController(factory, k8s.NewListerTenantFetcher(factory), metrics, loggers, scope)
+	srv.AddReadinessCheck("informers", controller.CheckReadiness)
+	srv.AddLivenessCheck("workqueue", controller.CheckLiveness)
 
-	log.Printf("Create Signal Handler")
+	logger.Info("Create Signal Handler")
 	stopCh := signals.SetupSignalHandler()
 
-	log.Printf("Start Informer")
+	logger.Info("Start server")
+	go func() {
+		if err := srv.Run(stopCh); err != nil {
+			logger.Fatalw("Error running server", "error", err)
+		}
+	}()
+
+	logger.Info("Watch configuration")
+	go configStore.Run(stopCh)
+
+	logger.Info("Start Informer")
+	go scope.Run(stopCh)
 	factory.StewardInformerFactory().Start(stopCh)
 
-	log.Printf("Run controller")
-	if err = controller.Run(2, stopCh); err != nil {
-		log.Fatalf("Error running controller: %s", err.Error())
+	logger.Info("Run controller")
+	err = leaderelection.Run(factory, leaderElectionConfig, loggers.Component(logging.ComponentLeaderElection), stopCh, func(stopCh <-chan struct{}) error {
+		return controller.Run(startConfig.Threadiness, stopCh)
+	})
+	if err != nil {
+		logger.Fatalw("Error running controller", "error", err)
 	}
 }
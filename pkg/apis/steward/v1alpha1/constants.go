@@ -20,3 +20,118 @@ const (
 	// default service account of a tenant namespace.
 	AnnotationTenantRole = steward.GroupName + "/tenant-role"
 )
+
+const (
+	// AnnotationClientNamespace is the key of the annotation of a tenant
+	// namespace referencing the Steward client namespace the tenant belongs to.
+	AnnotationClientNamespace = steward.GroupName + "/client-namespace"
+
+	// LabelClientNamespace is the key of the label of a Tekton TaskRun
+	// created by Steward referencing the Steward client namespace the
+	// pipeline run belongs to.
+	LabelClientNamespace = steward.GroupName + "/client-namespace"
+
+	// AnnotationPipelineRun is the key of the annotation of a run namespace
+	// referencing the pipeline run ("<namespace>/<name>") the namespace has
+	// been created for.
+	AnnotationPipelineRun = steward.GroupName + "/pipeline-run"
+
+	// AnnotationRunEgressCIDRs is the key of the annotation of a Steward
+	// client namespace or tenant namespace defining a comma-separated list
+	// of CIDRs pipeline runs are allowed to connect to.
+	AnnotationRunEgressCIDRs = steward.GroupName + "/run-egress-cidrs"
+
+	// AnnotationRunEgressExceptCIDRs is the key of the annotation of a
+	// Steward client namespace or tenant namespace defining a comma-separated
+	// list of CIDRs to be excluded from the allowed egress CIDRs.
+	AnnotationRunEgressExceptCIDRs = steward.GroupName + "/run-egress-except-cidrs"
+
+	// AnnotationRunEgressDNS is the key of the annotation of a Steward client
+	// namespace or tenant namespace defining whether pipeline runs are
+	// allowed to send DNS queries ("true" or "false").
+	AnnotationRunEgressDNS = steward.GroupName + "/run-egress-dns"
+
+	// AnnotationRunEgressServices is the key of the annotation of a Steward
+	// client namespace or tenant namespace defining a comma-separated list
+	// of services ("<namespace>/<name>") pipeline runs are allowed to
+	// connect to, e.g. an Elasticsearch service receiving pipeline logs.
+	AnnotationRunEgressServices = steward.GroupName + "/run-egress-services"
+
+	// AnnotationTenantResourceQuota is the key of the annotation of a
+	// Steward client namespace defining the name of a ResourceQuota in the
+	// client namespace to be used as template for tenant namespaces.
+	AnnotationTenantResourceQuota = steward.GroupName + "/tenant-resource-quota"
+
+	// AnnotationTenantLimitRange is the key of the annotation of a Steward
+	// client namespace defining the name of a LimitRange in the client
+	// namespace to be used as template for tenant namespaces.
+	AnnotationTenantLimitRange = steward.GroupName + "/tenant-limit-range"
+
+	// AnnotationRunResourceQuota is the key of the annotation of a Steward
+	// client namespace defining the name of a ResourceQuota in the client
+	// namespace to be used as template for pipeline run namespaces.
+	AnnotationRunResourceQuota = steward.GroupName + "/run-resource-quota"
+
+	// AnnotationRunLimitRange is the key of the annotation of a Steward
+	// client namespace defining the name of a LimitRange in the client
+	// namespace to be used as template for pipeline run namespaces.
+	AnnotationRunLimitRange = steward.GroupName + "/run-limit-range"
+
+	// AnnotationRunAllowedClusterTasks is the key of the annotation of a
+	// Steward client namespace defining a comma-separated list of Tekton
+	// ClusterTasks pipeline runs may select in addition to the default one.
+	AnnotationRunAllowedClusterTasks = steward.GroupName + "/run-allowed-cluster-tasks"
+
+	// AnnotationRunAllowedImages is the key of the annotation of a Steward
+	// client namespace defining a comma-separated list of Jenkinsfile Runner
+	// images pipeline runs may select. An entry ending with '*' allows all
+	// images starting with the preceding string.
+	AnnotationRunAllowedImages = steward.GroupName + "/run-allowed-images"
+
+	// AnnotationRunKillGracePeriod is the key of the annotation of a Steward
+	// client namespace defining how long a killed pipeline run is given to
+	// terminate before its namespace gets deleted, e.g. "30s".
+	AnnotationRunKillGracePeriod = steward.GroupName + "/run-kill-grace-period"
+
+	// AnnotationRunResultRules is the key of the annotation of a Steward
+	// client namespace defining additional rules to classify the result of
+	// pipeline runs, as JSON list. They take precedence over the built-in
+	// rules and are applied once the Tekton TaskRun has finished.
+	AnnotationRunResultRules = steward.GroupName + "/run-result-rules"
+
+	// AnnotationRunCacheStorageClass is the key of the annotation of a
+	// Steward client namespace defining the storage class of the volumes
+	// for persistent build caches. Pipeline runs can only request caches
+	// if it is set.
+	AnnotationRunCacheStorageClass = steward.GroupName + "/run-cache-storage-class"
+
+	// AnnotationRunCacheSize is the key of the annotation of a Steward
+	// client namespace defining the size of newly created build caches,
+	// e.g. "5Gi".
+	AnnotationRunCacheSize = steward.GroupName + "/run-cache-size"
+
+	// AnnotationRunCacheMaxAge is the key of the annotation of a Steward
+	// client namespace defining after which time an unused build cache
+	// gets evicted, e.g. "168h".
+	AnnotationRunCacheMaxAge = steward.GroupName + "/run-cache-max-age"
+
+	// AnnotationRunCacheMaxTotalSize is the key of the annotation of a
+	// Steward client namespace defining the maximum total size of the
+	// build caches of a tenant, e.g. "50Gi". The least recently used
+	// caches get evicted if exceeded.
+	AnnotationRunCacheMaxTotalSize = steward.GroupName + "/run-cache-max-total-size"
+
+	// AnnotationTenantRunSettings is the key of the annotation of a tenant
+	// namespace containing the run settings of the tenant (spec.runs) as
+	// JSON. It is maintained by the tenant controller and must not be
+	// modified otherwise.
+	AnnotationTenantRunSettings = steward.GroupName + "/tenant-run-settings"
+)
+
+const (
+	// AnnotationTraceID is the key of the annotation of a pipeline run
+	// containing the ID of the trace its first reconciliation is recorded
+	// in. The traces of subsequent reconciliations link to this trace.
+	// It is only set if the pipeline run is traced.
+	AnnotationTraceID = steward.GroupName + "/trace-id"
+)
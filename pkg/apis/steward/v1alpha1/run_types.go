@@ -33,8 +33,36 @@ type PipelineSpec struct {
 	Secrets     []string          `json:"secrets"`
 	Intent      Intent            `json:"intent"`
 	Logging     *Logging          `json:"logging"`
+	Runtime     *Runtime          `json:"runtime,omitempty"`
+	KillRequest *KillRequest      `json:"killRequest,omitempty"`
+	RerunOf     string            `json:"rerunOf,omitempty"`
+	Caches      []Cache           `json:"caches,omitempty"`
+	// Timeout is the maximum duration of the pipeline run. Defaults to
+	// the timeout of the original pipeline run for re-runs and to the
+	// default timeout of the tenant otherwise.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
 }
 
+// Cache requests a persistent build cache of the tenant to be mounted
+// into the Jenkinsfile Runner container at /caches/<name>.
+type Cache struct {
+	Name string `json:"name"`
+	// Mode defaults to ReadWrite.
+	Mode CacheMode `json:"mode,omitempty"`
+}
+
+// CacheMode defines how a pipeline run uses a cache
+type CacheMode string
+
+const (
+	// CacheModeReadWrite - the pipeline run uses the cache exclusively and
+	// its changes are kept. A new cache is created if none is available.
+	CacheModeReadWrite CacheMode = "ReadWrite"
+	// CacheModeReadOnly - the pipeline run only reads from the cache. An
+	// empty directory is mounted if no cache is available.
+	CacheModeReadOnly CacheMode = "ReadOnly"
+)
+
 // JenkinsFile represents the location from where to get the pipeline
 type JenkinsFile struct {
 	URL      string `json:"repoUrl"`
@@ -57,18 +85,78 @@ type Elasticsearch struct {
 	RunID *CustomJSON `json:"runID"`
 }
 
+// Runtime selects the Tekton ClusterTask and the Jenkinsfile Runner image
+// executing the pipeline. Both must be allowed by the configuration of the
+// Steward client.
+type Runtime struct {
+	// The name of the Tekton ClusterTask executing the pipeline.
+	// Defaults to 'steward-jenkinsfile-runner'.
+	ClusterTask string `json:"clusterTask,omitempty"`
+	// The Jenkinsfile Runner container image.
+	// Defaults to the image defined by the ClusterTask.
+	Image string `json:"image,omitempty"`
+}
+
+// KillRequest provides details about the request to kill a pipeline run.
+// It is only evaluated if the intent of the pipeline run is 'kill'.
+type KillRequest struct {
+	// The name of the user requesting the kill.
+	User string `json:"user,omitempty"`
+	// The reason why the pipeline run should be killed.
+	Reason string `json:"reason,omitempty"`
+}
+
 // PipelineStatus represents the status of the pipeline
 type PipelineStatus struct {
 	State        State                 `json:"state"`
 	StateDetails StateItem             `json:"stateDetails"`
 	StateHistory []StateItem           `json:"stateHistory"`
 	Result       Result                `json:"result"`
+	ResultReason ResultReason          `json:"resultReason,omitempty"`
 	Container    corev1.ContainerState `json:"container,omitempty"`
 	LogURL       string                `json:"logUrl"`
 	MessageShort string                `json:"messageShort"`
 	Message      string                `json:"message"`
 	History      []string              `json:"history"`
 	Namespace    string                `json:"namespace"`
+	RerunOf      string                `json:"rerunOf,omitempty"`
+	Attempt      int32                 `json:"attempt,omitempty"`
+	Outputs      map[string]string     `json:"outputs,omitempty"`
+	TestSummary  *TestSummary          `json:"testSummary,omitempty"`
+	Steps        []StepStatus          `json:"steps,omitempty"`
+	TraceParent  string                `json:"traceParent,omitempty"`
+}
+
+// StepStatus is the status of a step executing the pipeline run
+type StepStatus struct {
+	Name       string       `json:"name"`
+	State      StepState    `json:"state"`
+	Reason     string       `json:"reason,omitempty"`
+	ExitCode   *int32       `json:"exitCode,omitempty"`
+	StartedAt  *metav1.Time `json:"startedAt,omitempty"`
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+}
+
+// StepState is the state of a step
+type StepState string
+
+const (
+	// StepStateWaiting - the step has not been started yet
+	StepStateWaiting StepState = "waiting"
+	// StepStateRunning - the step is running
+	StepStateRunning StepState = "running"
+	// StepStateTerminated - the step has terminated
+	StepStateTerminated StepState = "terminated"
+)
+
+// TestSummary summarizes the JUnit test reports of a pipeline run
+type TestSummary struct {
+	Total   int32 `json:"total"`
+	Passed  int32 `json:"passed"`
+	Failed  int32 `json:"failed"`
+	Skipped int32 `json:"skipped"`
+	// FailedTests contains the names of (some of) the failed tests.
+	FailedTests []string `json:"failedTests,omitempty"`
 }
 
 // StateItem holds start and end time of a state in the history
@@ -90,6 +178,9 @@ const (
 	StateWaiting State = "waiting"
 	// StateRunning - the pipeline is running
 	StateRunning State = "running"
+	// StateKilling - the pipeline run has been cancelled and is given
+	// time to terminate gracefully
+	StateKilling State = "killing"
 	// StateCleaning - cleanup is ongoing
 	StateCleaning State = "cleaning"
 	// StateFinished - the pipeline run has finished
@@ -112,6 +203,34 @@ const (
 	ResultKilled Result = "killed"
 	// ResultTimeout - the pipeline run timed out
 	ResultTimeout Result = "timeout"
+	// ResultErrorQuota - the pipeline run failed because a resource quota
+	// of the run namespace was exceeded
+	ResultErrorQuota Result = "error_quota"
+)
+
+// ResultReason is a machine-readable classification of why a pipeline run
+// ended with a result other than success
+type ResultReason string
+
+const (
+	// ResultReasonUndefined - the reason is not known
+	ResultReasonUndefined ResultReason = ""
+	// ResultReasonImagePullFailed - a container image could not be pulled
+	ResultReasonImagePullFailed ResultReason = "ImagePullFailed"
+	// ResultReasonQuotaExceeded - a resource quota of the run namespace was exceeded
+	ResultReasonQuotaExceeded ResultReason = "QuotaExceeded"
+	// ResultReasonPodEvicted - the pod executing the pipeline was evicted
+	ResultReasonPodEvicted ResultReason = "PodEvicted"
+	// ResultReasonUnschedulable - the pod executing the pipeline could not be scheduled
+	ResultReasonUnschedulable ResultReason = "Unschedulable"
+	// ResultReasonOOMKilled - the Jenkinsfile Runner was killed because it ran out of memory
+	ResultReasonOOMKilled ResultReason = "OOMKilled"
+	// ResultReasonGitCloneFailed - the pipeline repository could not be cloned
+	ResultReasonGitCloneFailed ResultReason = "GitCloneFailed"
+	// ResultReasonPipelineScriptError - the pipeline script failed
+	ResultReasonPipelineScriptError ResultReason = "PipelineScriptError"
+	// ResultReasonTimeout - the pipeline run did not finish in time
+	ResultReasonTimeout ResultReason = "Timeout"
 )
 
 // Intent denotes how the pipeline run should be handled
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -29,16 +30,91 @@ type TenantList struct {
 type TenantSpec struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"displayName"`
+	// Runs contains quotas and defaults for the pipeline runs of the tenant
+	// +optional
+	Runs *TenantRunSettings `json:"runs,omitempty"`
+}
+
+// TenantRunSettings contains quotas and defaults for the pipeline runs in
+// the namespace of a tenant. The tenant controller stores them at the
+// tenant namespace, and the run controller applies them to the pipeline
+// runs in that namespace.
+type TenantRunSettings struct {
+	// MaxConcurrent is the maximum number of pipeline runs of the tenant
+	// being started or running at the same time. Further pipeline runs
+	// wait until active ones have finished. Zero means unlimited.
+	// +optional
+	MaxConcurrent int32 `json:"maxConcurrent,omitempty"`
+	// DefaultTimeout is the timeout of pipeline runs not specifying one.
+	// Defaults to MaxTimeout.
+	// +optional
+	DefaultTimeout *metav1.Duration `json:"defaultTimeout,omitempty"`
+	// MaxTimeout is the maximum timeout pipeline runs may specify.
+	// Defaults to and is capped at the build timeout of the run
+	// controller.
+	// +optional
+	MaxTimeout *metav1.Duration `json:"maxTimeout,omitempty"`
+	// DefaultResources are the compute resources of containers in run
+	// namespaces not specifying any.
+	// +optional
+	DefaultResources *corev1.ResourceRequirements `json:"defaultResources,omitempty"`
+	// Runtime is the runtime of all pipeline runs of the tenant. Pipeline
+	// runs may omit it or repeat it, but cannot select another one.
+	// +optional
+	Runtime *Runtime `json:"runtime,omitempty"`
+	// Logging is the logging configuration of pipeline runs not
+	// specifying one.
+	// +optional
+	Logging *Logging `json:"logging,omitempty"`
 }
 
 // TenantStatus contains the status of a Tenant
 type TenantStatus struct {
+	// ObservedGeneration is the generation of the tenant the status refers to
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions contains the Ready condition of the tenant
+	// +optional
+	Conditions []TenantCondition `json:"conditions,omitempty"`
+
 	Progress            TenantCreationProgress `json:"progress"`
 	Result              TenantResult           `json:"result"`
 	Message             string                 `json:"message"`
 	TenantNamespaceName string                 `json:"tenantNamespaceName"`
 }
 
+// TenantCondition describes an aspect of the state of a Tenant
+type TenantCondition struct {
+	Type   TenantConditionType    `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// TenantConditionType is the type of a TenantCondition
+type TenantConditionType string
+
+const (
+	// TenantConditionReady - the tenant namespace is prepared and can be used
+	TenantConditionReady TenantConditionType = "Ready"
+)
+
+// Reasons of the Ready condition of a Tenant
+const (
+	// TenantReasonPreparing - the tenant namespace is being prepared
+	TenantReasonPreparing = "Preparing"
+	// TenantReasonPrepared - the tenant namespace has been prepared
+	TenantReasonPrepared = "Prepared"
+	// TenantReasonInfraError - the preparation failed due to an infrastructure problem and is retried
+	TenantReasonInfraError = "InfraError"
+	// TenantReasonContentError - the preparation failed due to a content problem and is retried
+	TenantReasonContentError = "ContentError"
+)
+
 // TenantCreationProgress of the Tenant
 type TenantCreationProgress string
 
@@ -53,6 +129,8 @@ const (
 	TenantProgressGetServiceAccount TenantCreationProgress = "GetServiceAccount"
 	//TenantProgressAddRoleBinding current step add role binding
 	TenantProgressAddRoleBinding TenantCreationProgress = "AddRoleBinding"
+	//TenantProgressApplyResourceLimits current step apply resource quota and limit range
+	TenantProgressApplyResourceLimits TenantCreationProgress = "ApplyResourceLimits"
 	//TenantProgressFinalize current step finalize, all steps before were successful.
 	TenantProgressFinalize TenantCreationProgress = "Finalize"
 	//TenantProgressFinished process finished
@@ -0,0 +1,226 @@
+package controllerconfig
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Defaults of the configuration values
+const (
+	DefaultThreadiness              = 2
+	DefaultBuildTimeout             = 60 * time.Minute
+	DefaultRunNamespacePrefix       = "steward-run"
+	DefaultRunNamespaceRandomLength = 16
+	DefaultRunServiceAccountName    = "run-bot"
+	// DefaultTektonClusterTaskName is the name of the Tekton ClusterTask
+	// that is used to execute the Jenkinsfile Runner if the pipeline
+	// run does not select another one
+	DefaultTektonClusterTaskName = "steward-jenkinsfile-runner"
+
+	DefaultOrphanedNamespaceInterval = 10 * time.Minute
+	DefaultOrphanedNamespaceGrace    = time.Hour
+)
+
+// Keys of the configuration values in the ConfigMap
+const (
+	keyResyncPeriod              = "resyncPeriod"
+	keyThreadiness               = "threadiness"
+	keyBuildTimeout              = "buildTimeout"
+	keyRunNamespacePrefix        = "runNamespacePrefix"
+	keyRunNamespaceRandomLength  = "runNamespaceRandomLength"
+	keyRunServiceAccountName     = "runServiceAccountName"
+	keyTektonClusterTaskName     = "tektonClusterTaskName"
+	keyOrphanedNamespaceInterval = "orphanedNamespaceInterval"
+	keyOrphanedNamespaceGrace    = "orphanedNamespaceGracePeriod"
+	keyOrphanedNamespaceDryRun   = "orphanedNamespaceDryRun"
+)
+
+// maxNamespaceNameLength is the maximum length of namespace names
+const maxNamespaceNameLength = validation.DNS1123LabelMaxLength
+
+// Config is the configuration of a controller.
+// ResyncPeriod, Threadiness, RunNamespacePrefix, RunNamespaceRandomLength
+// and OrphanedNamespaceInterval are only applied on start of the
+// controller, changes of them are logged with a warning and take effect
+// after a restart. All other values take effect on the next
+// reconciliation after a change.
+type Config struct {
+	// ResyncPeriod is the time after which all resources are reconciled
+	// again, even if they did not change
+	ResyncPeriod time.Duration
+	// Threadiness is the number of workers reconciling resources in parallel
+	Threadiness int
+	// BuildTimeout is the maximum duration of a pipeline run
+	BuildTimeout time.Duration
+	// RunNamespacePrefix is the prefix of the names of run namespaces.
+	// It falls back to the default if empty.
+	RunNamespacePrefix string
+	// RunNamespaceRandomLength is the length of the random suffix of the
+	// names of run namespaces
+	RunNamespaceRandomLength int
+	// RunServiceAccountName is the name of the service account pipeline
+	// runs are executed with
+	RunServiceAccountName string
+	// TektonClusterTaskName is the name of the Tekton ClusterTask executing
+	// pipeline runs not selecting another one
+	TektonClusterTaskName string
+	// OrphanedNamespaceInterval is the interval in which run namespaces
+	// are checked for being orphaned. Zero disables the check.
+	OrphanedNamespaceInterval time.Duration
+	// OrphanedNamespaceGrace is the minimum age of orphaned run namespaces
+	// and the time after which the namespace of a finished pipeline run
+	// is considered orphaned
+	OrphanedNamespaceGrace time.Duration
+	// OrphanedNamespaceDryRun defines whether orphaned run namespaces are
+	// only reported instead of deleted
+	OrphanedNamespaceDryRun bool
+}
+
+// NewConfig returns the default configuration with the given resync period.
+func NewConfig(resyncPeriod time.Duration) *Config {
+	return &Config{
+		ResyncPeriod:              resyncPeriod,
+		Threadiness:               DefaultThreadiness,
+		BuildTimeout:              DefaultBuildTimeout,
+		RunNamespacePrefix:        DefaultRunNamespacePrefix,
+		RunNamespaceRandomLength:  DefaultRunNamespaceRandomLength,
+		RunServiceAccountName:     DefaultRunServiceAccountName,
+		TektonClusterTaskName:     DefaultTektonClusterTaskName,
+		OrphanedNamespaceInterval: DefaultOrphanedNamespaceInterval,
+		OrphanedNamespaceGrace:    DefaultOrphanedNamespaceGrace,
+	}
+}
+
+// AddFlags adds command line flags for the values relevant for all
+// controllers to the given flag set.
+func (c *Config) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.DurationVar(&c.ResyncPeriod, "resync-period", c.ResyncPeriod, "time after which all resources are reconciled again")
+	flagSet.IntVar(&c.Threadiness, "threadiness", c.Threadiness, "number of workers reconciling resources in parallel")
+}
+
+// AddRunFlags adds command line flags for the values relevant for the
+// pipeline run controller to the given flag set.
+func (c *Config) AddRunFlags(flagSet *flag.FlagSet) {
+	flagSet.DurationVar(&c.BuildTimeout, "build-timeout", c.BuildTimeout, "maximum duration of a pipeline run")
+	flagSet.StringVar(&c.RunNamespacePrefix, "run-namespace-prefix", c.RunNamespacePrefix, "prefix of the names of run namespaces")
+	flagSet.IntVar(&c.RunNamespaceRandomLength, "run-namespace-random-length", c.RunNamespaceRandomLength, "length of the random suffix of the names of run namespaces")
+	flagSet.StringVar(&c.RunServiceAccountName, "run-service-account", c.RunServiceAccountName, "name of the service account pipeline runs are executed with")
+	flagSet.StringVar(&c.TektonClusterTaskName, "tekton-cluster-task", c.TektonClusterTaskName, "name of the default Tekton ClusterTask executing pipeline runs")
+	flagSet.DurationVar(&c.OrphanedNamespaceInterval, "orphaned-namespace-interval", c.OrphanedNamespaceInterval, "interval in which orphaned run namespaces are deleted, 0 disables the deletion")
+	flagSet.DurationVar(&c.OrphanedNamespaceGrace, "orphaned-namespace-grace-period", c.OrphanedNamespaceGrace, "time after which unused run namespaces and namespaces of finished pipeline runs are considered orphaned")
+	flagSet.BoolVar(&c.OrphanedNamespaceDryRun, "orphaned-namespace-dry-run", c.OrphanedNamespaceDryRun, "only log orphaned run namespaces instead of deleting them")
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c *Config) Validate() error {
+	if c.ResyncPeriod <= 0 {
+		return fmt.Errorf("%s must be positive", keyResyncPeriod)
+	}
+	if c.Threadiness < 1 {
+		return fmt.Errorf("%s must be at least 1", keyThreadiness)
+	}
+	if c.BuildTimeout <= 0 {
+		return fmt.Errorf("%s must be positive", keyBuildTimeout)
+	}
+	if c.RunNamespacePrefix != "" {
+		if errs := validation.IsDNS1123Label(c.RunNamespacePrefix); len(errs) > 0 {
+			return fmt.Errorf("%s is invalid: %s", keyRunNamespacePrefix, strings.Join(errs, ", "))
+		}
+	}
+	maxRandomLength := maxNamespaceNameLength - len(c.RunNamespacePrefix) - 1
+	if c.RunNamespaceRandomLength < 0 || c.RunNamespaceRandomLength > maxRandomLength {
+		return fmt.Errorf("%s must be between 0 and %d", keyRunNamespaceRandomLength, maxRandomLength)
+	}
+	if errs := validation.IsDNS1123Subdomain(c.RunServiceAccountName); len(errs) > 0 {
+		return fmt.Errorf("%s is invalid: %s", keyRunServiceAccountName, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1123Subdomain(c.TektonClusterTaskName); len(errs) > 0 {
+		return fmt.Errorf("%s is invalid: %s", keyTektonClusterTaskName, strings.Join(errs, ", "))
+	}
+	if c.OrphanedNamespaceInterval < 0 {
+		return fmt.Errorf("%s must not be negative", keyOrphanedNamespaceInterval)
+	}
+	if c.OrphanedNamespaceGrace <= 0 {
+		return fmt.Errorf("%s must be positive", keyOrphanedNamespaceGrace)
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the configuration with empty values
+// replaced by their defaults where an empty value is not allowed.
+func (c *Config) withDefaults() *Config {
+	result := *c
+	// run namespaces are identified by the prefix label
+	if result.RunNamespacePrefix == "" {
+		result.RunNamespacePrefix = DefaultRunNamespacePrefix
+	}
+	return &result
+}
+
+// keepStartValues replaces the values of the configuration which are only
+// applied on start by those of the given configuration. It returns
+// whether any of them has been changed.
+func (c *Config) keepStartValues(start *Config) bool {
+	changed := c.ResyncPeriod != start.ResyncPeriod ||
+		c.Threadiness != start.Threadiness ||
+		c.RunNamespacePrefix != start.RunNamespacePrefix ||
+		c.RunNamespaceRandomLength != start.RunNamespaceRandomLength ||
+		c.OrphanedNamespaceInterval != start.OrphanedNamespaceInterval
+	c.ResyncPeriod = start.ResyncPeriod
+	c.Threadiness = start.Threadiness
+	c.RunNamespacePrefix = start.RunNamespacePrefix
+	c.RunNamespaceRandomLength = start.RunNamespaceRandomLength
+	c.OrphanedNamespaceInterval = start.OrphanedNamespaceInterval
+	return changed
+}
+
+// withData returns a copy of the configuration with the values defined
+// in the given ConfigMap data applied.
+// An error is returned for unknown keys and invalid values.
+func (c *Config) withData(data map[string]string) (*Config, error) {
+	result := *c
+	keys := []string{}
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.TrimSpace(data[key])
+		var err error
+		switch key {
+		case keyResyncPeriod:
+			result.ResyncPeriod, err = time.ParseDuration(value)
+		case keyThreadiness:
+			result.Threadiness, err = strconv.Atoi(value)
+		case keyBuildTimeout:
+			result.BuildTimeout, err = time.ParseDuration(value)
+		case keyRunNamespacePrefix:
+			result.RunNamespacePrefix = value
+		case keyRunNamespaceRandomLength:
+			result.RunNamespaceRandomLength, err = strconv.Atoi(value)
+		case keyRunServiceAccountName:
+			result.RunServiceAccountName = value
+		case keyTektonClusterTaskName:
+			result.TektonClusterTaskName = value
+		case keyOrphanedNamespaceInterval:
+			result.OrphanedNamespaceInterval, err = time.ParseDuration(value)
+		case keyOrphanedNamespaceGrace:
+			result.OrphanedNamespaceGrace, err = time.ParseDuration(value)
+		case keyOrphanedNamespaceDryRun:
+			result.OrphanedNamespaceDryRun, err = strconv.ParseBool(value)
+		default:
+			return nil, fmt.Errorf("unknown key '%s'", key)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value of key '%s'", key)
+		}
+	}
+	return &result, nil
+}
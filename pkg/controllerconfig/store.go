@@ -0,0 +1,176 @@
+package controllerconfig
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Source identifies the ConfigMap the configuration is loaded from.
+type Source struct {
+	// Namespace is the namespace of the ConfigMap
+	Namespace string
+	// Name is the name of the ConfigMap
+	Name string
+}
+
+// NewSource returns the default source for the given ConfigMap name.
+func NewSource(name string) *Source {
+	return &Source{
+		Namespace: "steward-system",
+		Name:      name,
+	}
+}
+
+// AddFlags adds command line flags for the source to the given flag set.
+func (s *Source) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&s.Namespace, "config-namespace", s.Namespace, "namespace of the controller configuration ConfigMap")
+	flagSet.StringVar(&s.Name, "config-map", s.Name, "name of the controller configuration ConfigMap")
+}
+
+// Store provides the current configuration of a controller.
+// The values of the ConfigMap override the base configuration, which
+// is typically defined via command line flags. Keys removed from the
+// ConfigMap fall back to the base configuration.
+type Store struct {
+	client  corev1.ConfigMapsGetter
+	source  Source
+	base    Config
+	logger  *zap.SugaredLogger
+	mutex   sync.RWMutex
+	current *Config
+}
+
+// NewStore creates a new store loading the configuration from the given
+// source on top of the given base configuration.
+func NewStore(client corev1.ConfigMapsGetter, source *Source, base *Config, logger *zap.SugaredLogger) *Store {
+	current := *base
+	return &Store{
+		client:  client,
+		source:  *source,
+		base:    *base,
+		logger:  logger,
+		current: &current,
+	}
+}
+
+// NewStaticStore creates a store always providing the given configuration.
+func NewStaticStore(config *Config) *Store {
+	current := *config
+	return &Store{
+		base:    *config,
+		logger:  zap.NewNop().Sugar(),
+		current: &current,
+	}
+}
+
+// Get returns the current configuration.
+// The returned configuration must not be modified.
+func (s *Store) Get() *Config {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.current
+}
+
+// Load loads the configuration from the ConfigMap.
+// If the ConfigMap does not exist, the base configuration is used.
+// An error is returned if the ConfigMap cannot be read or the resulting
+// configuration is invalid.
+func (s *Store) Load() error {
+	configMap, err := s.client.ConfigMaps(s.source.Namespace).Get(s.source.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return errors.WithMessagef(err, "could not load configuration from ConfigMap '%s/%s'", s.source.Namespace, s.source.Name)
+		}
+		s.logger.Infow("Configuration ConfigMap not found, using defaults", "configMap", s.source.Name)
+		configMap = nil
+	}
+	config, err := s.toConfig(configMap)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.current = config
+	return nil
+}
+
+// Run watches the ConfigMap and reloads the configuration on changes
+// until the stop channel is closed.
+// Invalid changes are logged and ignored. Changes of values which are
+// only applied on start are logged and ignored, too.
+func (s *Store) Run(stopCh <-chan struct{}) {
+	selector := fields.OneTermEqualSelector("metadata.name", s.source.Name).String()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return s.client.ConfigMaps(s.source.Namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return s.client.ConfigMaps(s.source.Namespace).Watch(options)
+		},
+	}
+	_, informer := cache.NewInformer(listWatch, &v1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.reload(obj.(*v1.ConfigMap))
+		},
+		UpdateFunc: func(old, new interface{}) {
+			s.reload(new.(*v1.ConfigMap))
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.reload(nil)
+		},
+	})
+	informer.Run(stopCh)
+}
+
+func (s *Store) reload(configMap *v1.ConfigMap) {
+	if configMap != nil && configMap.GetName() != s.source.Name {
+		return
+	}
+	config, err := s.toConfig(configMap)
+	if err != nil {
+		s.logger.Errorw("Ignoring invalid configuration", "configMap", s.source.Name, "error", err)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	changed := *config
+	if config.keepStartValues(s.current) {
+		s.logger.Warnw("Changes of resyncPeriod, threadiness, runNamespacePrefix, runNamespaceRandomLength and orphanedNamespaceInterval take effect after restart",
+			"resyncPeriod", changed.ResyncPeriod.String(), "threadiness", changed.Threadiness,
+			"runNamespacePrefix", changed.RunNamespacePrefix, "runNamespaceRandomLength", changed.RunNamespaceRandomLength,
+			"orphanedNamespaceInterval", changed.OrphanedNamespaceInterval.String())
+	}
+	if *config != *s.current {
+		s.logger.Infow("Configuration reloaded", "config", config)
+	}
+	s.current = config
+}
+
+func (s *Store) toConfig(configMap *v1.ConfigMap) (*Config, error) {
+	config := &s.base
+	if configMap != nil {
+		var err error
+		config, err = s.base.withData(configMap.Data)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid configuration in ConfigMap '%s/%s'", s.source.Namespace, s.source.Name)
+		}
+	}
+	result := config.withDefaults()
+	if err := result.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid configuration")
+	}
+	return result, nil
+}
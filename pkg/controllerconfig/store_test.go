@@ -0,0 +1,186 @@
+package controllerconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const waitTimeout = 5 * time.Second
+
+func newConfigMap(data map[string]string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: fake.ObjectMeta("config1", "ns1"),
+		Data:       data,
+	}
+}
+
+func newTestStore(factory *fake.ClientFactory) *Store {
+	return NewStore(factory.CoreV1(), &Source{Namespace: "ns1", Name: "config1"},
+		NewConfig(time.Minute), zap.NewNop().Sugar())
+}
+
+// waitForConfig waits until the configuration of the store fulfills the
+// given condition.
+func waitForConfig(t *testing.T, examinee *Store, condition func(*Config) bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !condition(examinee.Get()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("configuration not updated: %+v", examinee.Get())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_Store_Load_NoConfigMap_UsesBase(t *testing.T) {
+	// SETUP
+	examinee := newTestStore(fake.NewClientFactory())
+
+	// EXERCISE
+	err := examinee.Load()
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, NewConfig(time.Minute), examinee.Get())
+}
+
+func Test_Store_Load_OverridesBase(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(newConfigMap(map[string]string{
+		"threadiness":                  "4",
+		"buildTimeout":                 "2h",
+		"runNamespacePrefix":           "prefix1",
+		"runNamespaceRandomLength":     "8",
+		"runServiceAccountName":        "account1",
+		"tektonClusterTaskName":        "task1",
+		"orphanedNamespaceInterval":    "5m",
+		"orphanedNamespaceGracePeriod": "2h",
+		"orphanedNamespaceDryRun":      "true",
+	}))
+	examinee := newTestStore(cf)
+
+	// EXERCISE
+	err := examinee.Load()
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &Config{
+		ResyncPeriod:              time.Minute,
+		Threadiness:               4,
+		BuildTimeout:              2 * time.Hour,
+		RunNamespacePrefix:        "prefix1",
+		RunNamespaceRandomLength:  8,
+		RunServiceAccountName:     "account1",
+		TektonClusterTaskName:     "task1",
+		OrphanedNamespaceInterval: 5 * time.Minute,
+		OrphanedNamespaceGrace:    2 * time.Hour,
+		OrphanedNamespaceDryRun:   true,
+	}, examinee.Get())
+}
+
+func Test_Store_Load_EmptyPrefix_UsesDefault(t *testing.T) {
+	// SETUP
+	examinee := newTestStore(fake.NewClientFactory(newConfigMap(map[string]string{
+		"runNamespacePrefix":       "",
+		"runNamespaceRandomLength": "8",
+	})))
+
+	// EXERCISE
+	err := examinee.Load()
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, DefaultRunNamespacePrefix, examinee.Get().RunNamespacePrefix)
+	assert.Equal(t, 8, examinee.Get().RunNamespaceRandomLength)
+}
+
+func Test_Store_Load_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		data          map[string]string
+		expectedError string
+	}{
+		{"UnknownKey", map[string]string{"foo": "bar"},
+			"invalid configuration in ConfigMap 'ns1/config1': unknown key 'foo'"},
+		{"UnparsableNumber", map[string]string{"threadiness": "many"},
+			"invalid configuration in ConfigMap 'ns1/config1': invalid value of key 'threadiness': strconv.Atoi: parsing \"many\": invalid syntax"},
+		{"ZeroThreadiness", map[string]string{"threadiness": "0"},
+			"invalid configuration: threadiness must be at least 1"},
+		{"InvalidPrefix", map[string]string{"runNamespacePrefix": "Prefix"},
+			"invalid configuration: runNamespacePrefix is invalid: a DNS-1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"},
+		{"NamespaceNameTooLong", map[string]string{"runNamespacePrefix": "prefix1", "runNamespaceRandomLength": "56"},
+			"invalid configuration: runNamespaceRandomLength must be between 0 and 55"},
+		{"ZeroOrphanedNamespaceGracePeriod", map[string]string{"orphanedNamespaceGracePeriod": "0s"},
+			"invalid configuration: orphanedNamespaceGracePeriod must be positive"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			examinee := newTestStore(fake.NewClientFactory(newConfigMap(tc.data)))
+
+			// EXERCISE
+			err := examinee.Load()
+
+			// VERIFY
+			assert.Error(t, err, tc.expectedError)
+			assert.DeepEqual(t, NewConfig(time.Minute), examinee.Get())
+		})
+	}
+}
+
+func Test_Store_Run_ReloadsChanges(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(newConfigMap(map[string]string{"buildTimeout": "2h"}))
+	examinee := newTestStore(cf)
+	assert.NilError(t, examinee.Load())
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go examinee.Run(stopCh)
+	configMaps := cf.CoreV1().ConfigMaps("ns1")
+
+	// EXERCISE
+	_, err := configMaps.Update(newConfigMap(map[string]string{
+		"buildTimeout":             "3h",
+		"threadiness":              "8",
+		"runNamespacePrefix":       "prefix1",
+		"runNamespaceRandomLength": "8",
+	}))
+	assert.NilError(t, err)
+
+	// VERIFY
+	waitForConfig(t, examinee, func(config *Config) bool {
+		return config.BuildTimeout == 3*time.Hour
+	})
+	// threadiness and run namespace naming are only applied on start
+	assert.Equal(t, DefaultThreadiness, examinee.Get().Threadiness)
+	assert.Equal(t, DefaultRunNamespacePrefix, examinee.Get().RunNamespacePrefix)
+	assert.Equal(t, DefaultRunNamespaceRandomLength, examinee.Get().RunNamespaceRandomLength)
+
+	// EXERCISE
+	_, err = configMaps.Update(newConfigMap(map[string]string{"buildTimeout": "-1h"}))
+	assert.NilError(t, err)
+	err = configMaps.Delete("config1", &metav1.DeleteOptions{})
+	assert.NilError(t, err)
+
+	// VERIFY
+	waitForConfig(t, examinee, func(config *Config) bool {
+		return config.BuildTimeout == DefaultBuildTimeout
+	})
+}
+
+func Test_NewStaticStore(t *testing.T) {
+	// SETUP
+	config := NewConfig(time.Minute)
+
+	// EXERCISE
+	examinee := NewStaticStore(config)
+	config.Threadiness = 5
+
+	// VERIFY
+	assert.Equal(t, DefaultThreadiness, examinee.Get().Threadiness)
+}
@@ -1,7 +1,6 @@
 package k8s
 
 import (
-	"log"
 	"time"
 
 	steward "github.com/SAP/stewardci-core/pkg/client/clientset/versioned"
@@ -10,8 +9,11 @@ import (
 	tektonclient "github.com/SAP/stewardci-core/pkg/tektonclient/clientset/versioned"
 	tektonclientv1alpha1 "github.com/SAP/stewardci-core/pkg/tektonclient/clientset/versioned/typed/pipeline/v1alpha1"
 	tektoninformers "github.com/SAP/stewardci-core/pkg/tektonclient/informers/externalversions"
+	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes"
+	coordinationv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	networkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 	rbacv1beta1 "k8s.io/client-go/kubernetes/typed/rbac/v1beta1"
 	"k8s.io/client-go/rest"
 )
@@ -27,7 +29,9 @@ type clientFactory struct {
 
 // ClientFactory interface
 type ClientFactory interface {
+	CoordinationV1beta1() coordinationv1beta1.CoordinationV1beta1Interface
 	CoreV1() corev1.CoreV1Interface
+	NetworkingV1() networkingv1.NetworkingV1Interface
 	RbacV1beta1() rbacv1beta1.RbacV1beta1Interface
 	StewardV1alpha1() stewardv1alpha1.StewardV1alpha1Interface
 	StewardInformerFactory() stewardinformer.SharedInformerFactory
@@ -35,34 +39,35 @@ type ClientFactory interface {
 	TektonInformerFactory() tektoninformers.SharedInformerFactory
 }
 
-// NewClientFactory creates new client factory based on rest config
-func NewClientFactory(config *rest.Config, resyncPeriod time.Duration) ClientFactory {
+// NewClientFactory creates new client factory based on rest config.
+// The list and watch requests of the informers for Steward resources and
+// Tekton TaskRuns are restricted to the given scope as far as possible.
+func NewClientFactory(config *rest.Config, resyncPeriod time.Duration, scope *Scope) (ClientFactory, error) {
 	stewardClientset, err := steward.NewForConfig(config)
 	if err != nil {
-		log.Printf("Cannot create steward client %s", err)
-		return nil
+		return nil, errors.WithMessage(err, "cannot create steward clientset")
 	}
 
-	stewardInformerFactory := stewardinformer.NewSharedInformerFactory(stewardClientset, resyncPeriod)
+	stewardInformerFactory := stewardinformer.NewSharedInformerFactoryWithOptions(stewardClientset, resyncPeriod,
+		stewardinformer.WithTweakListOptions(scope.TweakListOptions))
 
 	kubernetesClientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		log.Printf("Cannot create k8s clientset %s", err)
-		return nil
+		return nil, errors.WithMessage(err, "cannot create k8s clientset")
 	}
 	tektonClientset, err := tektonclient.NewForConfig(config)
 	if err != nil {
-		log.Printf("Cannot create Tekton clientset %s", err)
-		return nil
+		return nil, errors.WithMessage(err, "cannot create Tekton clientset")
 	}
-	tektonInformerFactory := tektoninformers.NewSharedInformerFactory(tektonClientset, resyncPeriod)
+	tektonInformerFactory := tektoninformers.NewSharedInformerFactoryWithOptions(tektonClientset, resyncPeriod,
+		tektoninformers.WithTweakListOptions(scope.TweakTaskRunListOptions))
 	return &clientFactory{
 		kubernetesClientset:    kubernetesClientset,
 		stewardClientset:       stewardClientset,
 		stewardInformerFactory: stewardInformerFactory,
 		tektonClientset:        tektonClientset,
 		tektonInformerFactory:  tektonInformerFactory,
-	}
+	}, nil
 }
 
 // StewardInformerFactory returns Informer Factory for steward
@@ -75,11 +80,21 @@ func (f *clientFactory) StewardV1alpha1() stewardv1alpha1.StewardV1alpha1Interfa
 	return f.stewardClientset.StewardV1alpha1()
 }
 
+// CoordinationV1beta1 returns CoordinationV1beta1 kubernetesClients
+func (f *clientFactory) CoordinationV1beta1() coordinationv1beta1.CoordinationV1beta1Interface {
+	return f.kubernetesClientset.CoordinationV1beta1()
+}
+
 // CoreV1 returns CoreV1 kubernetesClients
 func (f *clientFactory) CoreV1() corev1.CoreV1Interface {
 	return f.kubernetesClientset.CoreV1()
 }
 
+// NetworkingV1 returns NetworkingV1 kubernetesClients
+func (f *clientFactory) NetworkingV1() networkingv1.NetworkingV1Interface {
+	return f.kubernetesClientset.NetworkingV1()
+}
+
 // RbacV1beta1 returns RbacV1beta1 kubernetesClients
 func (f *clientFactory) RbacV1beta1() rbacv1beta1.RbacV1beta1Interface {
 	return f.kubernetesClientset.RbacV1beta1()
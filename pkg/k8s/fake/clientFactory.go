@@ -15,7 +15,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	kubernetes "k8s.io/client-go/kubernetes/fake"
+	coordinationv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	networkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 	rbacv1beta1 "k8s.io/client-go/kubernetes/typed/rbac/v1beta1"
 )
 
@@ -73,16 +75,38 @@ func (f *ClientFactory) StewardV1alpha1() stewardv1alpha1.StewardV1alpha1Interfa
 	return f.stewardClientset.StewardV1alpha1()
 }
 
+// StewardClientset returns the fake Steward clientset, e.g. to inspect
+// actions or to add reactors.
+func (f *ClientFactory) StewardClientset() *steward.Clientset {
+	return f.stewardClientset
+}
+
 // StewardInformerFactory returns the informer factory for Steward
 func (f *ClientFactory) StewardInformerFactory() stewardinformer.SharedInformerFactory {
 	return f.stewardInformerFactory
 }
 
+// KubernetesClientset returns the fake Kubernetes clientset, e.g. to
+// inspect actions or to add reactors.
+func (f *ClientFactory) KubernetesClientset() *kubernetes.Clientset {
+	return f.kubernetesClientset
+}
+
+// CoordinationV1beta1 returns fake CoordinationV1beta1 clients
+func (f *ClientFactory) CoordinationV1beta1() coordinationv1beta1.CoordinationV1beta1Interface {
+	return f.kubernetesClientset.CoordinationV1beta1()
+}
+
 // CoreV1 returns fake CoreV1 clients
 func (f *ClientFactory) CoreV1() corev1.CoreV1Interface {
 	return f.kubernetesClientset.CoreV1()
 }
 
+// NetworkingV1 returns fake NetworkingV1 clients
+func (f *ClientFactory) NetworkingV1() networkingv1.NetworkingV1Interface {
+	return f.kubernetesClientset.NetworkingV1()
+}
+
 // RbacV1beta1 returns fake RbacV1beta1 clients
 func (f *ClientFactory) RbacV1beta1() rbacv1beta1.RbacV1beta1Interface {
 	return f.kubernetesClientset.RbacV1beta1()
@@ -28,6 +28,7 @@
 package mocks
 
 import (
+	context "context"
 	v1alpha1 "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	v1alpha10 "github.com/SAP/stewardci-core/pkg/client/clientset/versioned/typed/steward/v1alpha1"
 	externalversions "github.com/SAP/stewardci-core/pkg/client/informers/externalversions"
@@ -36,8 +37,11 @@ import (
 	externalversions0 "github.com/SAP/stewardci-core/pkg/tektonclient/informers/externalversions"
 	gomock "github.com/golang/mock/gomock"
 	v1 "k8s.io/api/core/v1"
-	v10 "k8s.io/client-go/kubernetes/typed/core/v1"
-	v1beta1 "k8s.io/client-go/kubernetes/typed/rbac/v1beta1"
+	v10 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	v11 "k8s.io/client-go/kubernetes/typed/core/v1"
+	v12 "k8s.io/client-go/kubernetes/typed/networking/v1"
+	v1beta10 "k8s.io/client-go/kubernetes/typed/rbac/v1beta1"
 	reflect "reflect"
 )
 
@@ -64,6 +68,20 @@ func (m *MockPipelineRun) EXPECT() *MockPipelineRunMockRecorder {
 	return m.recorder
 }
 
+// AddAnnotations mocks base method
+func (m *MockPipelineRun) AddAnnotations(arg0 map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAnnotations", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAnnotations indicates an expected call of AddAnnotations
+func (mr *MockPipelineRunMockRecorder) AddAnnotations(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAnnotations", reflect.TypeOf((*MockPipelineRun)(nil).AddAnnotations), arg0)
+}
+
 // AddFinalizer mocks base method
 func (m *MockPipelineRun) AddFinalizer() error {
 	m.ctrl.T.Helper()
@@ -78,6 +96,20 @@ func (mr *MockPipelineRunMockRecorder) AddFinalizer() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFinalizer", reflect.TypeOf((*MockPipelineRun)(nil).AddFinalizer))
 }
 
+// CommitStatus mocks base method
+func (m *MockPipelineRun) CommitStatus() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitStatus")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CommitStatus indicates an expected call of CommitStatus
+func (mr *MockPipelineRunMockRecorder) CommitStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitStatus", reflect.TypeOf((*MockPipelineRun)(nil).CommitStatus))
+}
+
 // DeleteFinalizerIfExists mocks base method
 func (m *MockPipelineRun) DeleteFinalizerIfExists() error {
 	m.ctrl.T.Helper()
@@ -93,12 +125,11 @@ func (mr *MockPipelineRunMockRecorder) DeleteFinalizerIfExists() *gomock.Call {
 }
 
 // FinishState mocks base method
-func (m *MockPipelineRun) FinishState() (*v1alpha1.StateItem, error) {
+func (m *MockPipelineRun) FinishState() *v1alpha1.StateItem {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "FinishState")
 	ret0, _ := ret[0].(*v1alpha1.StateItem)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	return ret0
 }
 
 // FinishState indicates an expected call of FinishState
@@ -107,6 +138,34 @@ func (mr *MockPipelineRunMockRecorder) FinishState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishState", reflect.TypeOf((*MockPipelineRun)(nil).FinishState))
 }
 
+// GetAnnotations mocks base method
+func (m *MockPipelineRun) GetAnnotations() map[string]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnnotations")
+	ret0, _ := ret[0].(map[string]string)
+	return ret0
+}
+
+// GetAnnotations indicates an expected call of GetAnnotations
+func (mr *MockPipelineRunMockRecorder) GetAnnotations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnnotations", reflect.TypeOf((*MockPipelineRun)(nil).GetAnnotations))
+}
+
+// GetCreationTimestamp mocks base method
+func (m *MockPipelineRun) GetCreationTimestamp() v10.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCreationTimestamp")
+	ret0, _ := ret[0].(v10.Time)
+	return ret0
+}
+
+// GetCreationTimestamp indicates an expected call of GetCreationTimestamp
+func (mr *MockPipelineRunMockRecorder) GetCreationTimestamp() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreationTimestamp", reflect.TypeOf((*MockPipelineRun)(nil).GetCreationTimestamp))
+}
+
 // GetKey mocks base method
 func (m *MockPipelineRun) GetKey() string {
 	m.ctrl.T.Helper()
@@ -121,6 +180,20 @@ func (mr *MockPipelineRunMockRecorder) GetKey() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKey", reflect.TypeOf((*MockPipelineRun)(nil).GetKey))
 }
 
+// GetLabels mocks base method
+func (m *MockPipelineRun) GetLabels() map[string]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLabels")
+	ret0, _ := ret[0].(map[string]string)
+	return ret0
+}
+
+// GetLabels indicates an expected call of GetLabels
+func (mr *MockPipelineRunMockRecorder) GetLabels() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLabels", reflect.TypeOf((*MockPipelineRun)(nil).GetLabels))
+}
+
 // GetName mocks base method
 func (m *MockPipelineRun) GetName() string {
 	m.ctrl.T.Helper()
@@ -206,11 +279,9 @@ func (mr *MockPipelineRunMockRecorder) HasDeletionTimestamp() *gomock.Call {
 }
 
 // StoreErrorAsMessage mocks base method
-func (m *MockPipelineRun) StoreErrorAsMessage(arg0 error, arg1 string) error {
+func (m *MockPipelineRun) StoreErrorAsMessage(arg0 error, arg1 string) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StoreErrorAsMessage", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "StoreErrorAsMessage", arg0, arg1)
 }
 
 // StoreErrorAsMessage indicates an expected call of StoreErrorAsMessage
@@ -220,11 +291,9 @@ func (mr *MockPipelineRunMockRecorder) StoreErrorAsMessage(arg0, arg1 interface{
 }
 
 // UpdateContainer mocks base method
-func (m *MockPipelineRun) UpdateContainer(arg0 *v1.ContainerState) error {
+func (m *MockPipelineRun) UpdateContainer(arg0 *v1.ContainerState) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateContainer", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "UpdateContainer", arg0)
 }
 
 // UpdateContainer indicates an expected call of UpdateContainer
@@ -246,11 +315,9 @@ func (mr *MockPipelineRunMockRecorder) UpdateLog() *gomock.Call {
 }
 
 // UpdateMessage mocks base method
-func (m *MockPipelineRun) UpdateMessage(arg0 string) error {
+func (m *MockPipelineRun) UpdateMessage(arg0 string) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateMessage", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "UpdateMessage", arg0)
 }
 
 // UpdateMessage indicates an expected call of UpdateMessage
@@ -259,12 +326,34 @@ func (mr *MockPipelineRunMockRecorder) UpdateMessage(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockPipelineRun)(nil).UpdateMessage), arg0)
 }
 
+// UpdateOutputs mocks base method
+func (m *MockPipelineRun) UpdateOutputs(arg0 map[string]string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateOutputs", arg0)
+}
+
+// UpdateOutputs indicates an expected call of UpdateOutputs
+func (mr *MockPipelineRunMockRecorder) UpdateOutputs(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutputs", reflect.TypeOf((*MockPipelineRun)(nil).UpdateOutputs), arg0)
+}
+
+// UpdateRerunOf mocks base method
+func (m *MockPipelineRun) UpdateRerunOf(arg0 string, arg1 int32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateRerunOf", arg0, arg1)
+}
+
+// UpdateRerunOf indicates an expected call of UpdateRerunOf
+func (mr *MockPipelineRunMockRecorder) UpdateRerunOf(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRerunOf", reflect.TypeOf((*MockPipelineRun)(nil).UpdateRerunOf), arg0, arg1)
+}
+
 // UpdateResult mocks base method
-func (m *MockPipelineRun) UpdateResult(arg0 v1alpha1.Result) error {
+func (m *MockPipelineRun) UpdateResult(arg0 v1alpha1.Result) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateResult", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "UpdateResult", arg0)
 }
 
 // UpdateResult indicates an expected call of UpdateResult
@@ -273,12 +362,22 @@ func (mr *MockPipelineRunMockRecorder) UpdateResult(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateResult", reflect.TypeOf((*MockPipelineRun)(nil).UpdateResult), arg0)
 }
 
+// UpdateResultReason mocks base method
+func (m *MockPipelineRun) UpdateResultReason(arg0 v1alpha1.ResultReason) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateResultReason", arg0)
+}
+
+// UpdateResultReason indicates an expected call of UpdateResultReason
+func (mr *MockPipelineRunMockRecorder) UpdateResultReason(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateResultReason", reflect.TypeOf((*MockPipelineRun)(nil).UpdateResultReason), arg0)
+}
+
 // UpdateRunNamespace mocks base method
-func (m *MockPipelineRun) UpdateRunNamespace(arg0 string) error {
+func (m *MockPipelineRun) UpdateRunNamespace(arg0 string) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateRunNamespace", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "UpdateRunNamespace", arg0)
 }
 
 // UpdateRunNamespace indicates an expected call of UpdateRunNamespace
@@ -287,13 +386,26 @@ func (mr *MockPipelineRunMockRecorder) UpdateRunNamespace(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRunNamespace", reflect.TypeOf((*MockPipelineRun)(nil).UpdateRunNamespace), arg0)
 }
 
+// UpdateSpec mocks base method
+func (m *MockPipelineRun) UpdateSpec(arg0 *v1alpha1.PipelineSpec) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSpec", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSpec indicates an expected call of UpdateSpec
+func (mr *MockPipelineRunMockRecorder) UpdateSpec(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSpec", reflect.TypeOf((*MockPipelineRun)(nil).UpdateSpec), arg0)
+}
+
 // UpdateState mocks base method
-func (m *MockPipelineRun) UpdateState(arg0 v1alpha1.State) (*v1alpha1.StateItem, error) {
+func (m *MockPipelineRun) UpdateState(arg0 v1alpha1.State) *v1alpha1.StateItem {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateState", arg0)
 	ret0, _ := ret[0].(*v1alpha1.StateItem)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	return ret0
 }
 
 // UpdateState indicates an expected call of UpdateState
@@ -302,6 +414,42 @@ func (mr *MockPipelineRunMockRecorder) UpdateState(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateState", reflect.TypeOf((*MockPipelineRun)(nil).UpdateState), arg0)
 }
 
+// UpdateSteps mocks base method
+func (m *MockPipelineRun) UpdateSteps(arg0 []v1alpha1.StepStatus) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateSteps", arg0)
+}
+
+// UpdateSteps indicates an expected call of UpdateSteps
+func (mr *MockPipelineRunMockRecorder) UpdateSteps(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSteps", reflect.TypeOf((*MockPipelineRun)(nil).UpdateSteps), arg0)
+}
+
+// UpdateTestSummary mocks base method
+func (m *MockPipelineRun) UpdateTestSummary(arg0 *v1alpha1.TestSummary) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateTestSummary", arg0)
+}
+
+// UpdateTestSummary indicates an expected call of UpdateTestSummary
+func (mr *MockPipelineRunMockRecorder) UpdateTestSummary(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTestSummary", reflect.TypeOf((*MockPipelineRun)(nil).UpdateTestSummary), arg0)
+}
+
+// UpdateTraceParent mocks base method
+func (m *MockPipelineRun) UpdateTraceParent(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateTraceParent", arg0)
+}
+
+// UpdateTraceParent indicates an expected call of UpdateTraceParent
+func (mr *MockPipelineRunMockRecorder) UpdateTraceParent(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTraceParent", reflect.TypeOf((*MockPipelineRun)(nil).UpdateTraceParent), arg0)
+}
+
 // MockClientFactory is a mock of ClientFactory interface
 type MockClientFactory struct {
 	ctrl     *gomock.Controller
@@ -325,11 +473,25 @@ func (m *MockClientFactory) EXPECT() *MockClientFactoryMockRecorder {
 	return m.recorder
 }
 
+// CoordinationV1beta1 mocks base method
+func (m *MockClientFactory) CoordinationV1beta1() v1beta1.CoordinationV1beta1Interface {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CoordinationV1beta1")
+	ret0, _ := ret[0].(v1beta1.CoordinationV1beta1Interface)
+	return ret0
+}
+
+// CoordinationV1beta1 indicates an expected call of CoordinationV1beta1
+func (mr *MockClientFactoryMockRecorder) CoordinationV1beta1() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CoordinationV1beta1", reflect.TypeOf((*MockClientFactory)(nil).CoordinationV1beta1))
+}
+
 // CoreV1 mocks base method
-func (m *MockClientFactory) CoreV1() v10.CoreV1Interface {
+func (m *MockClientFactory) CoreV1() v11.CoreV1Interface {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CoreV1")
-	ret0, _ := ret[0].(v10.CoreV1Interface)
+	ret0, _ := ret[0].(v11.CoreV1Interface)
 	return ret0
 }
 
@@ -339,11 +501,25 @@ func (mr *MockClientFactoryMockRecorder) CoreV1() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CoreV1", reflect.TypeOf((*MockClientFactory)(nil).CoreV1))
 }
 
+// NetworkingV1 mocks base method
+func (m *MockClientFactory) NetworkingV1() v12.NetworkingV1Interface {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NetworkingV1")
+	ret0, _ := ret[0].(v12.NetworkingV1Interface)
+	return ret0
+}
+
+// NetworkingV1 indicates an expected call of NetworkingV1
+func (mr *MockClientFactoryMockRecorder) NetworkingV1() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NetworkingV1", reflect.TypeOf((*MockClientFactory)(nil).NetworkingV1))
+}
+
 // RbacV1beta1 mocks base method
-func (m *MockClientFactory) RbacV1beta1() v1beta1.RbacV1beta1Interface {
+func (m *MockClientFactory) RbacV1beta1() v1beta10.RbacV1beta1Interface {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "RbacV1beta1")
-	ret0, _ := ret[0].(v1beta1.RbacV1beta1Interface)
+	ret0, _ := ret[0].(v1beta10.RbacV1beta1Interface)
 	return ret0
 }
 
@@ -524,30 +700,30 @@ func (m *MockNamespaceManager) EXPECT() *MockNamespaceManagerMockRecorder {
 }
 
 // Create mocks base method
-func (m *MockNamespaceManager) Create(arg0 string, arg1 map[string]string) (string, error) {
+func (m *MockNamespaceManager) Create(arg0 context.Context, arg1 string, arg2 map[string]string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create
-func (mr *MockNamespaceManagerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockNamespaceManagerMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockNamespaceManager)(nil).Create), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockNamespaceManager)(nil).Create), arg0, arg1, arg2)
 }
 
 // Delete mocks base method
-func (m *MockNamespaceManager) Delete(arg0 string) error {
+func (m *MockNamespaceManager) Delete(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete
-func (mr *MockNamespaceManagerMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockNamespaceManagerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockNamespaceManager)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockNamespaceManager)(nil).Delete), arg0, arg1)
 }
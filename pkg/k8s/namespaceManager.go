@@ -1,38 +1,43 @@
 package k8s
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
-	"log"
 	"math/big"
 	"strconv"
 	"strings"
 
+	"github.com/SAP/stewardci-core/pkg/tracing"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 //NamespaceManager manages namespaces
 type NamespaceManager interface {
-	Create(name string, annotations map[string]string) (string, error)
-	Delete(name string) error
+	Create(ctx context.Context, name string, annotations map[string]string) (string, error)
+	Delete(ctx context.Context, name string) error
 }
 
 type namespaceManager struct {
 	nsInterface  corev1.NamespaceInterface
 	prefix       string
 	suffixLength uint8
+	logger       *zap.SugaredLogger
 }
 
 // NewNamespaceManager creates a new NamespaceManager.
-func NewNamespaceManager(factory ClientFactory, prefix string, suffixLength uint8) NamespaceManager {
+func NewNamespaceManager(factory ClientFactory, prefix string, suffixLength uint8, logger *zap.SugaredLogger) NamespaceManager {
 	return &namespaceManager{
 		nsInterface:  factory.CoreV1().Namespaces(),
 		prefix:       prefix,
 		suffixLength: suffixLength,
+		logger:       logger,
 	}
 }
 
@@ -41,13 +46,21 @@ const (
 	labelID     = "id"
 )
 
+// NamespaceSelector returns the label selector of the namespaces created
+// by namespace managers with the given prefix.
+func NamespaceSelector(prefix string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{labelPrefix: prefix})
+}
+
 //Create creates a new namespace.
 //    nameCustomPart	the namespace name will be <prefix>-<nameCustomPart>-<random>
 //    annotations       annotations to create on the namespace
-func (m *namespaceManager) Create(nameCustomPart string, annotations map[string]string) (string, error) {
+func (m *namespaceManager) Create(ctx context.Context, nameCustomPart string, annotations map[string]string) (_ string, err error) {
+	_, span := tracing.Start(ctx, "create namespace")
+	defer func() { tracing.End(span, err) }()
 	name, err := m.generateName(nameCustomPart)
 	if err != nil {
-		log.Printf("Namespace creation failed %s", err)
+		m.logger.Errorw("Namespace creation failed", "error", err)
 		return "", err
 	}
 	meta := metav1.ObjectMeta{
@@ -59,19 +72,22 @@ func (m *namespaceManager) Create(nameCustomPart string, annotations map[string]
 		Annotations: annotations,
 	}
 
+	span.SetAttributes(tracing.KeyName.String(name))
 	namespace := &v1.Namespace{ObjectMeta: meta}
 	createdNamespace, err := m.nsInterface.Create(namespace)
 	if err != nil {
-		log.Printf("Namespace creation failed: %s", err)
+		m.logger.Errorw("Namespace creation failed", "namespace", name, "error", err)
 		return "", err
 	}
-	log.Printf("Namespace '%s' created", createdNamespace.GetName())
+	m.logger.Infow("Namespace created", "namespace", createdNamespace.GetName())
 	return createdNamespace.GetName(), nil
 }
 
 // Delete removes a namespace if existing
 // returns nil error if deletion was successful or namespace did not exist before
-func (m *namespaceManager) Delete(name string) error {
+func (m *namespaceManager) Delete(ctx context.Context, name string) (err error) {
+	_, span := tracing.Start(ctx, "delete namespace", tracing.KeyName.String(name))
+	defer func() { tracing.End(span, err) }()
 	if !strings.HasPrefix(name, m.prefix) {
 		return errors.Errorf("refused to delete namespace '%s': name does not start with '%s'", name, m.prefix)
 	}
@@ -95,7 +111,7 @@ func (m *namespaceManager) Delete(name string) error {
 		}
 		return errors.WithMessagef(err, "error deleting namespace '%s'", name)
 	}
-	log.Printf("deleted namespace '%s'", name)
+	m.logger.Infow("Namespace deleted", "namespace", name)
 	return nil
 }
 
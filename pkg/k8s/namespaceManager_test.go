@@ -1,11 +1,13 @@
 package k8s
 
 import (
+	"context"
 	"math"
 	"strconv"
 	"testing"
 
 	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"go.uber.org/zap"
 	"gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
 	corev1 "k8s.io/api/core/v1"
@@ -17,7 +19,7 @@ func Test_NewNamespaceManager(t *testing.T) {
 	cf := fake.NewClientFactory()
 
 	// EXERCISE
-	result := NewNamespaceManager(cf, "prefix1", 255)
+	result := NewNamespaceManager(cf, "prefix1", 255, zap.NewNop().Sugar())
 
 	// VERIFY
 	assert.Assert(t, result != nil)
@@ -109,10 +111,11 @@ func Test_namespaceManager_Create_uses_generateName(t *testing.T) {
 		nsInterface:  cf.CoreV1().Namespaces(),
 		prefix:       "prefix1",
 		suffixLength: 17,
+		logger:       zap.NewNop().Sugar(),
 	}
 
 	// EXERCISE
-	result, err := examinee.Create("customPart1", map[string]string{})
+	result, err := examinee.Create(context.Background(), "customPart1", map[string]string{})
 
 	// VERIFY
 	assert.NilError(t, err)
@@ -126,7 +129,7 @@ func Test_namespaceManager_Create_Success(t *testing.T) {
 	cf := fake.NewClientFactory(
 	// no objects preexist
 	)
-	examinee := NewNamespaceManager(cf, "", 0)
+	examinee := NewNamespaceManager(cf, "", 0, zap.NewNop().Sugar())
 	annotations := map[string]string{
 		"key1":         "0439u5kfgn",
 		"key2":         "9087652346",
@@ -135,7 +138,7 @@ func Test_namespaceManager_Create_Success(t *testing.T) {
 	}
 
 	// EXERCISE
-	result, err := examinee.Create(namespaceName, annotations)
+	result, err := examinee.Create(context.Background(), namespaceName, annotations)
 
 	// VERIFY
 	assert.NilError(t, err)
@@ -155,10 +158,10 @@ func Test_namespaceManager_Create_ExistsAlready(t *testing.T) {
 	cf := fake.NewClientFactory(
 		fake.Namespace(namespaceName), // existing namespace
 	)
-	examinee := NewNamespaceManager(cf, "", 0)
+	examinee := NewNamespaceManager(cf, "", 0, zap.NewNop().Sugar())
 
 	// EXERCISE
-	result, err := examinee.Create(namespaceName, map[string]string{})
+	result, err := examinee.Create(context.Background(), namespaceName, map[string]string{})
 
 	// VERIFY
 	assert.Assert(t, err != nil)
@@ -171,11 +174,11 @@ func Test_namespaceManager_Delete_Success(t *testing.T) {
 	cf := fake.NewClientFactory(
 		fake.Namespace(namespaceName),
 	)
-	examinee := NewNamespaceManager(cf, "", 0)
+	examinee := NewNamespaceManager(cf, "", 0, zap.NewNop().Sugar())
 	assert.Equal(t, 1, countNamespaces(cf))
 
 	// EXERCISE
-	err := examinee.Delete(namespaceName)
+	err := examinee.Delete(context.Background(), namespaceName)
 
 	// VERIFY
 	assert.NilError(t, err)
@@ -185,10 +188,10 @@ func Test_namespaceManager_Delete_Success(t *testing.T) {
 func Test_namespaceManager_Delete_FailsIfNameDoesNotStartWithPrefix(t *testing.T) {
 	// SETUP
 	cf := fake.NewClientFactory()
-	examinee := NewNamespaceManager(cf, "prefix1", 0)
+	examinee := NewNamespaceManager(cf, "prefix1", 0, zap.NewNop().Sugar())
 
 	// EXERCISE
-	err := examinee.Delete("foo")
+	err := examinee.Delete(context.Background(), "foo")
 
 	// VERIFY
 	assert.Assert(t, err != nil)
@@ -198,8 +201,8 @@ func Test_namespaceManager_Delete_FailsIfNameDoesNotStartWithPrefix(t *testing.T
 func Test_namespaceManager_Delete_FailsIfPrefixLabelDoesNotMatch(t *testing.T) {
 	// SETUP
 	cf := fake.NewClientFactory()
-	examinee := NewNamespaceManager(cf, "prefix1", 0)
-	namespaceName, err := examinee.Create("foo", map[string]string{})
+	examinee := NewNamespaceManager(cf, "prefix1", 0, zap.NewNop().Sugar())
+	namespaceName, err := examinee.Create(context.Background(), "foo", map[string]string{})
 	assert.NilError(t, err)
 
 	namespace, err := cf.CoreV1().Namespaces().Get(namespaceName, metav1.GetOptions{})
@@ -210,7 +213,7 @@ func Test_namespaceManager_Delete_FailsIfPrefixLabelDoesNotMatch(t *testing.T) {
 	cf.CoreV1().Namespaces().Update(namespace)
 
 	// EXERCISE
-	err = examinee.Delete(namespaceName)
+	err = examinee.Delete(context.Background(), namespaceName)
 
 	// VERIFY
 	assert.Assert(t, err != nil)
@@ -222,11 +225,11 @@ func Test_namespaceManager_Delete_NotExisting(t *testing.T) {
 	cf := fake.NewClientFactory(
 	// no namespace preexists
 	)
-	examinee := NewNamespaceManager(cf, "", 0)
+	examinee := NewNamespaceManager(cf, "", 0, zap.NewNop().Sugar())
 	assert.Equal(t, 0, countNamespaces(cf))
 
 	// EXERCISE
-	err := examinee.Delete("foo")
+	err := examinee.Delete(context.Background(), "foo")
 
 	// VERIFY
 	assert.NilError(t, err)
@@ -2,16 +2,17 @@ package k8s
 
 import (
 	"fmt"
-	"log"
 
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	stewardv1alpha1 "github.com/SAP/stewardci-core/pkg/client/clientset/versioned/typed/steward/v1alpha1"
+	listers "github.com/SAP/stewardci-core/pkg/client/listers/steward/v1alpha1"
 	utils "github.com/SAP/stewardci-core/pkg/utils"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 )
 
 // PipelineRun is a wrapper for the K8s PipelineRun resource
@@ -22,24 +23,43 @@ type PipelineRun interface {
 	GetKey() string
 	GetRunNamespace() string
 	GetNamespace() string
+	GetCreationTimestamp() metav1.Time
+	GetAnnotations() map[string]string
+	GetLabels() map[string]string
 	HasDeletionTimestamp() bool
 	AddFinalizer() error
 	DeleteFinalizerIfExists() error
-	UpdateState(api.State) (*api.StateItem, error)
-	FinishState() (*api.StateItem, error)
-	UpdateResult(api.Result) error
-	UpdateContainer(*corev1.ContainerState) error
-	StoreErrorAsMessage(error, string) error
-	UpdateRunNamespace(string) error
-	UpdateMessage(string) error
+	UpdateState(api.State) *api.StateItem
+	FinishState() *api.StateItem
+	UpdateResult(api.Result)
+	UpdateResultReason(api.ResultReason)
+	UpdateContainer(*corev1.ContainerState)
+	UpdateSteps([]api.StepStatus)
+	StoreErrorAsMessage(error, string)
+	UpdateRunNamespace(string)
+	UpdateMessage(string)
+	UpdateOutputs(map[string]string)
+	UpdateTestSummary(*api.TestSummary)
 	UpdateLog()
+	UpdateSpec(*api.PipelineSpec) error
+	UpdateRerunOf(string, int32)
+	UpdateTraceParent(string)
+	AddAnnotations(map[string]string) error
+	CommitStatus() error
 }
 
+// pipelineRun collects changes of the status locally until they are
+// written with CommitStatus, so that a reconciliation updates the status
+// at most once.
 type pipelineRun struct {
-	namespace string
-	client    stewardv1alpha1.PipelineRunInterface
-	name      string
-	cached    *api.PipelineRun
+	namespace     string
+	client        stewardv1alpha1.PipelineRunInterface
+	name          string
+	cached        *api.PipelineRun
+	statusChanged bool
+	// versions records the writes if the pipeline run has been read from
+	// an informer cache, nil otherwise
+	versions *resourceVersions
 }
 
 // PipelineRunFetcher has methods to fetch PipelineRun objects from Kubernetes
@@ -84,16 +104,110 @@ func (rf *pipelineRunFetcher) ByKey(key string) (PipelineRun, error) {
 	return rf.ByName(namespace, name)
 }
 
+type listerPipelineRunFetcher struct {
+	factory  ClientFactory
+	lister   listers.PipelineRunLister
+	versions *resourceVersions
+}
+
+// NewListerPipelineRunFetcher returns a PipelineRunFetcher reading
+// PipelineRun objects from the informer cache of the given factory.
+// Objects are read from Kubernetes instead if the cache has not observed
+// the last update made via the returned PipelineRun wrappers yet.
+// Must be called before the informers of the factory are started.
+func NewListerPipelineRunFetcher(factory ClientFactory) PipelineRunFetcher {
+	return &listerPipelineRunFetcher{
+		factory:  factory,
+		lister:   factory.StewardInformerFactory().Steward().V1alpha1().PipelineRuns().Lister(),
+		versions: newResourceVersions(),
+	}
+}
+
+// ByName fetches PipelineRun resource from the informer cache by name and
+// namespace
+// Return nil,nil if specified pipeline does not exist
+func (rf *listerPipelineRunFetcher) ByName(namespace string, name string) (PipelineRun, error) {
+	client := rf.factory.StewardV1alpha1().PipelineRuns(namespace)
+	result := &pipelineRun{client: client, name: name, namespace: namespace, versions: rf.versions}
+	cached, err := rf.lister.PipelineRuns(namespace).Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			rf.versions.forget(namespace + "/" + name)
+			return nil, nil
+		}
+		return nil, errors.Wrap(err,
+			fmt.Sprintf("Failed to fetch PipelineRun '%s' in namespace '%s'", name, namespace))
+	}
+	if rf.versions.isCurrent(cached) {
+		// objects of the cache must not be modified
+		result.cached = cached.DeepCopy()
+		return result, nil
+	}
+	result.cached, err = result.fetch()
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err,
+			fmt.Sprintf("Failed to fetch PipelineRun '%s' in namespace '%s'", name, namespace))
+	}
+	// the cache is used again once it has observed this version
+	rf.versions.record(result.cached)
+	return result, nil
+}
+
+// ByKey fetches PipelineRun resource from the informer cache
+// Return nil,nil if pipeline with key does not exist
+func (rf *listerPipelineRunFetcher) ByKey(key string) (PipelineRun, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return &pipelineRun{}, err
+	}
+	return rf.ByName(namespace, name)
+}
+
 func (r *pipelineRun) fetch() (*api.PipelineRun, error) {
 	return r.client.Get(r.name, metav1.GetOptions{})
 }
 
-func (r *pipelineRun) update() error {
-	result, err := r.client.Update(r.cached)
-	if err == nil {
-		r.cached = result
+// update applies the given change to the pipeline run and writes it
+// except its status. If the pipeline run has been modified concurrently,
+// the change is applied to the latest version of the pipeline run.
+// Status changes not committed yet are kept.
+func (r *pipelineRun) update(change func(*api.PipelineRun)) error {
+	status := r.cached.Status
+	pipelineRun := r.cached
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		change(pipelineRun)
+		result, err := r.client.Update(pipelineRun)
+		if err == nil {
+			r.cached = result
+			return nil
+		}
+		if k8serrors.IsConflict(err) {
+			latest, fetchErr := r.fetch()
+			if fetchErr != nil {
+				return fetchErr
+			}
+			pipelineRun = latest
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	r.cached.Status = status
+	r.recordWrite()
+	return nil
+}
+
+// recordWrite records the current version of the pipeline run as written,
+// so that subsequent reads from the informer cache do not return older
+// versions.
+func (r *pipelineRun) recordWrite() {
+	if r.versions != nil {
+		r.versions.record(r.cached)
 	}
-	return err
 }
 
 // GetRunNamespace returns the namespace in which the build takes place
@@ -112,6 +226,11 @@ func (r *pipelineRun) GetNamespace() string {
 	return r.cached.GetNamespace()
 }
 
+// GetCreationTimestamp returns the creation timestamp of the underlying pipelineRun object
+func (r *pipelineRun) GetCreationTimestamp() metav1.Time {
+	return r.cached.GetCreationTimestamp()
+}
+
 func (r *pipelineRun) GetName() string {
 	return r.name
 }
@@ -126,63 +245,71 @@ func (r *pipelineRun) GetSpec() *api.PipelineSpec {
 	return &r.cached.Spec
 }
 
-func (r *pipelineRun) UpdateState(state api.State) (*api.StateItem, error) {
-	// UpdateState set end time of current (defined) state (A) and store it to the history.
-	// It also creates a new current state (B) with start time.
-	// Returns the state details of state A
-	log.Printf("New State: %s", state)
+// UpdateState sets the end time of the current (defined) state (A) and
+// stores it to the history. It also creates a new current state (B) with
+// start time.
+// Returns the state details of state A.
+func (r *pipelineRun) UpdateState(state api.State) *api.StateItem {
 	now := metav1.Now()
-	oldstate, err := r.FinishState()
-	if err != nil {
-		return nil, err
-	}
+	oldstate := r.FinishState()
 	newState := api.StateItem{State: state, StartedAt: now}
 	r.cached.Status.StateDetails = newState
 	r.cached.Status.State = state
-	return oldstate, r.updateStatus()
+	return oldstate
 }
 
 // FinishState set end time stamp of the current (defined) state and add it to the history
 // Returns the state details
-func (r *pipelineRun) FinishState() (*api.StateItem, error) {
+func (r *pipelineRun) FinishState() *api.StateItem {
+	r.statusChanged = true
 	state := r.cached.Status.StateDetails
 	if state.State != api.StateUndefined {
 		state.FinishedAt = metav1.Now()
 		his := r.cached.Status.StateHistory
 		his = append(his, state)
 		r.cached.Status.StateHistory = his
-		return &state, r.updateStatus()
+		return &state
 	}
-	return nil, r.updateStatus()
+	return nil
 }
 
 // UpdateResult of the pipeline run
-func (r *pipelineRun) UpdateResult(result api.Result) error {
+func (r *pipelineRun) UpdateResult(result api.Result) {
 	r.cached.Status.Result = result
-	return r.updateStatus()
+	r.statusChanged = true
+}
+
+// UpdateResultReason stores the reason for the result of the pipeline run
+func (r *pipelineRun) UpdateResultReason(reason api.ResultReason) {
+	r.cached.Status.ResultReason = reason
+	r.statusChanged = true
 }
 
 // UpdateContainer ...
-func (r *pipelineRun) UpdateContainer(c *corev1.ContainerState) error {
+func (r *pipelineRun) UpdateContainer(c *corev1.ContainerState) {
 	if c == nil {
-		return nil
+		return
 	}
 	r.cached.Status.Container = *c
-	return r.updateStatus()
+	r.statusChanged = true
+}
+
+// UpdateSteps stores the status of the steps executing the pipeline run
+func (r *pipelineRun) UpdateSteps(steps []api.StepStatus) {
+	r.cached.Status.Steps = steps
+	r.statusChanged = true
 }
 
 // StoreErrorAsMessage stores the error as message in the status
-func (r *pipelineRun) StoreErrorAsMessage(err error, message string) error {
+func (r *pipelineRun) StoreErrorAsMessage(err error, message string) {
 	if err != nil {
 		text := fmt.Sprintf("ERROR: %s (%s - status:%s): %s", utils.Trim(message), r.GetName(), string(r.GetStatus().State), err.Error())
-		log.Printf(text)
-		return r.UpdateMessage(text)
+		r.UpdateMessage(text)
 	}
-	return nil
 }
 
 // UpdateMessage stores string as message in the status
-func (r *pipelineRun) UpdateMessage(message string) error {
+func (r *pipelineRun) UpdateMessage(message string) {
 	old := r.cached.Status.Message
 	if old != "" {
 		his := r.cached.Status.History
@@ -191,21 +318,83 @@ func (r *pipelineRun) UpdateMessage(message string) error {
 	}
 	r.cached.Status.Message = utils.Trim(message)
 	r.cached.Status.MessageShort = utils.ShortenMessage(message, 100)
-	return r.updateStatus()
+	r.statusChanged = true
+}
+
+// UpdateOutputs stores the outputs of the pipeline in the status
+func (r *pipelineRun) UpdateOutputs(outputs map[string]string) {
+	r.cached.Status.Outputs = outputs
+	r.statusChanged = true
+}
+
+// UpdateTestSummary stores the summary of the test reports in the status
+func (r *pipelineRun) UpdateTestSummary(summary *api.TestSummary) {
+	r.cached.Status.TestSummary = summary
+	r.statusChanged = true
 }
 
 // UpdateRunNamespace overrides the namespace in which the builds happens
-func (r *pipelineRun) UpdateRunNamespace(ns string) error {
+func (r *pipelineRun) UpdateRunNamespace(ns string) {
 	r.cached.Status.Namespace = ns
-	return r.updateStatus()
+	r.statusChanged = true
 }
 
 // UpdateLog ...
 func (r *pipelineRun) UpdateLog() {
 	if r.cached.Status.Namespace != "" {
 		r.cached.Status.LogURL = "dummy://foo"
-		r.updateStatus()
+		r.statusChanged = true
+	}
+}
+
+// UpdateSpec replaces the spec of the pipeline run
+func (r *pipelineRun) UpdateSpec(spec *api.PipelineSpec) error {
+	return r.update(func(pipelineRun *api.PipelineRun) {
+		pipelineRun.Spec = *spec
+	})
+}
+
+// UpdateRerunOf records in the status that the pipeline run is a re-run
+// of another pipeline run and the resulting attempt number
+func (r *pipelineRun) UpdateRerunOf(name string, attempt int32) {
+	r.cached.Status.RerunOf = name
+	r.cached.Status.Attempt = attempt
+	r.statusChanged = true
+}
+
+// UpdateTraceParent stores the W3C trace context ("traceparent") of the
+// first reconciliation in the status
+func (r *pipelineRun) UpdateTraceParent(traceParent string) {
+	r.cached.Status.TraceParent = traceParent
+	r.statusChanged = true
+}
+
+// GetAnnotations returns the annotations of the pipeline run
+func (r *pipelineRun) GetAnnotations() map[string]string {
+	return r.cached.GetAnnotations()
+}
+
+// GetLabels returns the labels of the pipeline run
+func (r *pipelineRun) GetLabels() map[string]string {
+	return r.cached.GetLabels()
+}
+
+// AddAnnotations adds the given annotations to the pipeline run,
+// replacing existing values of the same keys
+func (r *pipelineRun) AddAnnotations(annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return nil
 	}
+	return r.update(func(pipelineRun *api.PipelineRun) {
+		merged := map[string]string{}
+		for key, value := range pipelineRun.GetAnnotations() {
+			merged[key] = value
+		}
+		for key, value := range annotations {
+			merged[key] = value
+		}
+		pipelineRun.SetAnnotations(merged)
+	})
 }
 
 //HasDeletionTimestamp returns true if deletion timestamp is set
@@ -215,35 +404,56 @@ func (r *pipelineRun) HasDeletionTimestamp() bool {
 
 // AddFinalizer adds a finalizer to pipeline run
 func (r *pipelineRun) AddFinalizer() error {
-	changed, finalizerList := utils.AddStringIfMissing(r.cached.ObjectMeta.Finalizers, FinalizerName)
+	changed, _ := utils.AddStringIfMissing(r.cached.ObjectMeta.Finalizers, FinalizerName)
 	if changed {
-		r.cached.ObjectMeta.Finalizers = finalizerList
-		return r.update()
+		return r.update(func(pipelineRun *api.PipelineRun) {
+			_, pipelineRun.ObjectMeta.Finalizers = utils.AddStringIfMissing(pipelineRun.ObjectMeta.Finalizers, FinalizerName)
+		})
 	}
 	return nil
 }
 
 // DeleteFinalizerIfExists deletes a finalizer from pipeline run
 func (r *pipelineRun) DeleteFinalizerIfExists() error {
-	changed, finalizerList := utils.RemoveString(r.cached.ObjectMeta.Finalizers, FinalizerName)
+	changed, _ := utils.RemoveString(r.cached.ObjectMeta.Finalizers, FinalizerName)
 	if changed {
-		r.cached.ObjectMeta.Finalizers = finalizerList
-		return r.update()
+		return r.update(func(pipelineRun *api.PipelineRun) {
+			_, pipelineRun.ObjectMeta.Finalizers = utils.RemoveString(pipelineRun.ObjectMeta.Finalizers, FinalizerName)
+		})
 	}
 	return nil
 }
 
-func (r *pipelineRun) updateStatus() error {
-	pipelineRun, err := r.fetch()
-	if err != nil {
-		return err
+// CommitStatus writes the status changes made since the last commit to
+// the pipeline run. If the pipeline run has been modified concurrently,
+// the status is written to the latest version of the pipeline run.
+func (r *pipelineRun) CommitStatus() error {
+	if !r.statusChanged {
+		return nil
 	}
-	pipelineRun.Status = r.cached.Status
-	result, err := r.client.UpdateStatus(pipelineRun)
+	status := r.cached.Status
+	pipelineRun := r.cached
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		pipelineRun.Status = status
+		result, err := r.client.UpdateStatus(pipelineRun)
+		if err == nil {
+			r.cached = result
+			return nil
+		}
+		if k8serrors.IsConflict(err) {
+			latest, fetchErr := r.fetch()
+			if fetchErr != nil {
+				return fetchErr
+			}
+			pipelineRun = latest
+		}
+		return err
+	})
 	if err != nil {
 		return errors.Wrap(err,
 			fmt.Sprintf("Failed to update status of PipelineRun '%s' in namespace '%s'", r.name, r.namespace))
 	}
-	r.cached = result
+	r.statusChanged = false
+	r.recordWrite()
 	return nil
 }
@@ -1,11 +1,15 @@
 package k8s
 
 import (
+	"errors"
 	"testing"
 
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	"github.com/SAP/stewardci-core/pkg/k8s/fake"
 	"gotest.tools/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const message string = "MyMessage"
@@ -44,6 +48,25 @@ func Test__UpdateMessage__works(t *testing.T) {
 	assert.Equal(t, message, r.GetStatus().Message)
 }
 
+func Test__UpdateOutputs__works(t *testing.T) {
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	r.UpdateOutputs(map[string]string{"version": "1.0"})
+	assert.NilError(t, r.CommitStatus())
+	r, _ = NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	assert.DeepEqual(t, map[string]string{"version": "1.0"}, r.GetStatus().Outputs)
+}
+
+func Test__UpdateSteps__works(t *testing.T) {
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	steps := []api.StepStatus{{Name: "jenkinsfile-runner", State: api.StepStateRunning}}
+	r.UpdateSteps(steps)
+	assert.NilError(t, r.CommitStatus())
+	r, _ = NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	assert.DeepEqual(t, steps, r.GetStatus().Steps)
+}
+
 func Test__calling_UpdateState_Once__yieldsNoHistory(t *testing.T) {
 	factory := fake.NewClientFactory(newPipelineRun())
 	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
@@ -86,6 +109,184 @@ func Test__calling_UpdateState_and_FinishState_yieldsHistoryWithOneEntry(t *test
 	assert.Equal(t, 1, len(status.StateHistory))
 }
 
+func Test__CommitStatus__UpdatesStatusOnce(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	clientset := factory.StewardClientset()
+	clientset.ClearActions()
+	r.UpdateState(api.StatePreparing)
+	r.UpdateRunNamespace("run-ns1")
+	r.UpdateMessage(message)
+
+	// EXERCISE
+	err := r.CommitStatus()
+
+	// VERIFY
+	assert.NilError(t, err)
+	actions := clientset.Actions()
+	assert.Equal(t, 1, len(actions))
+	assert.Equal(t, "update", actions[0].GetVerb())
+	assert.Equal(t, "status", actions[0].GetSubresource())
+	r, _ = NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	assert.Equal(t, api.StatePreparing, r.GetStatus().State)
+	assert.Equal(t, "run-ns1", r.GetRunNamespace())
+	assert.Equal(t, message, r.GetStatus().Message)
+}
+
+func Test__CommitStatus__Unchanged__DoesNothing(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	clientset := factory.StewardClientset()
+	clientset.ClearActions()
+
+	// EXERCISE
+	err := r.CommitStatus()
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(clientset.Actions()))
+}
+
+func Test__CommitStatus__Conflict__RetriesWithLatestVersion(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	clientset := factory.StewardClientset()
+	conflicts := 0
+	clientset.PrependReactor("update", "pipelineruns", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" || conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, k8serrors.NewConflict(api.Resource("pipelineruns"), run1, errors.New("modified"))
+	})
+	// concurrent modification of the spec
+	modified, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	modified.UpdateSpec(&api.PipelineSpec{Secrets: []string{"secret2"}})
+	clientset.ClearActions()
+	r.UpdateMessage(message)
+
+	// EXERCISE
+	err := r.CommitStatus()
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 1, conflicts)
+	assert.Equal(t, 3, len(clientset.Actions()))
+	assert.Equal(t, "get", clientset.Actions()[1].GetVerb())
+	r, _ = NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	assert.Equal(t, message, r.GetStatus().Message)
+	assert.Equal(t, "secret2", r.GetSpec().Secrets[0])
+}
+
+func Test__AddFinalizer__KeepsUncommittedStatus(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	r.UpdateMessage(message)
+
+	// EXERCISE
+	err := r.AddFinalizer()
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, message, r.GetStatus().Message)
+	assert.NilError(t, r.CommitStatus())
+	r, _ = NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	assert.Equal(t, message, r.GetStatus().Message)
+	assert.DeepEqual(t, []string{FinalizerName}, r.(*pipelineRun).cached.Finalizers)
+}
+
+func Test__ListerFetcher__ReturnsCopyFromCache(t *testing.T) {
+	// SETUP
+	cached := newPipelineRun()
+	factory := fake.NewClientFactory(newPipelineRun())
+	examinee := NewListerPipelineRunFetcher(factory)
+	factory.StewardInformerFactory().Steward().V1alpha1().PipelineRuns().Informer().GetIndexer().Add(cached)
+	factory.StewardClientset().ClearActions()
+
+	// EXERCISE
+	r, err := examinee.ByKey(fake.ObjectKey(run1, ns1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(factory.StewardClientset().Actions()))
+	r.GetSpec().Secrets[0] = "changed"
+	assert.Equal(t, "secret1", cached.Spec.Secrets[0])
+}
+
+func Test__ListerFetcher__NotInCache__ReturnsNil(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory(newPipelineRun())
+	examinee := NewListerPipelineRunFetcher(factory)
+
+	// EXERCISE
+	r, err := examinee.ByName(ns1, run1)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Assert(t, r == nil)
+}
+
+func Test__ListerFetcher__CacheOutdated__FetchesFromKubernetes(t *testing.T) {
+	// SETUP
+	live := newPipelineRun()
+	live.ResourceVersion = "2"
+	live.Status.State = api.StateWaiting
+	factory := fake.NewClientFactory(live)
+	examinee := NewListerPipelineRunFetcher(factory)
+	indexer := factory.StewardInformerFactory().Steward().V1alpha1().PipelineRuns().Informer().GetIndexer()
+	outdated := newPipelineRun()
+	outdated.ResourceVersion = "1"
+	indexer.Add(outdated)
+	examinee.(*listerPipelineRunFetcher).versions.record(live)
+
+	// EXERCISE
+	r, err := examinee.ByName(ns1, run1)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, api.StateWaiting, r.GetStatus().State)
+
+	// EXERCISE
+	indexer.Update(live)
+	factory.StewardClientset().ClearActions()
+	r, err = examinee.ByName(ns1, run1)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, api.StateWaiting, r.GetStatus().State)
+	assert.Equal(t, 0, len(factory.StewardClientset().Actions()))
+}
+
+func Test__AddFinalizer__Conflict__AppliesChangeToLatestVersion(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory(newPipelineRun())
+	r, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	// concurrent modification of the annotations
+	modified, _ := NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	modified.AddAnnotations(map[string]string{"foo": "bar"})
+	conflicts := 0
+	factory.StewardClientset().PrependReactor("update", "pipelineruns", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, k8serrors.NewConflict(api.Resource("pipelineruns"), run1, errors.New("modified"))
+	})
+
+	// EXERCISE
+	err := r.AddFinalizer()
+
+	// VERIFY
+	assert.NilError(t, err)
+	r, _ = NewPipelineRunFetcher(factory).ByName(ns1, run1)
+	assert.DeepEqual(t, []string{FinalizerName}, r.(*pipelineRun).cached.Finalizers)
+	assert.Equal(t, "bar", r.GetAnnotations()["foo"])
+}
+
 func newPipelineRun() *api.PipelineRun {
 	return fake.PipelineRun(run1, ns1, api.PipelineSpec{
 		Secrets: []string{"secret1"},
@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	errors "github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CopyResourceQuota creates a copy of the resource quota with the given name
+// from the source namespace in the target namespace.
+func CopyResourceQuota(factory ClientFactory, name string, sourceNamespace string, targetNamespace string) error {
+	template, err := factory.CoreV1().ResourceQuotas(sourceNamespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return errors.WithMessagef(err, "could not get resource quota template '%s' in namespace '%s'", name, sourceNamespace)
+	}
+	quota := &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: targetNamespace,
+			Labels:    template.GetLabels(),
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	_, err = factory.CoreV1().ResourceQuotas(targetNamespace).Create(quota)
+	if err != nil {
+		return errors.WithMessagef(err, "could not create resource quota '%s' in namespace '%s'", name, targetNamespace)
+	}
+	return nil
+}
+
+// CopyLimitRange creates a copy of the limit range with the given name
+// from the source namespace in the target namespace.
+func CopyLimitRange(factory ClientFactory, name string, sourceNamespace string, targetNamespace string) error {
+	return CopyLimitRangeWithDefaults(factory, name, sourceNamespace, targetNamespace, nil)
+}
+
+// CopyLimitRangeWithDefaults creates a copy of the limit range with the
+// given name from the source namespace in the target namespace. The given
+// default resources of containers, if any, replace the defaults of the
+// template for the same resources.
+func CopyLimitRangeWithDefaults(factory ClientFactory, name string, sourceNamespace string, targetNamespace string, defaults *v1.ResourceRequirements) error {
+	template, err := factory.CoreV1().LimitRanges(sourceNamespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return errors.WithMessagef(err, "could not get limit range template '%s' in namespace '%s'", name, sourceNamespace)
+	}
+	limitRange := &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: targetNamespace,
+			Labels:    template.GetLabels(),
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	if defaults != nil {
+		mergeContainerDefaults(&limitRange.Spec, defaults)
+	}
+	_, err = factory.CoreV1().LimitRanges(targetNamespace).Create(limitRange)
+	if err != nil {
+		return errors.WithMessagef(err, "could not create limit range '%s' in namespace '%s'", name, targetNamespace)
+	}
+	return nil
+}
+
+// mergeContainerDefaults sets the given default resources in the container
+// limits of the given limit range spec, adding container limits if there
+// are none.
+func mergeContainerDefaults(spec *v1.LimitRangeSpec, defaults *v1.ResourceRequirements) {
+	merged := false
+	for i := range spec.Limits {
+		item := &spec.Limits[i]
+		if item.Type != v1.LimitTypeContainer {
+			continue
+		}
+		item.Default = mergeResourceList(item.Default, defaults.Limits)
+		item.DefaultRequest = mergeResourceList(item.DefaultRequest, defaults.Requests)
+		merged = true
+	}
+	if !merged {
+		spec.Limits = append(spec.Limits, v1.LimitRangeItem{
+			Type:           v1.LimitTypeContainer,
+			Default:        defaults.Limits,
+			DefaultRequest: defaults.Requests,
+		})
+	}
+}
+
+// mergeResourceList returns the given resource list with the quantities of
+// the given overrides.
+func mergeResourceList(list v1.ResourceList, overrides v1.ResourceList) v1.ResourceList {
+	if len(overrides) == 0 {
+		return list
+	}
+	if list == nil {
+		list = v1.ResourceList{}
+	}
+	for name, quantity := range overrides {
+		list[name] = quantity
+	}
+	return list
+}
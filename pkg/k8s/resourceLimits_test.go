@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_CopyResourceQuota_works(t *testing.T) {
+	// SETUP
+	template := &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota1", Namespace: ns1, Labels: map[string]string{"label1": "value1"}},
+		Spec: v1.ResourceQuotaSpec{
+			Hard: v1.ResourceList{v1.ResourcePods: resource.MustParse("5")},
+		},
+	}
+	factory := fake.NewClientFactory(template)
+
+	// EXERCISE
+	err := CopyResourceQuota(factory, "quota1", ns1, ns2)
+
+	// VERIFY
+	assert.NilError(t, err)
+	quota, err := factory.CoreV1().ResourceQuotas(ns2).Get("quota1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, quota.Spec.Hard.Pods().Cmp(resource.MustParse("5")) == 0)
+	assert.DeepEqual(t, template.GetLabels(), quota.GetLabels())
+}
+
+func Test_CopyResourceQuota_failsIfTemplateNotExisting(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory()
+
+	// EXERCISE
+	err := CopyResourceQuota(factory, "quota1", ns1, ns2)
+
+	// VERIFY
+	assert.Error(t, err, `could not get resource quota template 'quota1' in namespace 'namespace1': resourcequotas "quota1" not found`)
+}
+
+func Test_CopyLimitRange_works(t *testing.T) {
+	// SETUP
+	template := &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits1", Namespace: ns1},
+		Spec: v1.LimitRangeSpec{
+			Limits: []v1.LimitRangeItem{{
+				Type:    v1.LimitTypeContainer,
+				Default: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+			}},
+		},
+	}
+	factory := fake.NewClientFactory(template)
+
+	// EXERCISE
+	err := CopyLimitRange(factory, "limits1", ns1, ns2)
+
+	// VERIFY
+	assert.NilError(t, err)
+	limitRange, err := factory.CoreV1().LimitRanges(ns2).Get("limits1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(limitRange.Spec.Limits))
+	assert.Assert(t, limitRange.Spec.Limits[0].Default.Memory().Cmp(resource.MustParse("1Gi")) == 0)
+}
+
+func Test_CopyLimitRangeWithDefaults_mergesDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		limit v1.LimitRangeItem
+	}{
+		{"ContainerLimits", v1.LimitRangeItem{
+			Type:    v1.LimitTypeContainer,
+			Default: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi"), v1.ResourceCPU: resource.MustParse("1")},
+			Max:     v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+		}},
+		{"NoContainerLimits", v1.LimitRangeItem{
+			Type: v1.LimitTypePod,
+			Max:  v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			template := &v1.LimitRange{
+				ObjectMeta: metav1.ObjectMeta{Name: "limits1", Namespace: ns1},
+				Spec:       v1.LimitRangeSpec{Limits: []v1.LimitRangeItem{tc.limit}},
+			}
+			factory := fake.NewClientFactory(template)
+			defaults := &v1.ResourceRequirements{
+				Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")},
+				Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+			}
+
+			// EXERCISE
+			err := CopyLimitRangeWithDefaults(factory, "limits1", ns1, ns2, defaults)
+
+			// VERIFY
+			assert.NilError(t, err)
+			limitRange, err := factory.CoreV1().LimitRanges(ns2).Get("limits1", metav1.GetOptions{})
+			assert.NilError(t, err)
+			var container *v1.LimitRangeItem
+			for i, item := range limitRange.Spec.Limits {
+				if item.Type == v1.LimitTypeContainer {
+					assert.Assert(t, container == nil)
+					container = &limitRange.Spec.Limits[i]
+				}
+			}
+			assert.Assert(t, container != nil)
+			assert.Assert(t, container.Default.Memory().Cmp(resource.MustParse("2Gi")) == 0)
+			assert.Assert(t, container.DefaultRequest.Memory().Cmp(resource.MustParse("1Gi")) == 0)
+			assert.Assert(t, container.Default.Cpu().Cmp(*tc.limit.Default.Cpu()) == 0)
+			assert.Assert(t, limitRange.Spec.Limits[0].Max.Memory().Cmp(resource.MustParse("4Gi")) == 0)
+			// the template is not modified
+			template, err = factory.CoreV1().LimitRanges(ns1).Get("limits1", metav1.GetOptions{})
+			assert.NilError(t, err)
+			assert.Assert(t, equality.Semantic.DeepEqual([]v1.LimitRangeItem{tc.limit}, template.Spec.Limits))
+		})
+	}
+}
+
+func Test_CopyLimitRange_failsIfTemplateNotExisting(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory()
+
+	// EXERCISE
+	err := CopyLimitRange(factory, "limits1", ns1, ns2)
+
+	// VERIFY
+	assert.Error(t, err, `could not get limit range template 'limits1' in namespace 'namespace1': limitranges "limits1" not found`)
+}
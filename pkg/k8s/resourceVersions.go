@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceVersions records the resource versions of objects written by
+// the controller, so that reads from an informer cache which has not
+// observed the own writes yet can be detected.
+type resourceVersions struct {
+	mutex    sync.Mutex
+	versions map[string]string
+}
+
+func newResourceVersions() *resourceVersions {
+	return &resourceVersions{versions: map[string]string{}}
+}
+
+// record records the version of the given object written by the
+// controller. Objects which are deleted after the write are forgotten.
+func (v *resourceVersions) record(object metav1.Object) {
+	key, err := cache.MetaNamespaceKeyFunc(object)
+	if err != nil {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if object.GetDeletionTimestamp() != nil && len(object.GetFinalizers()) == 0 {
+		delete(v.versions, key)
+		return
+	}
+	v.versions[key] = object.GetResourceVersion()
+}
+
+// forget removes the recorded version of the object with the given key.
+func (v *resourceVersions) forget(key string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	delete(v.versions, key)
+}
+
+// isCurrent returns whether the given cached object contains the last
+// write of the controller, if any. Once the cache has observed the write,
+// the recorded version is not needed anymore.
+// As resource versions must not be compared other than for equality, a
+// cached object with another version than the recorded one is not
+// considered current, even if it is newer.
+func (v *resourceVersions) isCurrent(object metav1.Object) bool {
+	key, err := cache.MetaNamespaceKeyFunc(object)
+	if err != nil {
+		return false
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	version, found := v.versions[key]
+	if !found {
+		return true
+	}
+	if version != object.GetResourceVersion() {
+		return false
+	}
+	delete(v.versions, key)
+	return true
+}
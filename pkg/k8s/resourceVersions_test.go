@@ -0,0 +1,44 @@
+package k8s
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newObjectMeta(resourceVersion string) *metav1.ObjectMeta {
+	return &metav1.ObjectMeta{Name: run1, Namespace: ns1, ResourceVersion: resourceVersion}
+}
+
+func Test_resourceVersions_isCurrent(t *testing.T) {
+	// SETUP
+	examinee := newResourceVersions()
+
+	// VERIFY
+	assert.Assert(t, examinee.isCurrent(newObjectMeta("1")))
+
+	// EXERCISE
+	examinee.record(newObjectMeta("2"))
+
+	// VERIFY
+	assert.Assert(t, !examinee.isCurrent(newObjectMeta("1")))
+	assert.Assert(t, !examinee.isCurrent(newObjectMeta("3")))
+	assert.Assert(t, examinee.isCurrent(newObjectMeta("2")))
+	assert.Equal(t, 0, len(examinee.versions))
+}
+
+func Test_resourceVersions_record_DeletedObject_Forgets(t *testing.T) {
+	// SETUP
+	examinee := newResourceVersions()
+	examinee.record(newObjectMeta("1"))
+	deleted := newObjectMeta("2")
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	// EXERCISE
+	examinee.record(deleted)
+
+	// VERIFY
+	assert.Equal(t, 0, len(examinee.versions))
+}
@@ -0,0 +1,228 @@
+package k8s
+
+import (
+	"flag"
+	"sort"
+	"strings"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	steward "github.com/SAP/stewardci-core/pkg/client/clientset/versioned"
+	stewardinformer "github.com/SAP/stewardci-core/pkg/client/informers/externalversions"
+	stewardv1alpha1informer "github.com/SAP/stewardci-core/pkg/client/informers/externalversions/steward/v1alpha1"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ScopeConfig defines the objects a controller instance is responsible
+// for. An empty configuration selects all objects of the cluster.
+type ScopeConfig struct {
+	// ClientNamespaces is a comma-separated list of Steward client
+	// namespaces. If set, only objects in these namespaces and in the
+	// tenant namespaces of these clients are in scope.
+	ClientNamespaces string
+	// NamespaceSelector is a label selector for namespaces. If set, only
+	// objects in matching namespaces and in the tenant namespaces of
+	// matching client namespaces are in scope.
+	NamespaceSelector string
+	// Selector is a label selector for PipelineRuns and Tenants. If set,
+	// only matching objects are in scope.
+	Selector string
+}
+
+// NewScopeConfig returns the default scope configuration selecting all
+// objects of the cluster.
+func NewScopeConfig() *ScopeConfig {
+	return &ScopeConfig{}
+}
+
+// AddFlags adds command line flags for the configuration to the given
+// flag set.
+func (c *ScopeConfig) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&c.ClientNamespaces, "client-namespaces", c.ClientNamespaces,
+		"comma-separated list of client namespaces to watch, all if empty")
+	flagSet.StringVar(&c.NamespaceSelector, "namespace-selector", c.NamespaceSelector,
+		"label selector of the client or tenant namespaces to watch, all if empty")
+	flagSet.StringVar(&c.Selector, "selector", c.Selector,
+		"label selector of the PipelineRuns and Tenants to watch, all if empty")
+}
+
+// Scope decides whether objects are in the scope of a controller instance.
+type Scope struct {
+	clientNamespaces  map[string]bool
+	namespaceSelector labels.Selector
+	selector          labels.Selector
+	taskRunSelector   labels.Selector
+	namespaceInformer cache.SharedIndexInformer
+}
+
+// NewClusterScope returns a scope containing all objects of the cluster.
+func NewClusterScope() *Scope {
+	return &Scope{}
+}
+
+// NewScope creates a new scope for the given configuration.
+// Namespaces are watched using the given client if the scope is restricted
+// to namespaces.
+func NewScope(config *ScopeConfig, client corev1.NamespacesGetter, resyncPeriod time.Duration) (*Scope, error) {
+	scope := &Scope{}
+	for _, namespace := range strings.Split(config.ClientNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			if scope.clientNamespaces == nil {
+				scope.clientNamespaces = map[string]bool{}
+			}
+			scope.clientNamespaces[namespace] = true
+		}
+	}
+	var err error
+	if strings.TrimSpace(config.NamespaceSelector) != "" {
+		if scope.namespaceSelector, err = labels.Parse(config.NamespaceSelector); err != nil {
+			return nil, errors.WithMessagef(err, "invalid namespace selector '%s'", config.NamespaceSelector)
+		}
+	}
+	if strings.TrimSpace(config.Selector) != "" {
+		if scope.selector, err = labels.Parse(config.Selector); err != nil {
+			return nil, errors.WithMessagef(err, "invalid selector '%s'", config.Selector)
+		}
+	}
+	scope.taskRunSelector = scope.selector
+	if scope.clientNamespaces != nil {
+		names := make([]string, 0, len(scope.clientNamespaces))
+		for name := range scope.clientNamespaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		requirement, err := labels.NewRequirement(api.LabelClientNamespace, selection.In, names)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid client namespaces '%s'", config.ClientNamespaces)
+		}
+		if scope.taskRunSelector == nil {
+			scope.taskRunSelector = labels.NewSelector()
+		}
+		scope.taskRunSelector = scope.taskRunSelector.Add(*requirement)
+	}
+	if scope.isNamespaceRestricted() {
+		scope.namespaceInformer = cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.Namespaces().List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.Namespaces().Watch(options)
+			},
+		}, &v1.Namespace{}, resyncPeriod, cache.Indexers{})
+	}
+	return scope, nil
+}
+
+// Run watches the namespaces, if required, until the stop channel is closed.
+func (s *Scope) Run(stopCh <-chan struct{}) {
+	if s.namespaceInformer != nil {
+		s.namespaceInformer.Run(stopCh)
+	}
+}
+
+// HasSynced returns whether the namespaces required to decide about the
+// scope of objects are known.
+func (s *Scope) HasSynced() bool {
+	return s.namespaceInformer == nil || s.namespaceInformer.HasSynced()
+}
+
+// TweakListOptions restricts list and watch requests of informers for
+// PipelineRuns and Tenants to the label selector of the scope.
+// Namespace restrictions cannot be applied, as the API server does not
+// support listing objects of several namespaces or of namespaces selected
+// by labels, and PipelineRuns do not carry labels identifying their client
+// namespace. They must be checked via `Contains`. Unless restricted by
+// `RestrictTenantInformer`, the informer caches therefore stay cluster-wide
+// if the scope is restricted to namespaces only.
+func (s *Scope) TweakListOptions(options *metav1.ListOptions) {
+	if s.selector != nil {
+		options.LabelSelector = s.selector.String()
+	}
+}
+
+// TweakTaskRunListOptions restricts list and watch requests of informers
+// for Tekton TaskRuns to the scope. TaskRuns created by Steward carry the
+// labels of their PipelineRun and the client namespace label, so the label
+// selector and the client namespaces of the scope are applied. The
+// namespace selector cannot be applied and must be checked via `Contains`
+// on the PipelineRun of a TaskRun.
+func (s *Scope) TweakTaskRunListOptions(options *metav1.ListOptions) {
+	if s.taskRunSelector != nil {
+		options.LabelSelector = s.taskRunSelector.String()
+	}
+}
+
+// RestrictTenantInformer restricts the Tenant informer of the given factory
+// to the client namespace if the scope contains a single client namespace,
+// as Tenants are located in client namespaces. It must be called before the
+// Tenant informer of the factory is used. The PipelineRun informer cannot
+// be restricted this way, because PipelineRuns are located in the tenant
+// namespaces of the clients.
+func (s *Scope) RestrictTenantInformer(factory stewardinformer.SharedInformerFactory) {
+	if len(s.clientNamespaces) != 1 {
+		return
+	}
+	var namespace string
+	for name := range s.clientNamespaces {
+		namespace = name
+	}
+	factory.InformerFor(&api.Tenant{}, func(client steward.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		return stewardv1alpha1informer.NewFilteredTenantInformer(client, namespace, resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, s.TweakListOptions)
+	})
+}
+
+// Contains returns whether the given PipelineRun or Tenant is in scope.
+func (s *Scope) Contains(object metav1.Object) bool {
+	if s.selector != nil && !s.selector.Matches(labels.Set(object.GetLabels())) {
+		return false
+	}
+	if !s.isNamespaceRestricted() {
+		return true
+	}
+	namespace := s.getNamespace(object.GetNamespace())
+	if namespace == nil {
+		return false
+	}
+	// an object is in scope if its namespace or the client namespace
+	// its namespace belongs to is in scope
+	if s.containsNamespace(namespace) {
+		return true
+	}
+	clientNamespaceName := namespace.GetAnnotations()[api.AnnotationClientNamespace]
+	if clientNamespaceName == "" {
+		return false
+	}
+	clientNamespace := s.getNamespace(clientNamespaceName)
+	return clientNamespace != nil && s.containsNamespace(clientNamespace)
+}
+
+func (s *Scope) isNamespaceRestricted() bool {
+	return s.clientNamespaces != nil || s.namespaceSelector != nil
+}
+
+func (s *Scope) containsNamespace(namespace *v1.Namespace) bool {
+	if s.clientNamespaces != nil && !s.clientNamespaces[namespace.GetName()] {
+		return false
+	}
+	if s.namespaceSelector != nil && !s.namespaceSelector.Matches(labels.Set(namespace.GetLabels())) {
+		return false
+	}
+	return true
+}
+
+func (s *Scope) getNamespace(name string) *v1.Namespace {
+	obj, exists, err := s.namespaceInformer.GetStore().GetByKey(name)
+	if err != nil || !exists {
+		return nil
+	}
+	return obj.(*v1.Namespace)
+}
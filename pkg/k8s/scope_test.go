@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newScopeTestNamespace(name string, labels map[string]string, clientNamespace string) *v1.Namespace {
+	namespace := fake.Namespace(name)
+	namespace.SetLabels(labels)
+	if clientNamespace != "" {
+		namespace.SetAnnotations(map[string]string{api.AnnotationClientNamespace: clientNamespace})
+	}
+	return namespace
+}
+
+func newScopeTestObject(namespace string, labels map[string]string) metav1.Object {
+	return &metav1.ObjectMeta{Name: "object1", Namespace: namespace, Labels: labels}
+}
+
+func Test_Scope_Contains(t *testing.T) {
+	cf := fake.NewClientFactory(
+		newScopeTestNamespace("client1", map[string]string{"env": "prod"}, ""),
+		newScopeTestNamespace("client2", map[string]string{"env": "staging"}, ""),
+		newScopeTestNamespace("tenant1", nil, "client1"),
+		newScopeTestNamespace("tenant2", nil, "client2"),
+		newScopeTestNamespace("tenant3", map[string]string{"env": "prod"}, "client2"),
+	)
+	for _, tc := range []struct {
+		name     string
+		config   ScopeConfig
+		object   metav1.Object
+		expected bool
+	}{
+		{"Cluster", ScopeConfig{}, newScopeTestObject("tenant2", nil), true},
+		{"ClientNamespace", ScopeConfig{ClientNamespaces: "client1"}, newScopeTestObject("client1", nil), true},
+		{"ClientNamespaceOther", ScopeConfig{ClientNamespaces: "client1"}, newScopeTestObject("client2", nil), false},
+		{"ClientNamespaceOfTenant", ScopeConfig{ClientNamespaces: " client3, client1"}, newScopeTestObject("tenant1", nil), true},
+		{"ClientNamespaceOfTenantOther", ScopeConfig{ClientNamespaces: "client1"}, newScopeTestObject("tenant2", nil), false},
+		{"UnknownNamespace", ScopeConfig{ClientNamespaces: "client1"}, newScopeTestObject("unknown1", nil), false},
+		{"NamespaceSelector", ScopeConfig{NamespaceSelector: "env=prod"}, newScopeTestObject("client1", nil), true},
+		{"NamespaceSelectorOther", ScopeConfig{NamespaceSelector: "env=prod"}, newScopeTestObject("client2", nil), false},
+		{"NamespaceSelectorOfClient", ScopeConfig{NamespaceSelector: "env=prod"}, newScopeTestObject("tenant1", nil), true},
+		{"NamespaceSelectorOfTenant", ScopeConfig{NamespaceSelector: "env=prod"}, newScopeTestObject("tenant3", nil), true},
+		{"NamespaceSelectorOfClientOther", ScopeConfig{NamespaceSelector: "env=prod"}, newScopeTestObject("tenant2", nil), false},
+		{"ClientNamespaceAndNamespaceSelector", ScopeConfig{ClientNamespaces: "client1", NamespaceSelector: "env=staging"}, newScopeTestObject("tenant1", nil), false},
+		{"Selector", ScopeConfig{Selector: "steward=prod"}, newScopeTestObject("tenant2", map[string]string{"steward": "prod"}), true},
+		{"SelectorOther", ScopeConfig{Selector: "steward=prod"}, newScopeTestObject("tenant2", nil), false},
+		{"SelectorAndClientNamespace", ScopeConfig{ClientNamespaces: "client2", Selector: "steward=prod"}, newScopeTestObject("tenant1", map[string]string{"steward": "prod"}), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			examinee, err := NewScope(&tc.config, cf.CoreV1(), time.Minute)
+			assert.NilError(t, err)
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			go examinee.Run(stopCh)
+			assert.Assert(t, cache.WaitForCacheSync(stopCh, examinee.HasSynced))
+
+			// EXERCISE
+			result := examinee.Contains(tc.object)
+
+			// VERIFY
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func Test_Scope_TweakListOptions(t *testing.T) {
+	// SETUP
+	examinee, err := NewScope(&ScopeConfig{Selector: "steward=prod"}, fake.NewClientFactory().CoreV1(), time.Minute)
+	assert.NilError(t, err)
+	options := metav1.ListOptions{}
+
+	// EXERCISE
+	examinee.TweakListOptions(&options)
+
+	// VERIFY
+	assert.Equal(t, "steward=prod", options.LabelSelector)
+}
+
+func Test_Scope_TweakTaskRunListOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   ScopeConfig
+		expected string
+	}{
+		{"Cluster", ScopeConfig{}, ""},
+		{"Selector", ScopeConfig{Selector: "steward=prod"}, "steward=prod"},
+		{"ClientNamespaces", ScopeConfig{ClientNamespaces: "client2, client1"}, "steward.sap.com/client-namespace in (client1,client2)"},
+		{"ClientNamespacesAndSelector", ScopeConfig{ClientNamespaces: "client1", Selector: "steward=prod"}, "steward=prod,steward.sap.com/client-namespace in (client1)"},
+		{"NamespaceSelector", ScopeConfig{NamespaceSelector: "steward=prod"}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			examinee, err := NewScope(&tc.config, fake.NewClientFactory().CoreV1(), time.Minute)
+			assert.NilError(t, err)
+			options := metav1.ListOptions{}
+
+			// EXERCISE
+			examinee.TweakTaskRunListOptions(&options)
+
+			// VERIFY
+			assert.Equal(t, tc.expected, options.LabelSelector)
+		})
+	}
+}
+
+func Test_NewScope_InvalidSelector(t *testing.T) {
+	// EXERCISE
+	_, err := NewScope(&ScopeConfig{Selector: "a=b=c"}, fake.NewClientFactory().CoreV1(), time.Minute)
+
+	// VERIFY
+	assert.ErrorContains(t, err, "invalid selector 'a=b=c'")
+}
+
+func Test_NewScope_InvalidClientNamespaces(t *testing.T) {
+	// EXERCISE
+	_, err := NewScope(&ScopeConfig{ClientNamespaces: "client 1"}, fake.NewClientFactory().CoreV1(), time.Minute)
+
+	// VERIFY
+	assert.ErrorContains(t, err, "invalid client namespaces 'client 1'")
+}
+
+func Test_Scope_RestrictTenantInformer(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		clientNamespaces string
+		expectedTenants  int
+	}{
+		{"Cluster", "", 2},
+		{"SingleClientNamespace", "client1", 1},
+		{"SeveralClientNamespaces", "client1,client2", 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			cf := fake.NewClientFactory(
+				fake.Tenant("tenant1", "name1", "", "client1"),
+				fake.Tenant("tenant2", "name2", "", "client2"),
+			)
+			examinee, err := NewScope(&ScopeConfig{ClientNamespaces: tc.clientNamespaces}, cf.CoreV1(), time.Minute)
+			assert.NilError(t, err)
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			// EXERCISE
+			examinee.RestrictTenantInformer(cf.StewardInformerFactory())
+
+			// VERIFY
+			informer := cf.StewardInformerFactory().Steward().V1alpha1().Tenants()
+			synced := informer.Informer().HasSynced
+			cf.StewardInformerFactory().Start(stopCh)
+			assert.Assert(t, cache.WaitForCacheSync(stopCh, synced))
+			tenants, err := informer.Lister().List(labels.Everything())
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expectedTenants, len(tenants))
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package k8s
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/SAP/stewardci-core/pkg/tracing"
 	v1 "k8s.io/api/core/v1"
 	v1beta1 "k8s.io/api/rbac/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,13 +17,14 @@ const (
 
 //ServiceAccountManager manages serviceAccounts
 type ServiceAccountManager interface {
-	CreateServiceAccount(name string, scmCloneSecretName string, pullSecretName string) (*ServiceAccountWrap, error)
-	GetServiceAccount(name string) (*ServiceAccountWrap, error)
+	CreateServiceAccount(ctx context.Context, name string, scmCloneSecretName string, pullSecretName string) (*ServiceAccountWrap, error)
+	GetServiceAccount(ctx context.Context, name string) (*ServiceAccountWrap, error)
 }
 
 type serviceAccountManager struct {
-	factory ClientFactory
-	client  corev1.ServiceAccountInterface
+	factory   ClientFactory
+	client    corev1.ServiceAccountInterface
+	namespace string
 }
 
 // ServiceAccountWrap wraps a Service Account and enriches it with futher things
@@ -36,8 +39,9 @@ type RoleName string
 //NewServiceAccountManager creates ServiceAccountManager
 func NewServiceAccountManager(factory ClientFactory, namespace string) ServiceAccountManager {
 	return &serviceAccountManager{
-		factory: factory,
-		client:  factory.CoreV1().ServiceAccounts(namespace),
+		factory:   factory,
+		client:    factory.CoreV1().ServiceAccounts(namespace),
+		namespace: namespace,
 	}
 }
 
@@ -45,7 +49,8 @@ func NewServiceAccountManager(factory ClientFactory, namespace string) ServiceAc
 //   name					name of the service account
 //   scmCloneSecretName		(optional) the scm clone secret to attach to this service account (e.g. for fetching the Jenkinsfile)
 //   pullSecretName			(optional) the pull secret to attach to this service account (e.g. for pulling the Jenkinsfile Runner image)
-func (c *serviceAccountManager) CreateServiceAccount(name string, scmCloneSecretName string, pullSecretName string) (*ServiceAccountWrap, error) {
+func (c *serviceAccountManager) CreateServiceAccount(ctx context.Context, name string, scmCloneSecretName string, pullSecretName string) (*ServiceAccountWrap, error) {
+	_, span := tracing.Start(ctx, "create service account", tracing.KeyNamespace.String(c.namespace), tracing.KeyName.String(name))
 	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name}}
 	if scmCloneSecretName != "" {
 		secretList := make([]v1.ObjectReference, 1)
@@ -59,6 +64,7 @@ func (c *serviceAccountManager) CreateServiceAccount(name string, scmCloneSecret
 	}
 
 	account, err := c.client.Create(serviceAccount)
+	tracing.End(span, err)
 	return &ServiceAccountWrap{
 		factory: c.factory,
 		cache:   account,
@@ -66,7 +72,9 @@ func (c *serviceAccountManager) CreateServiceAccount(name string, scmCloneSecret
 }
 
 // GetServiceAccount gets a ServiceAccount from the cluster
-func (c *serviceAccountManager) GetServiceAccount(name string) (serviceAccount *ServiceAccountWrap, err error) {
+func (c *serviceAccountManager) GetServiceAccount(ctx context.Context, name string) (serviceAccount *ServiceAccountWrap, err error) {
+	_, span := tracing.Start(ctx, "get service account", tracing.KeyNamespace.String(c.namespace), tracing.KeyName.String(name))
+	defer func() { tracing.End(span, err) }()
 	var account *v1.ServiceAccount
 	if account, err = c.client.Get(name, metav1.GetOptions{}); err != nil {
 		return
@@ -79,7 +87,9 @@ func (c *serviceAccountManager) GetServiceAccount(name string) (serviceAccount *
 }
 
 // AddRoleBinding creates a role binding in the targetNamespace connecting the service account with the specified cluster role
-func (a *ServiceAccountWrap) AddRoleBinding(clusterRole RoleName, targetNamespace string) (*v1beta1.RoleBinding, error) {
+func (a *ServiceAccountWrap) AddRoleBinding(ctx context.Context, clusterRole RoleName, targetNamespace string) (_ *v1beta1.RoleBinding, err error) {
+	_, span := tracing.Start(ctx, "add role binding", tracing.KeyNamespace.String(targetNamespace), tracing.KeyName.String(string(clusterRole)))
+	defer func() { tracing.End(span, err) }()
 
 	//Check if cluster role exists
 	if checkRoleExistence {
@@ -1,6 +1,7 @@
 package k8s
 
 import (
+	"context"
 	"testing"
 
 	"github.com/SAP/stewardci-core/pkg/k8s/fake"
@@ -29,40 +30,40 @@ func fakeServiceAccount() *v1.ServiceAccount {
 
 func Test_CreateServiceAccount_works(t *testing.T) {
 	setupAccountManager()
-	acc, err := accountManager.CreateServiceAccount(accountName, "scmCloneSecretName", "pullSecretName")
+	acc, err := accountManager.CreateServiceAccount(context.Background(), accountName, "scmCloneSecretName", "pullSecretName")
 	assert.NilError(t, err)
 	assert.Equal(t, accountName, acc.GetServiceAccount().GetName())
 }
 
 func Test_CreateServiceAccount_failsWhenAlreadyExists(t *testing.T) {
 	setupAccountManager(fakeServiceAccount())
-	_, err := accountManager.CreateServiceAccount(accountName, "scmCloneSecretName", "pullSecretName")
+	_, err := accountManager.CreateServiceAccount(context.Background(), accountName, "scmCloneSecretName", "pullSecretName")
 	assert.Equal(t, `serviceaccounts "dummyAccount" already exists`, err.Error())
 }
 
 func Test_FetchServiceAccount_works(t *testing.T) {
 	setupAccountManager(fakeServiceAccount())
-	acc, err := accountManager.GetServiceAccount(accountName)
+	acc, err := accountManager.GetServiceAccount(context.Background(), accountName)
 	assert.NilError(t, err)
 	assert.Equal(t, accountName, acc.GetServiceAccount().GetName())
 }
 
 func Test_FetchServiceAccount_failsIfNotExisting(t *testing.T) {
 	setupAccountManager()
-	_, err := accountManager.GetServiceAccount(accountName)
+	_, err := accountManager.GetServiceAccount(context.Background(), accountName)
 	assert.Equal(t, `serviceaccounts "dummyAccount" not found`, err.Error())
 }
 
 func Test_CreateRoleSameNamespace_works(t *testing.T) {
 	setupAccountManager(fakeServiceAccount(), fake.ClusterRole(string(roleName)))
-	acc, _ := accountManager.GetServiceAccount(accountName)
-	_, err := acc.AddRoleBinding(roleName, ns1)
+	acc, _ := accountManager.GetServiceAccount(context.Background(), accountName)
+	_, err := acc.AddRoleBinding(context.Background(), roleName, ns1)
 	assert.NilError(t, err)
 }
 
 func Test_CreateRoleOtherNamespace_works(t *testing.T) {
 	setupAccountManager(fakeServiceAccount(), fake.ClusterRole(string(roleName)))
-	acc, _ := accountManager.GetServiceAccount(accountName)
-	_, err := acc.AddRoleBinding(roleName, ns1)
+	acc, _ := accountManager.GetServiceAccount(context.Background(), accountName)
+	_, err := acc.AddRoleBinding(context.Background(), roleName, ns1)
 	assert.NilError(t, err)
 }
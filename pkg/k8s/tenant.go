@@ -2,6 +2,7 @@ package k8s
 
 import (
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	listers "github.com/SAP/stewardci-core/pkg/client/listers/steward/v1alpha1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
@@ -10,6 +11,9 @@ import (
 // TenantFetcher has methods to fetch tenants from Kubernetes
 type TenantFetcher interface {
 	ByKey(key string) (*api.Tenant, error)
+	// Updated notifies the fetcher about the given tenant written by the
+	// caller, so that subsequent fetches do not return older versions.
+	Updated(*api.Tenant)
 }
 
 type tenantFetcher struct {
@@ -29,6 +33,10 @@ func (tf *tenantFetcher) ByKey(key string) (*api.Tenant, error) {
 	if err != nil {
 		return nil, err
 	}
+	return tf.get(namespace, name)
+}
+
+func (tf *tenantFetcher) get(namespace, name string) (*api.Tenant, error) {
 	client := tf.factory.StewardV1alpha1().Tenants(namespace)
 	t, err := client.Get(name, metav1.GetOptions{})
 	if k8serrors.IsNotFound(err) {
@@ -36,3 +44,58 @@ func (tf *tenantFetcher) ByKey(key string) (*api.Tenant, error) {
 	}
 	return t, err
 }
+
+// Updated does nothing as tenants are always fetched from Kubernetes
+func (tf *tenantFetcher) Updated(*api.Tenant) {}
+
+type listerTenantFetcher struct {
+	tenantFetcher
+	lister   listers.TenantLister
+	versions *resourceVersions
+}
+
+// NewListerTenantFetcher returns a TenantFetcher reading tenants from
+// the informer cache of the given factory. Tenants are fetched from
+// Kubernetes instead if the cache has not observed the last update
+// reported via Updated yet.
+// Must be called before the informers of the factory are started.
+func NewListerTenantFetcher(factory ClientFactory) TenantFetcher {
+	return &listerTenantFetcher{
+		tenantFetcher: tenantFetcher{factory: factory},
+		lister:        factory.StewardInformerFactory().Steward().V1alpha1().Tenants().Lister(),
+		versions:      newResourceVersions(),
+	}
+}
+
+// ByKey fetches Tenant resource from the informer cache.
+// The returned tenant is a copy which may be modified.
+//     key    has to be "<namespace>/<name>"
+// Return nil,nil if tenant with key does not exist
+func (tf *listerTenantFetcher) ByKey(key string) (*api.Tenant, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := tf.lister.Tenants(namespace).Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			tf.versions.forget(key)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if tf.versions.isCurrent(cached) {
+		return cached.DeepCopy(), nil
+	}
+	t, err := tf.get(namespace, name)
+	if t != nil {
+		// the cache is used again once it has observed this version
+		tf.versions.record(t)
+	}
+	return t, err
+}
+
+// Updated records the version of the given tenant
+func (tf *listerTenantFetcher) Updated(tenant *api.Tenant) {
+	tf.versions.record(tenant)
+}
@@ -1,10 +1,9 @@
 package k8s
 
 import (
-	"log"
-
 	stewardv1alpha1 "github.com/SAP/stewardci-core/pkg/client/clientset/versioned/typed/steward/v1alpha1"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -27,18 +26,20 @@ type tenantNamespace struct {
 	secretsClient     corev1.SecretInterface
 	pipelineRunClient stewardv1alpha1.PipelineRunInterface
 	factory           ClientFactory
+	logger            *zap.SugaredLogger
 }
 
 // NewTenantNamespace creates new TenantNamespace object
-func NewTenantNamespace(factory ClientFactory, namespace string) TenantNamespace {
+func NewTenantNamespace(factory ClientFactory, namespace string, logger *zap.SugaredLogger) TenantNamespace {
 	secretsClient := factory.CoreV1().Secrets(namespace)
 	pipelineRunClient := factory.StewardV1alpha1().PipelineRuns(namespace)
-	log.Printf("Creating tenantNamespace: '%s'", namespace)
+	logger.Debugw("Creating tenantNamespace", "namespace", namespace)
 	return &tenantNamespace{
 		namespace:         namespace,
 		secretsClient:     secretsClient,
 		pipelineRunClient: pipelineRunClient,
 		factory:           factory,
+		logger:            logger,
 	}
 }
 
@@ -52,7 +53,7 @@ func (t *tenantNamespace) GetSecret(name string) (*v1.Secret, error) {
 	secret, err := t.secretsClient.Get(name, metav1.GetOptions{})
 	if err != nil {
 		errorWithMessage := errors.WithMessagef(err, "Failed to get secret '%s' in namespace '%s'", name, t.namespace)
-		log.Printf(errorWithMessage.Error())
+		t.logger.Errorw("Failed to get secret", "secret", name, "namespace", t.namespace, "error", err)
 		return secret, errorWithMessage
 	}
 	return secret, nil
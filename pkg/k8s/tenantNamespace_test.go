@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"go.uber.org/zap"
 	"gotest.tools/assert"
 )
 
@@ -12,7 +13,7 @@ const name string = "MyName"
 
 func Test_GetSecret_works(t *testing.T) {
 	factory := fake.NewClientFactory(fake.Secret(name, ns1))
-	tn := NewTenantNamespace(factory, ns1)
+	tn := NewTenantNamespace(factory, ns1, zap.NewNop().Sugar())
 	storedSecret, _ := tn.GetSecret(name)
 	assert.Equal(t, name, storedSecret.GetName(), "Name should be equal")
 	assert.Equal(t, ns1, storedSecret.GetNamespace(), "Namespace should be equal")
@@ -21,7 +22,7 @@ func Test_GetSecret_works(t *testing.T) {
 
 func Test__GetSecret__failsWithMissingSecret(t *testing.T) {
 	factory := fake.NewClientFactory()
-	tn := NewTenantNamespace(factory, ns1)
+	tn := NewTenantNamespace(factory, ns1, zap.NewNop().Sugar())
 	_, err := tn.GetSecret(name)
 	expectedMessage := fmt.Sprintf("Failed to get secret '%s' in namespace '%s': secrets \"%s\" not found",
 		name, ns1, name)
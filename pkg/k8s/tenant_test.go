@@ -31,3 +31,39 @@ func Test__ByKey_InvalidKey_ReturnsError(t *testing.T) {
 func newTenant(name string) *api.Tenant {
 	return fake.Tenant(name, name, name, ns1)
 }
+
+func Test__ListerTenantFetcher__ReturnsCopyFromCache(t *testing.T) {
+	// SETUP
+	cached := newTenant(tenant1)
+	factory := fake.NewClientFactory()
+	examinee := NewListerTenantFetcher(factory)
+	factory.StewardInformerFactory().Steward().V1alpha1().Tenants().Informer().GetIndexer().Add(cached)
+
+	// EXERCISE
+	tenant, err := examinee.ByKey(fake.ObjectKey(tenant1, ns1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	tenant.Status.Message = "changed"
+	assert.Equal(t, "", cached.Status.Message)
+}
+
+func Test__ListerTenantFetcher__CacheOutdated__FetchesFromKubernetes(t *testing.T) {
+	// SETUP
+	live := newTenant(tenant1)
+	live.ResourceVersion = "2"
+	live.Status.Message = "updated"
+	factory := fake.NewClientFactory(live)
+	examinee := NewListerTenantFetcher(factory)
+	outdated := newTenant(tenant1)
+	outdated.ResourceVersion = "1"
+	factory.StewardInformerFactory().Steward().V1alpha1().Tenants().Informer().GetIndexer().Add(outdated)
+
+	// EXERCISE
+	examinee.Updated(live)
+	tenant, err := examinee.ByKey(fake.ObjectKey(tenant1, ns1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "updated", tenant.Status.Message)
+}
@@ -0,0 +1,165 @@
+package leaderelection
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/leaderelection"
+)
+
+// Config is the configuration of the leader election.
+type Config struct {
+	// Enabled defines whether leader election is enabled. If disabled,
+	// the controller runs without coordination with other replicas.
+	Enabled bool
+	// LeaseName is the name of the Lease object used as lock
+	LeaseName string
+	// LeaseNamespace is the namespace of the Lease object used as lock
+	LeaseNamespace string
+	// LeaseDuration is the time non-leader candidates wait after the last
+	// renewal of the leader before trying to acquire leadership
+	LeaseDuration time.Duration
+	// RenewDeadline is the time the leader retries renewing leadership
+	// before giving up
+	RenewDeadline time.Duration
+	// RetryPeriod is the time candidates wait between tries of actions
+	RetryPeriod time.Duration
+	// Identity is the identity of the candidate. Defaults to the host
+	// name with a unique suffix.
+	Identity string
+}
+
+// NewConfig returns the default configuration for the given lease name.
+func NewConfig(leaseName string) *Config {
+	return &Config{
+		Enabled:        true,
+		LeaseName:      leaseName,
+		LeaseNamespace: "steward-system",
+		LeaseDuration:  15 * time.Second,
+		RenewDeadline:  10 * time.Second,
+		RetryPeriod:    2 * time.Second,
+	}
+}
+
+// AddFlags adds command line flags for the configuration to the given
+// flag set.
+func (c *Config) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.BoolVar(&c.Enabled, "leader-elect", c.Enabled, "enable leader election to run multiple replicas of the controller")
+	flagSet.StringVar(&c.LeaseNamespace, "leader-elect-namespace", c.LeaseNamespace, "namespace of the Lease object used for leader election")
+	flagSet.DurationVar(&c.LeaseDuration, "leader-elect-lease-duration", c.LeaseDuration, "time non-leader replicas wait before trying to acquire leadership")
+	flagSet.DurationVar(&c.RenewDeadline, "leader-elect-renew-deadline", c.RenewDeadline, "time the leader retries renewing leadership before giving up")
+	flagSet.DurationVar(&c.RetryPeriod, "leader-elect-retry-period", c.RetryPeriod, "time replicas wait between leader election actions")
+}
+
+// Run runs the given function as soon as leadership has been acquired.
+// The stop channel passed to the function is closed if the given stop
+// channel is closed or if leadership is lost. In the first case the lease
+// is released after the function returned, so that another replica can
+// take over immediately. In the second case an error is returned, as the
+// process must not continue with another replica being the leader.
+func Run(factory k8s.ClientFactory, config *Config, logger *zap.SugaredLogger, stopCh <-chan struct{}, run func(stopCh <-chan struct{}) error) error {
+	if !config.Enabled {
+		return run(stopCh)
+	}
+
+	identity, err := config.getIdentity()
+	if err != nil {
+		return err
+	}
+	logger = logger.With("identity", identity)
+	lock := newLeaseLock(factory.CoordinationV1beta1(), config.LeaseNamespace, config.LeaseName, identity, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var runErr error
+	runDone := make(chan struct{})
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: config.LeaseDuration,
+		RenewDeadline: config.RenewDeadline,
+		RetryPeriod:   config.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				defer close(runDone)
+				logger.Info("Started leading")
+				runErr = run(leaderCtx.Done())
+			},
+			OnStoppedLeading: func() {
+				logger.Info("Stopped leading")
+			},
+			OnNewLeader: func(leader string) {
+				logger.Infow("New leader", "leader", leader)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	elector.Run(ctx)
+
+	if ctx.Err() != nil && !elector.IsLeader() {
+		// stopped before leadership has been acquired
+		return nil
+	}
+	// the function is started asynchronously and must be finished
+	// before the lease can be released
+	<-runDone
+	if ctx.Err() == nil {
+		return fmt.Errorf("leadership of lease '%s' lost", lock.Describe())
+	}
+	if err := release(lock); err != nil {
+		logger.Warnw("Failed to release lease", "lease", lock.Describe(), "error", err)
+	}
+	return runErr
+}
+
+// release releases the lease if it is held by the lock's identity.
+func release(lock *leaseLock) error {
+	record, err := lock.Get()
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if record.HolderIdentity != lock.Identity() {
+		return nil
+	}
+	now := metav1.Now()
+	record.HolderIdentity = ""
+	record.LeaseDurationSeconds = 1
+	record.AcquireTime = now
+	record.RenewTime = now
+	if err := lock.Update(*record); err != nil {
+		return err
+	}
+	lock.logger.Infow("Released lease", "lease", lock.Describe())
+	return nil
+}
+
+func (c *Config) getIdentity() (string, error) {
+	if c.Identity != "" {
+		return c.Identity, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return hostname + "_" + rand.String(8), nil
+}
@@ -0,0 +1,146 @@
+package leaderelection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const waitTimeout = 5 * time.Second
+
+func newTestConfig(identity string) *Config {
+	config := NewConfig("lease1")
+	config.LeaseNamespace = "ns1"
+	config.LeaseDuration = time.Second
+	config.RenewDeadline = 500 * time.Millisecond
+	config.RetryPeriod = 10 * time.Millisecond
+	config.Identity = identity
+	return config
+}
+
+// startCandidate runs a candidate whose function signals its start on the
+// returned started channel and blocks until stopped.
+func startCandidate(factory *fake.ClientFactory, config *Config, stopCh <-chan struct{}) (started chan struct{}, result chan error) {
+	started = make(chan struct{})
+	result = make(chan error, 1)
+	go func() {
+		result <- Run(factory, config, zap.NewNop().Sugar(), stopCh, func(stopCh <-chan struct{}) error {
+			close(started)
+			<-stopCh
+			return nil
+		})
+	}()
+	return started, result
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, message string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(waitTimeout):
+		t.Fatal(message)
+	}
+}
+
+func Test_Run_Disabled_RunsImmediately(t *testing.T) {
+	// SETUP
+	config := newTestConfig("candidate1")
+	config.Enabled = false
+	stopCh := make(chan struct{})
+	called := false
+
+	// EXERCISE
+	err := Run(fake.NewClientFactory(), config, zap.NewNop().Sugar(), stopCh, func(runStopCh <-chan struct{}) error {
+		called = true
+		assert.Equal(t, (<-chan struct{})(stopCh), runStopCh)
+		return nil
+	})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Assert(t, called)
+}
+
+func Test_Run_AcquiresAndReleasesLease(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory()
+	stopCh := make(chan struct{})
+
+	// EXERCISE
+	started, result := startCandidate(factory, newTestConfig("candidate1"), stopCh)
+	waitFor(t, started, "candidate did not start leading")
+	lease, err := factory.CoordinationV1beta1().Leases("ns1").Get("lease1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	holderWhileLeading := *lease.Spec.HolderIdentity
+	close(stopCh)
+
+	// VERIFY
+	assert.Equal(t, "candidate1", holderWhileLeading)
+	select {
+	case err := <-result:
+		assert.NilError(t, err)
+	case <-time.After(waitTimeout):
+		t.Fatal("candidate did not stop")
+	}
+	lease, err = factory.CoordinationV1beta1().Leases("ns1").Get("lease1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "", *lease.Spec.HolderIdentity)
+}
+
+func Test_Run_OnlyOneLeader_OtherTakesOverAfterRelease(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory()
+	stopCh1 := make(chan struct{})
+	stopCh2 := make(chan struct{})
+	defer close(stopCh2)
+	started1, _ := startCandidate(factory, newTestConfig("candidate1"), stopCh1)
+	waitFor(t, started1, "first candidate did not start leading")
+
+	// EXERCISE
+	started2, _ := startCandidate(factory, newTestConfig("candidate2"), stopCh2)
+	select {
+	case <-started2:
+		t.Fatal("second candidate started leading while first is leader")
+	case <-time.After(200 * time.Millisecond):
+	}
+	close(stopCh1)
+
+	// VERIFY
+	// the released lease is taken over without waiting for its expiry
+	select {
+	case <-started2:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("second candidate did not take over")
+	}
+}
+
+func Test_Run_StoppedBeforeLeading(t *testing.T) {
+	// SETUP
+	factory := fake.NewClientFactory()
+	stopCh1 := make(chan struct{})
+	defer close(stopCh1)
+	started1, _ := startCandidate(factory, newTestConfig("candidate1"), stopCh1)
+	waitFor(t, started1, "first candidate did not start leading")
+	stopCh2 := make(chan struct{})
+	started2, result2 := startCandidate(factory, newTestConfig("candidate2"), stopCh2)
+
+	// EXERCISE
+	close(stopCh2)
+
+	// VERIFY
+	select {
+	case err := <-result2:
+		assert.NilError(t, err)
+	case <-time.After(waitTimeout):
+		t.Fatal("second candidate did not stop")
+	}
+	select {
+	case <-started2:
+		t.Fatal("second candidate started leading")
+	default:
+	}
+}
@@ -0,0 +1,137 @@
+package leaderelection
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	coordination "k8s.io/api/coordination/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaseLock is a resource lock based on a Lease object.
+// It is missing in the client-go version in use.
+// A released Lease, i.e. one without holder, is reported as not found.
+// Candidates then take it over by "creating" it, instead of waiting for
+// its expiry as the leader elector of this client-go version would do.
+type leaseLock struct {
+	leaseMeta metav1.ObjectMeta
+	client    coordinationv1beta1.LeasesGetter
+	identity  string
+	lease     *coordination.Lease
+	logger    *zap.SugaredLogger
+}
+
+func newLeaseLock(client coordinationv1beta1.LeasesGetter, namespace string, name string, identity string, logger *zap.SugaredLogger) *leaseLock {
+	return &leaseLock{
+		leaseMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		client:   client,
+		identity: identity,
+		logger:   logger,
+	}
+}
+
+// Get returns the election record from the Lease
+func (l *leaseLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	l.lease = nil
+	lease, err := l.client.Leases(l.leaseMeta.Namespace).Get(l.leaseMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	l.lease = lease
+	if isReleased(lease) {
+		return nil, k8serrors.NewNotFound(coordination.Resource("leases"), l.leaseMeta.Name)
+	}
+	return leaseSpecToRecord(&lease.Spec), nil
+}
+
+// Create attempts to create a Lease or to take over a released one
+func (l *leaseLock) Create(record resourcelock.LeaderElectionRecord) error {
+	if l.lease != nil && isReleased(l.lease) {
+		// fails on conflicting takeovers of other candidates
+		return l.Update(record)
+	}
+	lease, err := l.client.Leases(l.leaseMeta.Namespace).Create(&coordination.Lease{
+		ObjectMeta: l.leaseMeta,
+		Spec:       recordToLeaseSpec(&record),
+	})
+	if err != nil {
+		return err
+	}
+	l.lease = lease
+	return nil
+}
+
+// Update will update an existing Lease
+func (l *leaseLock) Update(record resourcelock.LeaderElectionRecord) error {
+	if l.lease == nil {
+		return fmt.Errorf("lease not initialized, call get or create first")
+	}
+	lease := l.lease.DeepCopy()
+	lease.Spec = recordToLeaseSpec(&record)
+	lease, err := l.client.Leases(l.leaseMeta.Namespace).Update(lease)
+	if err != nil {
+		return err
+	}
+	l.lease = lease
+	return nil
+}
+
+// RecordEvent logs leader election events
+func (l *leaseLock) RecordEvent(event string) {
+	l.logger.Infow("Leader election event", "lease", l.Describe(), "event", event)
+}
+
+// Identity returns the identity of the candidate
+func (l *leaseLock) Identity() string {
+	return l.identity
+}
+
+// Describe returns the namespace and name of the Lease
+func (l *leaseLock) Describe() string {
+	return fmt.Sprintf("%v/%v", l.leaseMeta.Namespace, l.leaseMeta.Name)
+}
+
+func isReleased(lease *coordination.Lease) bool {
+	return lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == ""
+}
+
+func leaseSpecToRecord(spec *coordination.LeaseSpec) *resourcelock.LeaderElectionRecord {
+	record := &resourcelock.LeaderElectionRecord{}
+	if spec.HolderIdentity != nil {
+		record.HolderIdentity = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		record.LeaseDurationSeconds = int(*spec.LeaseDurationSeconds)
+	}
+	if spec.LeaseTransitions != nil {
+		record.LeaderTransitions = int(*spec.LeaseTransitions)
+	}
+	if spec.AcquireTime != nil {
+		record.AcquireTime = metav1.NewTime(spec.AcquireTime.Time)
+	}
+	if spec.RenewTime != nil {
+		record.RenewTime = metav1.NewTime(spec.RenewTime.Time)
+	}
+	return record
+}
+
+func recordToLeaseSpec(record *resourcelock.LeaderElectionRecord) coordination.LeaseSpec {
+	holderIdentity := record.HolderIdentity
+	leaseDurationSeconds := int32(record.LeaseDurationSeconds)
+	leaseTransitions := int32(record.LeaderTransitions)
+	acquireTime := metav1.NewMicroTime(record.AcquireTime.Time)
+	renewTime := metav1.NewMicroTime(record.RenewTime.Time)
+	return coordination.LeaseSpec{
+		HolderIdentity:       &holderIdentity,
+		LeaseDurationSeconds: &leaseDurationSeconds,
+		LeaseTransitions:     &leaseTransitions,
+		AcquireTime:          &acquireTime,
+		RenewTime:            &renewTime,
+	}
+}
@@ -0,0 +1,208 @@
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// Keys of structured log fields
+const (
+	// KeyComponent is the component writing the log entry
+	KeyComponent = "component"
+	// KeyPipelineRun is the key (namespace/name) of a pipeline run
+	KeyPipelineRun = "pipelineRun"
+	// KeyTenant is the key (namespace/name) of a tenant
+	KeyTenant = "tenant"
+	// KeyRunNamespace is the run namespace of a pipeline run
+	KeyRunNamespace = "runNamespace"
+	// KeyTenantNamespace is the tenant namespace of a tenant
+	KeyTenantNamespace = "tenantNamespace"
+	// KeyReconcileID identifies the log entries of one reconciliation
+	KeyReconcileID = "reconcileID"
+	// KeyTraceID is the ID of the trace a reconciliation is recorded in
+	KeyTraceID = "traceID"
+)
+
+// Components with separately configurable log levels
+const (
+	ComponentRunController    = "run-controller"
+	ComponentTenantController = "tenant-controller"
+	ComponentRunManager       = "run-manager"
+	ComponentNamespaceManager = "namespace-manager"
+	ComponentK8s              = "k8s"
+	ComponentLeaderElection   = "leader-election"
+	ComponentServer           = "server"
+	ComponentConfig           = "config"
+	ComponentSharding         = "sharding"
+	ComponentNamespaceGC      = "namespace-gc"
+)
+
+// Log formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Config is the logging configuration.
+type Config struct {
+	// Format is the format of log entries, either `FormatJSON` or `FormatConsole`
+	Format string
+	// Level is the default log level, e.g. `info`
+	Level string
+	// ComponentLevels are log levels of single components overriding the
+	// default level, as comma-separated list of `<component>=<level>`
+	ComponentLevels string
+}
+
+// NewConfig returns the default logging configuration.
+func NewConfig() *Config {
+	return &Config{
+		Format: FormatJSON,
+		Level:  "info",
+	}
+}
+
+// AddFlags adds command line flags for the configuration to the given
+// flag set.
+func (c *Config) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&c.Format, "log-format", c.Format, "log format, 'json' or 'console'")
+	flagSet.StringVar(&c.Level, "log-level", c.Level, "log level, one of 'debug', 'info', 'warn', 'error'")
+	flagSet.StringVar(&c.ComponentLevels, "log-component-levels", c.ComponentLevels,
+		"log levels of components overriding the log level, e.g. 'run-manager=debug,k8s=warn'")
+}
+
+// Loggers provides the loggers of all components.
+type Loggers struct {
+	encoder         zapcore.Encoder
+	sink            zapcore.WriteSyncer
+	level           zapcore.Level
+	componentLevels map[string]zapcore.Level
+	fields          []interface{}
+}
+
+// New creates the loggers for the given configuration writing to stderr.
+func New(config *Config) (*Loggers, error) {
+	return newLoggers(config, zapcore.Lock(os.Stderr))
+}
+
+func newLoggers(config *Config, sink zapcore.WriteSyncer) (*Loggers, error) {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	switch config.Format {
+	case FormatJSON:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case FormatConsole:
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("invalid log format '%s'", config.Format)
+	}
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	componentLevels, err := parseComponentLevels(config.ComponentLevels)
+	if err != nil {
+		return nil, err
+	}
+	return &Loggers{
+		encoder:         encoder,
+		sink:            sink,
+		level:           level,
+		componentLevels: componentLevels,
+	}, nil
+}
+
+// NewNop returns loggers discarding all log entries.
+func NewNop() *Loggers {
+	return &Loggers{}
+}
+
+// Component returns the logger of the given component.
+func (l *Loggers) Component(component string) *zap.SugaredLogger {
+	if l.encoder == nil {
+		return zap.NewNop().Sugar()
+	}
+	level, ok := l.componentLevels[component]
+	if !ok {
+		level = l.level
+	}
+	core := zapcore.NewCore(l.encoder, l.sink, level)
+	return zap.New(core, zap.AddCaller()).Sugar().With(KeyComponent, component).With(l.fields...)
+}
+
+// With returns loggers adding the given key-value pairs to all log entries
+// of all components, e.g. to identify a reconciliation.
+func (l *Loggers) With(keysAndValues ...interface{}) *Loggers {
+	result := *l
+	result.fields = append(append([]interface{}{}, l.fields...), keysAndValues...)
+	return &result
+}
+
+// NewReconcileID returns a random ID to correlate the log entries of a
+// reconciliation.
+func NewReconcileID() string {
+	return rand.String(10)
+}
+
+func parseLevel(text string) (zapcore.Level, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return level, fmt.Errorf("invalid log level '%s'", text)
+	}
+	return level, nil
+}
+
+func parseComponentLevels(text string) (map[string]zapcore.Level, error) {
+	result := map[string]zapcore.Level{}
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !isComponent(parts[0]) {
+			return nil, fmt.Errorf("invalid component log level '%s', expected '<component>=<level>' with component one of %s",
+				entry, strings.Join(components(), ", "))
+		}
+		level, err := parseLevel(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		result[parts[0]] = level
+	}
+	return result, nil
+}
+
+func components() []string {
+	result := []string{
+		ComponentRunController,
+		ComponentTenantController,
+		ComponentRunManager,
+		ComponentNamespaceManager,
+		ComponentK8s,
+		ComponentLeaderElection,
+		ComponentServer,
+		ComponentConfig,
+		ComponentSharding,
+		ComponentNamespaceGC,
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isComponent(name string) bool {
+	for _, component := range components() {
+		if component == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"gotest.tools/assert"
+)
+
+func newTestLoggers(t *testing.T, config *Config) (*Loggers, *bytes.Buffer) {
+	t.Helper()
+	buffer := &bytes.Buffer{}
+	loggers, err := newLoggers(config, zapcore.AddSync(buffer))
+	assert.NilError(t, err)
+	return loggers, buffer
+}
+
+func Test_Component_WritesStructuredEntries(t *testing.T) {
+	// SETUP
+	loggers, buffer := newTestLoggers(t, NewConfig())
+
+	// EXERCISE
+	loggers.Component(ComponentRunController).With(KeyPipelineRun, "ns1/run1").Infow("message1", KeyRunNamespace, "runns1")
+
+	// VERIFY
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "message1", entry["msg"])
+	assert.Equal(t, ComponentRunController, entry[KeyComponent])
+	assert.Equal(t, "ns1/run1", entry[KeyPipelineRun])
+	assert.Equal(t, "runns1", entry[KeyRunNamespace])
+}
+
+func Test_Component_LevelPerComponent(t *testing.T) {
+	// SETUP
+	config := NewConfig()
+	config.Level = "warn"
+	config.ComponentLevels = "run-manager=debug, k8s=error"
+	loggers, buffer := newTestLoggers(t, config)
+
+	// EXERCISE
+	loggers.Component(ComponentRunManager).Debug("debug1")
+	loggers.Component(ComponentK8s).Warn("warn1")
+	loggers.Component(ComponentRunController).Info("info1")
+	loggers.Component(ComponentRunController).Warn("warn2")
+
+	// VERIFY
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Assert(t, strings.Contains(lines[0], "debug1"))
+	assert.Assert(t, strings.Contains(lines[1], "warn2"))
+}
+
+func Test_With_AddsFieldsToAllComponents(t *testing.T) {
+	// SETUP
+	loggers, buffer := newTestLoggers(t, NewConfig())
+
+	// EXERCISE
+	examinee := loggers.With(KeyReconcileID, "id1")
+	examinee.Component(ComponentRunManager).Info("message1")
+	loggers.Component(ComponentRunManager).Info("message2")
+
+	// VERIFY
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "id1", entry[KeyReconcileID])
+	assert.Equal(t, ComponentRunManager, entry[KeyComponent])
+	assert.Assert(t, !strings.Contains(lines[1], KeyReconcileID))
+}
+
+func Test_New_InvalidConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		config        Config
+		expectedError string
+	}{
+		{"format", Config{Format: "xml", Level: "info"}, "invalid log format 'xml'"},
+		{"level", Config{Format: FormatJSON, Level: "verbose"}, "invalid log level 'verbose'"},
+		{"componentLevelLevel", Config{Format: FormatJSON, Level: "info", ComponentLevels: "k8s=verbose"}, "invalid log level 'verbose'"},
+		{"componentLevelComponent", Config{Format: FormatJSON, Level: "info", ComponentLevels: "foo=debug"},
+			"invalid component log level 'foo=debug', expected '<component>=<level>' with component one of config, k8s, leader-election, namespace-gc, namespace-manager, run-controller, run-manager, server, sharding, tenant-controller"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(&tc.config)
+			assert.Error(t, err, tc.expectedError)
+		})
+	}
+}
+
+func Test_NewNop_DiscardsEntries(t *testing.T) {
+	NewNop().Component(ComponentK8s).Error("error1")
+}
@@ -2,75 +2,161 @@ package metrics
 
 import (
 	"fmt"
-	"log"
-	"net/http"
+	"sync"
+	"time"
 
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 //TODO: Move to pipeline run controller
 
+const (
+	// maxTenantLabelValues is the maximum number of distinct tenants
+	// (and clients) used as label values. Further tenants are reported
+	// with label value `otherLabelValue` to keep the cardinality of
+	// metrics bounded.
+	maxTenantLabelValues = 500
+
+	otherLabelValue = "other"
+)
+
 // Metrics provides metrics
 type Metrics interface {
-	CountStart()
-	CountResult(api.Result)
+	CountStart(RunLabels)
+	CountResult(api.Result, api.ResultReason, RunLabels)
 	ObserveDurationByState(state *api.StateItem) error
-	StartServer()
+	ObserveTimeToStart(RunLabels, time.Duration)
+	ObserveReconcile(state api.State, duration time.Duration, failed bool)
+	ObserveNamespaceOperation(operation string, duration time.Duration)
+	SetRunsByState(map[api.State]int)
+	SetOrphanedNamespaces(count int)
+	CountOrphanedNamespaceDeletion(result string)
+	Register(prometheus.Registerer) error
+}
+
+// RunLabels identify the Steward client and the tenant a pipeline run
+// belongs to.
+type RunLabels struct {
+	// Client is the namespace of the Steward client
+	Client string
+	// Tenant is the namespace of the tenant
+	Tenant string
 }
 
 type metrics struct {
-	Started   prometheus.Counter
-	Completed *prometheus.CounterVec
-	Duration  *prometheus.HistogramVec
+	Started         *prometheus.CounterVec
+	Completed       *prometheus.CounterVec
+	Duration        *prometheus.HistogramVec
+	TimeToStart     *prometheus.HistogramVec
+	RunsByState     *prometheus.GaugeVec
+	Reconcile       *prometheus.HistogramVec
+	ReconcileErrors *prometheus.CounterVec
+	Namespace       *prometheus.HistogramVec
+	Orphaned        prometheus.Gauge
+	OrphanDeletions *prometheus.CounterVec
+
+	mutex       sync.Mutex
+	labelValues map[string]map[string]bool
+	knownStates map[api.State]bool
+	maxTenants  int
 }
 
 // NewMetrics create metrics
 func NewMetrics() Metrics {
 	return &metrics{
-		Started: prometheus.NewCounter(prometheus.CounterOpts{
+		Started: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "steward_pipeline_runs_started_total_count",
 			Help: "total number of started pipelines",
-		}),
+		},
+			[]string{"client", "tenant"}),
 		Completed: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "steward_pipeline_runs_completed_total_count",
 			Help: "completed pipelines",
 		},
-			[]string{"result"}),
+			[]string{"client", "tenant", "result", "reason"}),
 		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "steward_pipeline_run_duration_seconds",
 			Help:    "pipeline run durations",
 			Buckets: prometheus.ExponentialBuckets(0.125, 2, 15),
 		},
 			[]string{"state"}),
+		TimeToStart: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "steward_pipeline_run_time_to_start_seconds",
+			Help:    "time from creation of pipeline runs until they are running",
+			Buckets: prometheus.ExponentialBuckets(0.125, 2, 15),
+		},
+			[]string{"client", "tenant"}),
+		RunsByState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "steward_pipeline_runs_total_number",
+			Help: "number of pipeline runs by state",
+		},
+			[]string{"state"}),
+		Reconcile: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "steward_pipeline_run_reconcile_duration_seconds",
+			Help:    "durations of pipeline run reconciliations by state",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+		},
+			[]string{"state"}),
+		ReconcileErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "steward_pipeline_run_reconcile_errors_total_count",
+			Help: "failed pipeline run reconciliations by state",
+		},
+			[]string{"state"}),
+		Namespace: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "steward_run_namespace_operation_duration_seconds",
+			Help:    "durations of run namespace creations and deletions",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+			[]string{"operation"}),
+		Orphaned: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "steward_orphaned_run_namespaces_number",
+			Help: "number of orphaned run namespaces found by the last check",
+		}),
+		OrphanDeletions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "steward_orphaned_run_namespaces_deleted_total_count",
+			Help: "deletions of orphaned run namespaces by result",
+		},
+			[]string{"result"}),
+		labelValues: map[string]map[string]bool{},
+		knownStates: map[api.State]bool{},
+		maxTenants:  maxTenantLabelValues,
 	}
 }
 
-// StartServer registers metrics and start http listener
-func (metrics *metrics) StartServer() {
-	prometheus.MustRegister(metrics.Started)
-	prometheus.MustRegister(metrics.Completed)
-	prometheus.MustRegister(metrics.Duration)
-	go provideMetrics()
-}
-
-func provideMetrics() {
-	http.Handle("/metrics", promhttp.Handler())
-	err := http.ListenAndServe(":9090", nil)
-	if err != nil {
-		log.Fatalf("Failed to start metrics server for pipeline run controller:%v", err)
+// Register registers the metrics at the given registry
+func (metrics *metrics) Register(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		metrics.Started,
+		metrics.Completed,
+		metrics.Duration,
+		metrics.TimeToStart,
+		metrics.RunsByState,
+		metrics.Reconcile,
+		metrics.ReconcileErrors,
+		metrics.Namespace,
+		metrics.Orphaned,
+		metrics.OrphanDeletions,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // CountStart counts the start events
-func (metrics *metrics) CountStart() {
-	metrics.Started.Inc()
+func (metrics *metrics) CountStart(labels RunLabels) {
+	metrics.Started.With(metrics.runLabels(labels)).Inc()
 }
 
-// CountResult counts the completed events by result type
-func (metrics *metrics) CountResult(result api.Result) {
-	metrics.Completed.With(prometheus.Labels{"result": string(result)}).Inc()
+// CountResult counts the completed events by result type and reason
+func (metrics *metrics) CountResult(result api.Result, reason api.ResultReason, labels RunLabels) {
+	promLabels := metrics.runLabels(labels)
+	promLabels["result"] = string(result)
+	promLabels["reason"] = string(reason)
+	metrics.Completed.With(promLabels).Inc()
 }
 
 // ObserveDurationByState logs duration of the state
@@ -85,3 +171,77 @@ func (metrics *metrics) ObserveDurationByState(state *api.StateItem) error {
 	metrics.Duration.With(prometheus.Labels{"state": string(state.State)}).Observe(duration.Seconds())
 	return nil
 }
+
+// ObserveTimeToStart logs the time from creation of a pipeline run until
+// it is running
+func (metrics *metrics) ObserveTimeToStart(labels RunLabels, duration time.Duration) {
+	metrics.TimeToStart.With(metrics.runLabels(labels)).Observe(duration.Seconds())
+}
+
+// ObserveReconcile logs the duration and the failure of a reconciliation
+// of a pipeline run in the given state
+func (metrics *metrics) ObserveReconcile(state api.State, duration time.Duration, failed bool) {
+	promLabels := prometheus.Labels{"state": string(state)}
+	metrics.Reconcile.With(promLabels).Observe(duration.Seconds())
+	if failed {
+		metrics.ReconcileErrors.With(promLabels).Inc()
+	}
+}
+
+// ObserveNamespaceOperation logs the duration of an operation on a run
+// namespace, e.g. `create` or `delete`
+func (metrics *metrics) ObserveNamespaceOperation(operation string, duration time.Duration) {
+	metrics.Namespace.With(prometheus.Labels{"operation": operation}).Observe(duration.Seconds())
+}
+
+// SetRunsByState sets the number of pipeline runs in each state.
+// States reported before but missing now are set to zero.
+func (metrics *metrics) SetRunsByState(counts map[api.State]int) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	for state := range counts {
+		metrics.knownStates[state] = true
+	}
+	for state := range metrics.knownStates {
+		metrics.RunsByState.With(prometheus.Labels{"state": string(state)}).Set(float64(counts[state]))
+	}
+}
+
+// SetOrphanedNamespaces sets the number of orphaned run namespaces found
+func (metrics *metrics) SetOrphanedNamespaces(count int) {
+	metrics.Orphaned.Set(float64(count))
+}
+
+// CountOrphanedNamespaceDeletion counts a deletion of an orphaned run
+// namespace with the given result, e.g. `deleted`, `dry-run` or `failed`
+func (metrics *metrics) CountOrphanedNamespaceDeletion(result string) {
+	metrics.OrphanDeletions.With(prometheus.Labels{"result": result}).Inc()
+}
+
+// runLabels returns the Prometheus labels for the given run labels.
+// Once the maximum number of distinct values is reached, unknown values
+// are replaced by `otherLabelValue`.
+func (metrics *metrics) runLabels(labels RunLabels) prometheus.Labels {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	return prometheus.Labels{
+		"client": metrics.boundedLabelValue("client", labels.Client),
+		"tenant": metrics.boundedLabelValue("tenant", labels.Tenant),
+	}
+}
+
+func (metrics *metrics) boundedLabelValue(name string, value string) string {
+	values := metrics.labelValues[name]
+	if values == nil {
+		values = map[string]bool{}
+		metrics.labelValues[name] = values
+	}
+	if values[value] {
+		return value
+	}
+	if len(values) >= metrics.maxTenants {
+		return otherLabelValue
+	}
+	values[value] = true
+	return value
+}
@@ -1,11 +1,14 @@
 package metrics
 
 import (
+	"testing"
+	"time"
+
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"gotest.tools/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"testing"
-	"time"
 )
 
 func Test_Duration_Missing_Start_Time(t *testing.T) {
@@ -35,3 +38,65 @@ func fakeStateItem(state api.State, duration time.Duration) *api.StateItem {
 		FinishedAt: endTime,
 	}
 }
+
+func Test_CountResult_ByTenantAndReason(t *testing.T) {
+	// SETUP
+	m := NewMetrics().(*metrics)
+	labels := RunLabels{Client: "client1", Tenant: "tenant1"}
+
+	// EXERCISE
+	m.CountResult(api.ResultErrorInfra, api.ResultReasonImagePullFailed, labels)
+	m.CountResult(api.ResultErrorInfra, api.ResultReasonImagePullFailed, labels)
+
+	// VERIFY
+	counter := m.Completed.With(prometheus.Labels{
+		"client": "client1",
+		"tenant": "tenant1",
+		"result": string(api.ResultErrorInfra),
+		"reason": string(api.ResultReasonImagePullFailed),
+	})
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter))
+}
+
+func Test_RunLabels_CardinalityIsBounded(t *testing.T) {
+	// SETUP
+	m := NewMetrics().(*metrics)
+	m.maxTenants = 2
+
+	// EXERCISE
+	m.CountStart(RunLabels{Client: "client1", Tenant: "tenant1"})
+	m.CountStart(RunLabels{Client: "client1", Tenant: "tenant2"})
+	m.CountStart(RunLabels{Client: "client1", Tenant: "tenant3"})
+	m.CountStart(RunLabels{Client: "client1", Tenant: "tenant4"})
+	m.CountStart(RunLabels{Client: "client1", Tenant: "tenant1"})
+
+	// VERIFY
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.Started.With(prometheus.Labels{"client": "client1", "tenant": "tenant1"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Started.With(prometheus.Labels{"client": "client1", "tenant": "tenant2"})))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.Started.With(prometheus.Labels{"client": "client1", "tenant": otherLabelValue})))
+}
+
+func Test_SetRunsByState_ResetsMissingStates(t *testing.T) {
+	// SETUP
+	m := NewMetrics().(*metrics)
+	m.SetRunsByState(map[api.State]int{api.StatePreparing: 3, api.StateRunning: 5})
+
+	// EXERCISE
+	m.SetRunsByState(map[api.State]int{api.StateRunning: 4})
+
+	// VERIFY
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.RunsByState.With(prometheus.Labels{"state": string(api.StatePreparing)})))
+	assert.Equal(t, float64(4), testutil.ToFloat64(m.RunsByState.With(prometheus.Labels{"state": string(api.StateRunning)})))
+}
+
+func Test_ObserveReconcile_CountsErrors(t *testing.T) {
+	// SETUP
+	m := NewMetrics().(*metrics)
+
+	// EXERCISE
+	m.ObserveReconcile(api.StatePreparing, time.Second, true)
+	m.ObserveReconcile(api.StatePreparing, time.Second, false)
+
+	// VERIFY
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ReconcileErrors.With(prometheus.Labels{"state": string(api.StatePreparing)})))
+}
@@ -0,0 +1,479 @@
+package runctl
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/tracing"
+	"github.com/pkg/errors"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+/*
+ * Build caches are persistent volumes labelled with the tenant namespace
+ * and the cache name. A pipeline run writing to a cache locks an unused
+ * volume of the cache exclusively and pre-binds it to a claim in its run
+ * namespace. Volumes provisioned for new caches are retained and labelled
+ * when the pipeline run gets cleaned up. As a volume can only be bound to a
+ * single claim, a cache may consist of several volumes if it is written
+ * concurrently.
+ *
+ * Pipeline runs reading a cache register as readers of a volume which is
+ * not locked by a writer and get a read-only alias of it: a second
+ * persistent volume with the same volume source and access mode
+ * ReadOnlyMany, pre-bound to the claim in the run namespace. Writers wait
+ * for all readers of a volume, readers do not wait for each other. The
+ * storage of the cache volumes must therefore support being attached
+ * read-only to several nodes.
+ */
+
+const (
+	// cacheMountPath is the directory in the Jenkinsfile Runner container
+	// the caches are mounted to.
+	cacheMountPath = "/caches"
+
+	// cacheClaimPrefix is the name prefix of the persistent volume claims
+	// for caches in run namespaces.
+	cacheClaimPrefix = "cache-"
+
+	labelCacheTenant        = "steward.sap.com/cache-tenant"
+	labelCacheName          = "steward.sap.com/cache-name"
+	labelCacheAliasOf       = "steward.sap.com/cache-alias-of"
+	annotationCacheLock     = "steward.sap.com/cache-lock"
+	annotationCacheReaders  = "steward.sap.com/cache-readers"
+	annotationCacheLastUsed = "steward.sap.com/cache-last-used"
+)
+
+// cacheVolume is a cache provided to a pipeline run.
+type cacheVolume struct {
+	name string
+	// claimName is the name of the persistent volume claim in the run
+	// namespace, or empty if an empty directory is used.
+	claimName string
+	readOnly  bool
+}
+
+// validateCaches returns an error if the caches requested by the given
+// pipeline run spec are invalid or not enabled.
+func validateCaches(spec *api.PipelineSpec, config runConfig) error {
+	if len(spec.Caches) == 0 {
+		return nil
+	}
+	if config.GetCacheStorageClass() == "" {
+		return errors.New("caches in spec.caches are not enabled")
+	}
+	names := map[string]bool{}
+	for _, cache := range spec.Caches {
+		if len(cache.Name) > validation.DNS1123LabelMaxLength-len(cacheClaimPrefix) ||
+			len(validation.IsDNS1123Label(cache.Name)) > 0 {
+			return errors.Errorf("cache name '%s' in spec.caches is invalid", cache.Name)
+		}
+		if names[cache.Name] {
+			return errors.Errorf("cache '%s' in spec.caches is requested more than once", cache.Name)
+		}
+		names[cache.Name] = true
+		switch cache.Mode {
+		case "", api.CacheModeReadWrite, api.CacheModeReadOnly:
+		default:
+			return errors.Errorf("mode '%s' of cache '%s' in spec.caches is invalid", cache.Mode, cache.Name)
+		}
+	}
+	return nil
+}
+
+// provideCaches creates persistent volume claims in the run namespace for
+// the caches requested by the pipeline run.
+func (c *runManager) provideCaches(ctx context.Context, pipelineRun k8s.PipelineRun, config runConfig) (_ []cacheVolume, err error) {
+	_, span := tracing.Start(ctx, "provide caches")
+	defer func() { tracing.End(span, err) }()
+	runNamespace := pipelineRun.GetRunNamespace()
+	result := []cacheVolume{}
+	for _, cache := range pipelineRun.GetSpec().Caches {
+		readOnly := cache.Mode == api.CacheModeReadOnly
+		claimName := cacheClaimPrefix + cache.Name
+		var volume *v1.PersistentVolume
+		if readOnly {
+			volume, err = c.acquireCacheShared(pipelineRun, cache.Name, claimName)
+		} else {
+			volume, err = c.acquireCache(pipelineRun, cache.Name, claimName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		claim := &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      claimName,
+				Namespace: runNamespace,
+				Labels:    map[string]string{labelCacheName: cache.Name},
+			},
+		}
+		switch {
+		case volume != nil:
+			claim.Spec = v1.PersistentVolumeClaimSpec{
+				AccessModes:      volume.Spec.AccessModes,
+				StorageClassName: &volume.Spec.StorageClassName,
+				VolumeName:       volume.GetName(),
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: volume.Spec.Capacity[v1.ResourceStorage]},
+				},
+			}
+		case !readOnly:
+			storageClass := config.GetCacheStorageClass()
+			claim.Spec = v1.PersistentVolumeClaimSpec{
+				AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				StorageClassName: &storageClass,
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: config.GetCacheSize()},
+				},
+			}
+		default:
+			c.logger.Infow("No cache available, using an empty directory", "cache", cache.Name)
+			result = append(result, cacheVolume{name: cache.Name, readOnly: true})
+			continue
+		}
+		if _, err = c.factory.CoreV1().PersistentVolumeClaims(runNamespace).Create(claim); err != nil {
+			return nil, errors.WithMessagef(err, "could not create claim for cache '%s' in namespace '%s'", cache.Name, runNamespace)
+		}
+		result = append(result, cacheVolume{name: cache.Name, claimName: claim.GetName(), readOnly: readOnly})
+	}
+	return result, nil
+}
+
+// acquireCache locks the most recently used volume of the given cache
+// which is neither locked nor read, and pre-binds it to the claim with the
+// given name in the run namespace. Returns nil if there is none.
+func (c *runManager) acquireCache(pipelineRun k8s.PipelineRun, name string, claimName string) (*v1.PersistentVolume, error) {
+	volumes, err := c.listCacheVolumes(pipelineRun.GetNamespace(), name)
+	if err != nil {
+		return nil, err
+	}
+	sortByLastUsed(volumes)
+	for _, volume := range volumes {
+		if volume.GetAnnotations()[annotationCacheLock] != "" || len(cacheReaders(&volume)) > 0 {
+			continue
+		}
+		if volume.Status.Phase != v1.VolumeAvailable && volume.Status.Phase != v1.VolumeReleased {
+			continue
+		}
+		annotations := volume.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotationCacheLock] = pipelineRun.GetKey()
+		volume.SetAnnotations(annotations)
+		// Pre-binding releases the volume for the claim in the run
+		// namespace only. Without claim reference the volume would be
+		// available for any claim in the cluster.
+		volume.Spec.ClaimRef = &v1.ObjectReference{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+			Namespace:  pipelineRun.GetRunNamespace(),
+			Name:       claimName,
+		}
+		updated, err := c.factory.CoreV1().PersistentVolumes().Update(&volume)
+		if err != nil {
+			if k8serrors.IsConflict(err) {
+				// locked by another pipeline run in the meantime
+				continue
+			}
+			return nil, errors.WithMessagef(err, "could not lock volume '%s' of cache '%s'", volume.GetName(), name)
+		}
+		return updated, nil
+	}
+	return nil, nil
+}
+
+// acquireCacheShared registers the pipeline run as reader of the most
+// recently used volume of the given cache which is not locked by a writer,
+// and creates a read-only alias of the volume pre-bound to the claim with
+// the given name in the run namespace. Returns the alias, or nil if there
+// is no volume.
+func (c *runManager) acquireCacheShared(pipelineRun k8s.PipelineRun, name string, claimName string) (*v1.PersistentVolume, error) {
+	volumes, err := c.listCacheVolumes(pipelineRun.GetNamespace(), name)
+	if err != nil {
+		return nil, err
+	}
+	sortByLastUsed(volumes)
+	for _, volume := range volumes {
+		if volume.GetAnnotations()[annotationCacheLock] != "" || volume.Status.Phase == v1.VolumeFailed {
+			continue
+		}
+		annotations := volume.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotationCacheReaders] = strings.Join(append(cacheReaders(&volume), pipelineRun.GetKey()), ",")
+		volume.SetAnnotations(annotations)
+		if _, err := c.factory.CoreV1().PersistentVolumes().Update(&volume); err != nil {
+			if k8serrors.IsConflict(err) {
+				// locked by another pipeline run in the meantime
+				continue
+			}
+			return nil, errors.WithMessagef(err, "could not lock volume '%s' of cache '%s'", volume.GetName(), name)
+		}
+		alias := &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   volume.GetName() + "-" + pipelineRun.GetRunNamespace(),
+				Labels: map[string]string{labelCacheAliasOf: volume.GetName()},
+			},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: volume.Spec.PersistentVolumeSource,
+				Capacity:               volume.Spec.Capacity,
+				AccessModes:            []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany},
+				ClaimRef: &v1.ObjectReference{
+					Kind:       "PersistentVolumeClaim",
+					APIVersion: "v1",
+					Namespace:  pipelineRun.GetRunNamespace(),
+					Name:       claimName,
+				},
+				// the storage belongs to the aliased volume
+				PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimRetain,
+				StorageClassName:              volume.Spec.StorageClassName,
+				MountOptions:                  volume.Spec.MountOptions,
+				VolumeMode:                    volume.Spec.VolumeMode,
+				NodeAffinity:                  volume.Spec.NodeAffinity,
+			},
+		}
+		created, err := c.factory.CoreV1().PersistentVolumes().Create(alias)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not create read-only alias of volume '%s' of cache '%s'", volume.GetName(), name)
+		}
+		return created, nil
+	}
+	return nil, nil
+}
+
+// releaseCaches unlocks the cache volumes used by the pipeline run and
+// retains volumes provisioned for new caches.
+func (c *runManager) releaseCaches(pipelineRun k8s.PipelineRun) error {
+	tenantNamespace := pipelineRun.GetNamespace()
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	if runNamespace := pipelineRun.GetRunNamespace(); runNamespace != "" {
+		claims, err := c.factory.CoreV1().PersistentVolumeClaims(runNamespace).List(metav1.ListOptions{
+			LabelSelector: labelCacheName,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "could not list cache claims in namespace '%s'", runNamespace)
+		}
+		for _, claim := range claims.Items {
+			if claim.Spec.VolumeName == "" {
+				continue
+			}
+			volume, err := c.factory.CoreV1().PersistentVolumes().Get(claim.Spec.VolumeName, metav1.GetOptions{})
+			if err != nil {
+				if k8serrors.IsNotFound(err) {
+					continue
+				}
+				return errors.WithMessagef(err, "could not get volume '%s'", claim.Spec.VolumeName)
+			}
+			if volume.GetLabels()[labelCacheAliasOf] != "" {
+				err = c.factory.CoreV1().PersistentVolumes().Delete(volume.GetName(), &metav1.DeleteOptions{})
+				if err != nil && !k8serrors.IsNotFound(err) {
+					return errors.WithMessagef(err, "could not delete read-only alias volume '%s'", volume.GetName())
+				}
+				continue
+			}
+			if volume.GetLabels()[labelCacheTenant] != "" {
+				continue
+			}
+			volume.SetLabels(labels.Merge(volume.GetLabels(), labels.Set{
+				labelCacheTenant: tenantNamespace,
+				labelCacheName:   claim.GetLabels()[labelCacheName],
+			}))
+			volume.SetAnnotations(labels.Merge(volume.GetAnnotations(), labels.Set{
+				annotationCacheLastUsed: now,
+			}))
+			volume.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+			if _, err = c.factory.CoreV1().PersistentVolumes().Update(volume); err != nil {
+				return errors.WithMessagef(err, "could not retain volume '%s'", volume.GetName())
+			}
+		}
+	}
+
+	volumes, err := c.listCacheVolumes(tenantNamespace, "")
+	if err != nil {
+		return err
+	}
+	for _, volume := range volumes {
+		annotations := volume.GetAnnotations()
+		readers := cacheReaders(&volume)
+		otherReaders := []string{}
+		for _, reader := range readers {
+			if reader != pipelineRun.GetKey() {
+				otherReaders = append(otherReaders, reader)
+			}
+		}
+		if annotations[annotationCacheLock] != pipelineRun.GetKey() && len(otherReaders) == len(readers) {
+			continue
+		}
+		if annotations[annotationCacheLock] == pipelineRun.GetKey() {
+			delete(annotations, annotationCacheLock)
+		}
+		if len(otherReaders) == 0 {
+			delete(annotations, annotationCacheReaders)
+		} else {
+			annotations[annotationCacheReaders] = strings.Join(otherReaders, ",")
+		}
+		annotations[annotationCacheLastUsed] = now
+		volume.SetAnnotations(annotations)
+		if _, err = c.factory.CoreV1().PersistentVolumes().Update(&volume); err != nil {
+			return errors.WithMessagef(err, "could not unlock volume '%s'", volume.GetName())
+		}
+	}
+	return nil
+}
+
+// evictCaches deletes unused cache volumes of a tenant which have not been
+// used for longer than the maximum age, and the least recently used ones
+// while the total size of the caches exceeds the maximum.
+// The volumes are unlabelled and their reclaim policy is set to Delete.
+// The claim reference of a volume which is not bound is set to a claim
+// which does not exist, so that the volume gets released and deleted by
+// Kubernetes, and cannot be bound by any other claim.
+func (c *runManager) evictCaches(tenantNamespace string, config runConfig) error {
+	maxAge := config.GetCacheMaxAge()
+	maxTotalSize := config.GetCacheMaxTotalSize()
+	if maxAge == 0 && maxTotalSize == nil {
+		return nil
+	}
+
+	volumes, err := c.listCacheVolumes(tenantNamespace, "")
+	if err != nil {
+		return err
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return cacheLastUsed(&volumes[i]).Before(cacheLastUsed(&volumes[j]))
+	})
+	totalSize := k8sresource.Quantity{}
+	for _, volume := range volumes {
+		totalSize.Add(volume.Spec.Capacity[v1.ResourceStorage])
+	}
+
+	now := time.Now()
+	for _, volume := range volumes {
+		if volume.GetAnnotations()[annotationCacheLock] != "" || len(cacheReaders(&volume)) > 0 {
+			continue
+		}
+		expired := maxAge > 0 && now.Sub(cacheLastUsed(&volume)) > maxAge
+		exceeded := maxTotalSize != nil && totalSize.Cmp(*maxTotalSize) > 0
+		if !expired && !exceeded {
+			continue
+		}
+		c.logger.Infow("Evicting cache volume", "volume", volume.GetName(), "cache", volume.GetLabels()[labelCacheName])
+		// the volume gets deleted as soon as it is released
+		volumeLabels := volume.GetLabels()
+		delete(volumeLabels, labelCacheTenant)
+		cacheName := volumeLabels[labelCacheName]
+		delete(volumeLabels, labelCacheName)
+		volume.SetLabels(volumeLabels)
+		volume.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimDelete
+		if volume.Status.Phase != v1.VolumeBound && volume.Status.Phase != v1.VolumeReleased {
+			// a claim reference with a UID no claim has lets Kubernetes
+			// consider the volume released
+			claimRef := &v1.ObjectReference{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+				Namespace:  tenantNamespace,
+				Name:       cacheClaimPrefix + cacheName,
+			}
+			if volume.Spec.ClaimRef != nil {
+				claimRef.Namespace = volume.Spec.ClaimRef.Namespace
+				claimRef.Name = volume.Spec.ClaimRef.Name
+			}
+			claimRef.UID = volume.GetUID()
+			volume.Spec.ClaimRef = claimRef
+		}
+		if _, err = c.factory.CoreV1().PersistentVolumes().Update(&volume); err != nil {
+			return errors.WithMessagef(err, "could not evict volume '%s'", volume.GetName())
+		}
+		totalSize.Sub(volume.Spec.Capacity[v1.ResourceStorage])
+	}
+	return nil
+}
+
+// listCacheVolumes returns the volumes of the given cache of a tenant, or
+// of all caches of the tenant if name is empty.
+func (c *runManager) listCacheVolumes(tenantNamespace string, name string) ([]v1.PersistentVolume, error) {
+	selector := labels.Set{labelCacheTenant: tenantNamespace}
+	if name != "" {
+		selector[labelCacheName] = name
+	}
+	list, err := c.factory.CoreV1().PersistentVolumes().List(metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not list cache volumes of tenant '%s'", tenantNamespace)
+	}
+	return list.Items, nil
+}
+
+// sortByLastUsed sorts cache volumes by descending last use.
+func sortByLastUsed(volumes []v1.PersistentVolume) {
+	sort.Slice(volumes, func(i, j int) bool {
+		return cacheLastUsed(&volumes[i]).After(cacheLastUsed(&volumes[j]))
+	})
+}
+
+// cacheReaders returns the keys of the pipeline runs reading a cache
+// volume.
+func cacheReaders(volume *v1.PersistentVolume) []string {
+	value := volume.GetAnnotations()[annotationCacheReaders]
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// cacheLastUsed returns when a cache volume has been used last.
+func cacheLastUsed(volume *v1.PersistentVolume) time.Time {
+	lastUsed, err := time.Parse(time.RFC3339, volume.GetAnnotations()[annotationCacheLastUsed])
+	if err != nil {
+		return time.Time{}
+	}
+	return lastUsed
+}
+
+// addCacheVolumes mounts the caches into the Jenkinsfile Runner step of
+// the given task spec.
+func addCacheVolumes(taskSpec *tekton.TaskSpec, caches []cacheVolume) error {
+	var step *tekton.Step
+	for i := range taskSpec.Steps {
+		if taskSpec.Steps[i].Name == tektonClusterTaskJenkinsfileRunnerStep {
+			step = &taskSpec.Steps[i]
+		}
+	}
+	if step == nil {
+		return fmt.Errorf("task has no step '%s' to mount caches to", tektonClusterTaskJenkinsfileRunnerStep)
+	}
+	for _, cache := range caches {
+		volume := v1.Volume{Name: cacheClaimPrefix + cache.name}
+		if cache.claimName == "" {
+			volume.EmptyDir = &v1.EmptyDirVolumeSource{}
+		} else {
+			volume.PersistentVolumeClaim = &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: cache.claimName,
+				ReadOnly:  cache.readOnly,
+			}
+		}
+		taskSpec.Volumes = append(taskSpec.Volumes, volume)
+		step.VolumeMounts = append(step.VolumeMounts, v1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: path.Join(cacheMountPath, cache.name),
+			ReadOnly:  cache.readOnly,
+		})
+	}
+	return nil
+}
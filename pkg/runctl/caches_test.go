@@ -0,0 +1,358 @@
+package runctl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
+	k8s "github.com/SAP/stewardci-core/pkg/k8s"
+	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newCacheVolume(name string, tenant string, cache string, lastUsed time.Time, lock string, phase v1.PersistentVolumePhase) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{labelCacheTenant: tenant, labelCacheName: cache},
+			Annotations: map[string]string{
+				annotationCacheLastUsed: lastUsed.UTC().Format(time.RFC3339),
+				annotationCacheLock:     lock,
+			},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity:                      v1.ResourceList{v1.ResourceStorage: k8sresource.MustParse("5Gi")},
+			AccessModes:                   []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			StorageClassName:              "standard",
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimRetain,
+			ClaimRef:                      &v1.ObjectReference{Name: "cache-maven", Namespace: "old-run-ns"},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: phase},
+	}
+}
+
+func setupCacheExaminee(t *testing.T, caches []api.Cache, objects ...runtime.Object) (*runManager, k8s.PipelineRun, *fake.ClientFactory) {
+	objects = append(objects, fake.PipelineRun("run1", "tenant1", api.PipelineSpec{Caches: caches}))
+	cf := fake.NewClientFactory(objects...)
+	pipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("tenant1", "run1")
+	assert.NilError(t, err)
+	pipelineRun.UpdateRunNamespace("run-ns1")
+	return &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}, pipelineRun, cf
+}
+
+func getVolume(t *testing.T, cf *fake.ClientFactory, name string) *v1.PersistentVolume {
+	volume, err := cf.CoreV1().PersistentVolumes().Get(name, metav1.GetOptions{})
+	assert.NilError(t, err)
+	return volume
+}
+
+func Test_validateCaches(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		storageClass  string
+		caches        []api.Cache
+		expectedError string
+	}{
+		{"None", "", nil, ""},
+		{"Valid", "standard", []api.Cache{{Name: "maven"}, {Name: "npm", Mode: api.CacheModeReadOnly}}, ""},
+		{"NotEnabled", "", []api.Cache{{Name: "maven"}}, `caches in spec.caches are not enabled`},
+		{"InvalidName", "standard", []api.Cache{{Name: "Maven"}}, `cache name 'Maven' in spec.caches is invalid`},
+		{"Duplicate", "standard", []api.Cache{{Name: "maven"}, {Name: "maven"}}, `cache 'maven' in spec.caches is requested more than once`},
+		{"InvalidMode", "standard", []api.Cache{{Name: "maven", Mode: "Exclusive"}}, `mode 'Exclusive' of cache 'maven' in spec.caches is invalid`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			config := &runConfigImpl{cacheStorageClass: tc.storageClass}
+
+			// EXERCISE
+			err := validateCaches(&api.PipelineSpec{Caches: tc.caches}, config)
+
+			// VERIFY
+			if tc.expectedError == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Error(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func Test_provideCaches_UsesMostRecentlyUsedFreeVolume(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	examinee, pipelineRun, cf := setupCacheExaminee(t,
+		[]api.Cache{{Name: "maven"}},
+		newCacheVolume("pv1", "tenant1", "maven", now.Add(-2*time.Hour), "", v1.VolumeReleased),
+		newCacheVolume("pv2", "tenant1", "maven", now.Add(-1*time.Hour), "", v1.VolumeReleased),
+		newCacheVolume("pv3", "tenant1", "maven", now, "tenant1/other", v1.VolumeBound),
+		newCacheVolume("pv4", "tenant2", "maven", now, "", v1.VolumeReleased),
+	)
+
+	// EXERCISE
+	caches, err := examinee.provideCaches(context.Background(), pipelineRun, &runConfigImpl{cacheStorageClass: "standard"})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(caches))
+	assert.Equal(t, cacheVolume{name: "maven", claimName: "cache-maven"}, caches[0])
+	volume := getVolume(t, cf, "pv2")
+	assert.Equal(t, "tenant1/run1", volume.GetAnnotations()[annotationCacheLock])
+	assert.DeepEqual(t, &v1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: "run-ns1", Name: "cache-maven"}, volume.Spec.ClaimRef)
+	claim, err := cf.CoreV1().PersistentVolumeClaims("run-ns1").Get("cache-maven", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "pv2", claim.Spec.VolumeName)
+	assert.Equal(t, "standard", *claim.Spec.StorageClassName)
+	assert.Equal(t, "", getVolume(t, cf, "pv1").GetAnnotations()[annotationCacheLock])
+}
+
+func Test_provideCaches_WriterSkipsVolumeBeingRead(t *testing.T) {
+	// SETUP
+	volume := newCacheVolume("pv1", "tenant1", "maven", time.Now(), "", v1.VolumeReleased)
+	volume.Annotations[annotationCacheReaders] = "tenant1/other"
+	examinee, pipelineRun, cf := setupCacheExaminee(t, []api.Cache{{Name: "maven"}}, volume)
+	size := k8sresource.MustParse("1Gi")
+
+	// EXERCISE
+	_, err := examinee.provideCaches(context.Background(), pipelineRun, &runConfigImpl{cacheStorageClass: "standard", cacheSize: &size})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "", getVolume(t, cf, "pv1").GetAnnotations()[annotationCacheLock])
+	claim, err := cf.CoreV1().PersistentVolumeClaims("run-ns1").Get("cache-maven", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "", claim.Spec.VolumeName)
+}
+
+func Test_provideCaches_ReadOnly_SharesVolume(t *testing.T) {
+	// SETUP
+	volume := newCacheVolume("pv1", "tenant1", "maven", time.Now(), "", v1.VolumeReleased)
+	volume.Annotations[annotationCacheReaders] = "tenant1/other"
+	volume.Spec.PersistentVolumeSource = v1.PersistentVolumeSource{NFS: &v1.NFSVolumeSource{Server: "server1", Path: "/pv1"}}
+	examinee, pipelineRun, cf := setupCacheExaminee(t,
+		[]api.Cache{{Name: "maven", Mode: api.CacheModeReadOnly}},
+		volume,
+		newCacheVolume("pv2", "tenant1", "maven", time.Now(), "tenant1/writer", v1.VolumeBound),
+	)
+
+	// EXERCISE
+	caches, err := examinee.provideCaches(context.Background(), pipelineRun, &runConfigImpl{cacheStorageClass: "standard"})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(caches))
+	assert.Equal(t, cacheVolume{name: "maven", claimName: "cache-maven", readOnly: true}, caches[0])
+	volume = getVolume(t, cf, "pv1")
+	assert.Equal(t, "tenant1/other,tenant1/run1", volume.GetAnnotations()[annotationCacheReaders])
+	assert.Equal(t, "", volume.GetAnnotations()[annotationCacheLock])
+	alias := getVolume(t, cf, "pv1-run-ns1")
+	assert.Equal(t, "pv1", alias.GetLabels()[labelCacheAliasOf])
+	assert.DeepEqual(t, volume.Spec.PersistentVolumeSource, alias.Spec.PersistentVolumeSource)
+	assert.DeepEqual(t, []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}, alias.Spec.AccessModes)
+	assert.Equal(t, v1.PersistentVolumeReclaimRetain, alias.Spec.PersistentVolumeReclaimPolicy)
+	assert.DeepEqual(t, &v1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: "run-ns1", Name: "cache-maven"}, alias.Spec.ClaimRef)
+	claim, err := cf.CoreV1().PersistentVolumeClaims("run-ns1").Get("cache-maven", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "pv1-run-ns1", claim.Spec.VolumeName)
+	assert.DeepEqual(t, []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}, claim.Spec.AccessModes)
+}
+
+func Test_provideCaches_NoFreeVolume(t *testing.T) {
+	// SETUP
+	examinee, pipelineRun, cf := setupCacheExaminee(t,
+		[]api.Cache{{Name: "maven"}, {Name: "npm", Mode: api.CacheModeReadOnly}},
+		newCacheVolume("pv1", "tenant1", "maven", time.Now(), "tenant1/other", v1.VolumeBound),
+	)
+	size := k8sresource.MustParse("1Gi")
+	config := &runConfigImpl{cacheStorageClass: "standard", cacheSize: &size}
+
+	// EXERCISE
+	caches, err := examinee.provideCaches(context.Background(), pipelineRun, config)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(caches))
+	assert.Equal(t, cacheVolume{name: "maven", claimName: "cache-maven"}, caches[0])
+	assert.Equal(t, cacheVolume{name: "npm", readOnly: true}, caches[1])
+	claim, err := cf.CoreV1().PersistentVolumeClaims("run-ns1").Get("cache-maven", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "", claim.Spec.VolumeName)
+	assert.Equal(t, "standard", *claim.Spec.StorageClassName)
+	requested := claim.Spec.Resources.Requests[v1.ResourceStorage]
+	assert.Assert(t, requested.Cmp(size) == 0)
+	claims, err := cf.CoreV1().PersistentVolumeClaims("run-ns1").List(metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(claims.Items))
+}
+
+func Test_releaseCaches(t *testing.T) {
+	// SETUP
+	lastUsed := time.Now().Add(-time.Hour)
+	newVolume := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-new"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+		},
+	}
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cache-npm",
+			Namespace: "run-ns1",
+			Labels:    map[string]string{labelCacheName: "npm"},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{VolumeName: "pv-new"},
+	}
+	readVolume := newCacheVolume("pv3", "tenant1", "gradle", lastUsed, "", v1.VolumeReleased)
+	readVolume.Annotations[annotationCacheReaders] = "tenant1/other,tenant1/run1"
+	alias := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv3-run-ns1", Labels: map[string]string{labelCacheAliasOf: "pv3"}},
+	}
+	aliasClaim := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cache-gradle",
+			Namespace: "run-ns1",
+			Labels:    map[string]string{labelCacheName: "gradle"},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{VolumeName: "pv3-run-ns1"},
+	}
+	examinee, pipelineRun, cf := setupCacheExaminee(t,
+		[]api.Cache{{Name: "maven"}, {Name: "npm"}, {Name: "gradle", Mode: api.CacheModeReadOnly}},
+		newCacheVolume("pv1", "tenant1", "maven", lastUsed, "tenant1/run1", v1.VolumeBound),
+		newCacheVolume("pv2", "tenant1", "maven", lastUsed, "tenant1/other", v1.VolumeBound),
+		newVolume,
+		claim,
+		readVolume,
+		alias,
+		aliasClaim,
+	)
+
+	// EXERCISE
+	err := examinee.releaseCaches(pipelineRun)
+
+	// VERIFY
+	assert.NilError(t, err)
+	volume := getVolume(t, cf, "pv1")
+	assert.Equal(t, "", volume.GetAnnotations()[annotationCacheLock])
+	assert.Assert(t, cacheLastUsed(volume).After(lastUsed))
+	assert.Equal(t, "tenant1/other", getVolume(t, cf, "pv2").GetAnnotations()[annotationCacheLock])
+	volume = getVolume(t, cf, "pv-new")
+	assert.DeepEqual(t, map[string]string{labelCacheTenant: "tenant1", labelCacheName: "npm"}, volume.GetLabels())
+	assert.Equal(t, v1.PersistentVolumeReclaimRetain, volume.Spec.PersistentVolumeReclaimPolicy)
+	assert.Assert(t, !cacheLastUsed(volume).IsZero())
+	volume = getVolume(t, cf, "pv3")
+	assert.Equal(t, "tenant1/other", volume.GetAnnotations()[annotationCacheReaders])
+	assert.Assert(t, cacheLastUsed(volume).After(lastUsed))
+	_, err = cf.CoreV1().PersistentVolumes().Get("pv3-run-ns1", metav1.GetOptions{})
+	assert.Assert(t, k8serrors.IsNotFound(err))
+}
+
+func Test_evictCaches(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name            string
+		maxAge          time.Duration
+		maxTotalSize    string
+		expectedEvicted []string
+	}{
+		{"NoLimits", 0, "", []string{}},
+		{"MaxAge", 36 * time.Hour, "", []string{"pv1"}},
+		{"MaxTotalSize", 0, "10Gi", []string{"pv1", "pv2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			examinee, _, cf := setupCacheExaminee(t, nil,
+				newCacheVolume("pv1", "tenant1", "maven", now.Add(-48*time.Hour), "", v1.VolumeReleased),
+				newCacheVolume("pv2", "tenant1", "npm", now.Add(-24*time.Hour), "", v1.VolumeReleased),
+				newCacheVolume("pv3", "tenant1", "maven", now.Add(-72*time.Hour), "tenant1/other", v1.VolumeBound),
+				newCacheVolume("pv4", "tenant1", "npm", now, "", v1.VolumeReleased),
+			)
+			config := &runConfigImpl{cacheStorageClass: "standard", cacheMaxAge: tc.maxAge}
+			if tc.maxTotalSize != "" {
+				quantity := k8sresource.MustParse(tc.maxTotalSize)
+				config.cacheMaxTotalSize = &quantity
+			}
+
+			// EXERCISE
+			err := examinee.evictCaches("tenant1", config)
+
+			// VERIFY
+			assert.NilError(t, err)
+			evicted := []string{}
+			for _, name := range []string{"pv1", "pv2", "pv3", "pv4"} {
+				volume := getVolume(t, cf, name)
+				if volume.Spec.PersistentVolumeReclaimPolicy == v1.PersistentVolumeReclaimDelete {
+					assert.Equal(t, "", volume.GetLabels()[labelCacheTenant])
+					evicted = append(evicted, name)
+				}
+			}
+			assert.DeepEqual(t, tc.expectedEvicted, evicted)
+		})
+	}
+}
+
+func Test_evictCaches_UnboundVolume_ReleasesVolume(t *testing.T) {
+	// SETUP
+	volume := newCacheVolume("pv1", "tenant1", "maven", time.Now().Add(-48*time.Hour), "", v1.VolumeAvailable)
+	volume.UID = "uid1"
+	volume.Spec.ClaimRef = nil
+	read := newCacheVolume("pv2", "tenant1", "maven", time.Now().Add(-48*time.Hour), "", v1.VolumeReleased)
+	read.Annotations[annotationCacheReaders] = "tenant1/other"
+	examinee, _, cf := setupCacheExaminee(t, nil, volume, read)
+
+	// EXERCISE
+	err := examinee.evictCaches("tenant1", &runConfigImpl{cacheStorageClass: "standard", cacheMaxAge: time.Hour})
+
+	// VERIFY
+	assert.NilError(t, err)
+	volume = getVolume(t, cf, "pv1")
+	assert.Equal(t, v1.PersistentVolumeReclaimDelete, volume.Spec.PersistentVolumeReclaimPolicy)
+	assert.DeepEqual(t, &v1.ObjectReference{Kind: "PersistentVolumeClaim", APIVersion: "v1", Namespace: "tenant1", Name: "cache-maven", UID: "uid1"}, volume.Spec.ClaimRef)
+	assert.Equal(t, v1.PersistentVolumeReclaimRetain, getVolume(t, cf, "pv2").Spec.PersistentVolumeReclaimPolicy)
+}
+
+func Test_RunManager_createTektonTaskRun_Caches(t *testing.T) {
+	// SETUP
+	clusterTask := &tekton.ClusterTask{
+		TypeMeta:   metav1.TypeMeta{Kind: "ClusterTask", APIVersion: "tekton.dev/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: controllerconfig.DefaultTektonClusterTaskName},
+		Spec: tekton.TaskSpec{
+			Steps: []tekton.Step{{Container: v1.Container{Name: tektonClusterTaskJenkinsfileRunnerStep, Image: "image1"}}},
+		},
+	}
+	examinee, pipelineRun, cf := setupCacheExaminee(t, []api.Cache{{Name: "maven"}}, clusterTask)
+	caches := []cacheVolume{
+		{name: "maven", claimName: "cache-maven"},
+		{name: "npm", readOnly: true},
+	}
+
+	// EXERCISE
+	err := examinee.createTektonTaskRun(context.Background(), pipelineRun, "", &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, time.Hour, nil, caches)
+
+	// VERIFY
+	assert.NilError(t, err)
+	taskRun, err := cf.TektonV1alpha1().TaskRuns("run-ns1").Get(tektonTaskRunName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, taskRun.Spec.TaskRef == nil)
+	taskSpec := taskRun.Spec.TaskSpec
+	assert.Assert(t, taskSpec != nil)
+	assert.Equal(t, 2, len(taskSpec.Volumes))
+	assert.Equal(t, "cache-maven", taskSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Assert(t, taskSpec.Volumes[1].EmptyDir != nil)
+	assert.DeepEqual(t, []v1.VolumeMount{
+		{Name: "cache-maven", MountPath: "/caches/maven"},
+		{Name: "cache-npm", MountPath: "/caches/npm", ReadOnly: true},
+	}, taskSpec.Steps[0].VolumeMounts)
+	// the ClusterTask itself is unchanged
+	clusterTask, err = cf.TektonV1alpha1().ClusterTasks().Get(controllerconfig.DefaultTektonClusterTaskName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(clusterTask.Spec.Volumes, 0))
+}
@@ -0,0 +1,157 @@
+package runctl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// queuedRunPollInterval is the interval in which pipeline runs waiting for
+// the concurrency limit of their tenant are checked again.
+const queuedRunPollInterval = 10 * time.Second
+
+// admissionExpiry is the time a pipeline run admitted to start is counted
+// as active even if the informer cache still shows it as new.
+const admissionExpiry = time.Minute
+
+// concurrencyLimitExpiry is the time the concurrency limit of a tenant is
+// cached, so that queued pipeline runs do not read the tenant namespace
+// each time they are checked.
+const concurrencyLimitExpiry = time.Minute
+
+// admissions records the pipeline runs recently admitted to start and
+// caches the concurrency limits of tenants.
+type admissions struct {
+	mutex    sync.Mutex
+	admitted map[string]time.Time
+	limits   map[string]concurrencyLimit
+}
+
+// concurrencyLimit is a concurrency limit of a tenant loaded at some time.
+type concurrencyLimit struct {
+	limit    int
+	loadedAt time.Time
+}
+
+func newAdmissions() *admissions {
+	return &admissions{
+		admitted: map[string]time.Time{},
+		limits:   map[string]concurrencyLimit{},
+	}
+}
+
+// getConcurrencyLimit returns the maximum number of concurrent pipeline
+// runs in the given tenant namespace, or zero if unlimited. The limit is
+// read from the tenant namespace at most once per expiry period.
+func (c *Controller) getConcurrencyLimit(tenantNamespace string) (int, error) {
+	now := time.Now()
+	c.admissions.mutex.Lock()
+	cached, found := c.admissions.limits[tenantNamespace]
+	c.admissions.mutex.Unlock()
+	if found && now.Sub(cached.loadedAt) <= concurrencyLimitExpiry {
+		return cached.limit, nil
+	}
+	namespace, err := c.factory.CoreV1().Namespaces().Get(tenantNamespace, metav1.GetOptions{})
+	if err != nil {
+		return 0, errors.WithMessagef(err, "could not get namespace '%s'", tenantNamespace)
+	}
+	settings, err := parseTenantRunSettings(namespace)
+	if err != nil {
+		return 0, err
+	}
+	limit := 0
+	if settings != nil {
+		limit = int(settings.MaxConcurrent)
+	}
+	c.admissions.mutex.Lock()
+	c.admissions.limits[tenantNamespace] = concurrencyLimit{limit: limit, loadedAt: now}
+	c.admissions.mutex.Unlock()
+	return limit, nil
+}
+
+// admit returns whether the given new pipeline run may be started without
+// exceeding the maximum number of concurrent pipeline runs of its tenant.
+// Pipeline runs being started or running are active. New pipeline runs
+// are admitted in the order of their creation. A pipeline run not
+// admitted gets a message telling that it waits.
+// Sharding assigns all pipeline runs of a tenant namespace to the same
+// replica, so admissions need not be coordinated between replicas.
+func (c *Controller) admit(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun) (bool, error) {
+	limit, err := c.getConcurrencyLimit(pipelineRun.GetNamespace())
+	if err != nil {
+		// the pipeline run will fail to start with this error
+		logger.Warnw("Cannot load concurrency limit, skipping it", "error", err)
+		return true, nil
+	}
+	if limit <= 0 {
+		return true, nil
+	}
+	list, err := c.pipelineRunLister.PipelineRuns(pipelineRun.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+
+	c.admissions.mutex.Lock()
+	defer c.admissions.mutex.Unlock()
+	now := time.Now()
+	for key, admittedAt := range c.admissions.admitted {
+		if now.Sub(admittedAt) > admissionExpiry {
+			delete(c.admissions.admitted, key)
+		}
+	}
+	key := pipelineRun.GetKey()
+	created := pipelineRun.GetCreationTimestamp()
+	active, queuedBefore := 0, 0
+	for _, other := range list {
+		otherKey, err := cache.MetaNamespaceKeyFunc(other)
+		if err != nil {
+			return false, err
+		}
+		if otherKey == key || !c.scope.Contains(other) {
+			continue
+		}
+		switch other.Status.State {
+		case api.StateUndefined:
+			// the informer cache may not reflect the new state yet
+			if _, admitted := c.admissions.admitted[otherKey]; admitted {
+				active++
+			} else if other.Spec.Intent != api.IntentKill && isCreatedBefore(other, created.Time, pipelineRun.GetName()) {
+				queuedBefore++
+			}
+		case api.StateCleaning, api.StateFinished:
+		default:
+			active++
+		}
+	}
+	if active+queuedBefore >= limit {
+		logger.Debugw("Pipeline run waits for concurrency limit of tenant",
+			"limit", limit, "active", active, "queuedBefore", queuedBefore)
+		// each message update is recorded in the history
+		message := fmt.Sprintf("Waiting until fewer than %d pipeline runs of the tenant are active", limit)
+		if pipelineRun.GetStatus().Message != message {
+			pipelineRun.UpdateMessage(message)
+		}
+		return false, nil
+	}
+	c.admissions.admitted[key] = now
+	return true, nil
+}
+
+// isCreatedBefore returns whether the given pipeline run has been created
+// before a pipeline run with the given creation time and name. Pipeline
+// runs created at the same time are ordered by name.
+func isCreatedBefore(pipelineRun *api.PipelineRun, created time.Time, name string) bool {
+	otherCreated := pipelineRun.GetCreationTimestamp().Time
+	if otherCreated.Equal(created) {
+		return pipelineRun.GetName() < name
+	}
+	return otherCreated.Before(created)
+}
@@ -0,0 +1,140 @@
+package runctl
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"github.com/SAP/stewardci-core/pkg/logging"
+	"github.com/SAP/stewardci-core/pkg/metrics"
+	"github.com/SAP/stewardci-core/pkg/sharding"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newQueuedPipelineRun(name string, age time.Duration, state api.State) *api.PipelineRun {
+	pipelineRun := fake.PipelineRun(name, "tenant-ns-1", api.PipelineSpec{})
+	pipelineRun.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-age)))
+	pipelineRun.Status.State = state
+	return pipelineRun
+}
+
+// newAdmissionExaminee returns a controller whose informer cache contains
+// the given pipeline runs of a tenant with the given run settings.
+func newAdmissionExaminee(t *testing.T, settings string, pipelineRuns ...*api.PipelineRun) (*Controller, *fake.ClientFactory) {
+	cf := fake.NewClientFactory(fake.NamespaceWithAnnotations("tenant-ns-1", map[string]string{
+		api.AnnotationTenantRunSettings: settings,
+	}))
+	indexer := cf.StewardInformerFactory().Steward().V1alpha1().PipelineRuns().Informer().GetIndexer()
+	for _, pipelineRun := range pipelineRuns {
+		_, err := cf.StewardV1alpha1().PipelineRuns(pipelineRun.GetNamespace()).Create(pipelineRun)
+		assert.NilError(t, err)
+		assert.NilError(t, indexer.Add(pipelineRun))
+	}
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+	return examinee, cf
+}
+
+func admit(t *testing.T, examinee *Controller, cf *fake.ClientFactory, name string) (bool, k8s.PipelineRun) {
+	pipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("tenant-ns-1", name)
+	assert.NilError(t, err)
+	admitted, err := examinee.admit(zap.NewNop().Sugar(), pipelineRun)
+	assert.NilError(t, err)
+	return admitted, pipelineRun
+}
+
+func Test_Controller_admit(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		settings string
+		others   []*api.PipelineRun
+		expected bool
+	}{
+		{"NoLimit", `{}`, []*api.PipelineRun{newQueuedPipelineRun("other", time.Hour, api.StateRunning)}, true},
+		{"LimitNotReached", `{"maxConcurrent": 2}`, []*api.PipelineRun{newQueuedPipelineRun("other", time.Hour, api.StateRunning)}, true},
+		{"LimitReached", `{"maxConcurrent": 1}`, []*api.PipelineRun{newQueuedPipelineRun("other", time.Hour, api.StatePreparing)}, false},
+		{"FinishedNotCounted", `{"maxConcurrent": 1}`, []*api.PipelineRun{
+			newQueuedPipelineRun("cleaning", time.Hour, api.StateCleaning),
+			newQueuedPipelineRun("finished", time.Hour, api.StateFinished),
+		}, true},
+		{"OlderQueuedFirst", `{"maxConcurrent": 1}`, []*api.PipelineRun{newQueuedPipelineRun("older", time.Hour, api.StateUndefined)}, false},
+		{"NewerQueuedLater", `{"maxConcurrent": 1}`, []*api.PipelineRun{newQueuedPipelineRun("newer", 0, api.StateUndefined)}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			pipelineRuns := append([]*api.PipelineRun{newQueuedPipelineRun("run1", time.Minute, api.StateUndefined)}, tc.others...)
+			examinee, cf := newAdmissionExaminee(t, tc.settings, pipelineRuns...)
+
+			// EXERCISE
+			admitted, _ := admit(t, examinee, cf, "run1")
+
+			// VERIFY
+			assert.Equal(t, tc.expected, admitted)
+		})
+	}
+}
+
+func Test_Controller_admit_CountsRecentlyAdmitted(t *testing.T) {
+	// SETUP
+	// the informer cache does not reflect the start of run1
+	examinee, cf := newAdmissionExaminee(t, `{"maxConcurrent": 1}`,
+		newQueuedPipelineRun("run1", 2*time.Minute, api.StateUndefined),
+		newQueuedPipelineRun("run2", time.Minute, api.StateUndefined),
+	)
+	admitted, _ := admit(t, examinee, cf, "run1")
+	assert.Assert(t, admitted)
+
+	// EXERCISE
+	admitted, pipelineRun := admit(t, examinee, cf, "run2")
+
+	// VERIFY
+	assert.Assert(t, !admitted)
+	assert.Equal(t, "Waiting until fewer than 1 pipeline runs of the tenant are active", pipelineRun.GetStatus().Message)
+}
+
+func Test_Controller_admit_KeepsMessageHistory(t *testing.T) {
+	// SETUP
+	examinee, cf := newAdmissionExaminee(t, `{"maxConcurrent": 1}`,
+		newQueuedPipelineRun("run1", time.Minute, api.StateUndefined),
+		newQueuedPipelineRun("other", time.Hour, api.StateRunning),
+	)
+	admitted, pipelineRun := admit(t, examinee, cf, "run1")
+	assert.Assert(t, !admitted)
+
+	// EXERCISE
+	admitted, err := examinee.admit(zap.NewNop().Sugar(), pipelineRun)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Assert(t, !admitted)
+	assert.Equal(t, 0, len(pipelineRun.GetStatus().History))
+}
+
+func Test_Controller_admit_CachesConcurrencyLimit(t *testing.T) {
+	// SETUP
+	examinee, cf := newAdmissionExaminee(t, `{"maxConcurrent": 1}`,
+		newQueuedPipelineRun("run1", time.Minute, api.StateUndefined),
+		newQueuedPipelineRun("other", time.Hour, api.StateRunning),
+	)
+	admitted, _ := admit(t, examinee, cf, "run1")
+	assert.Assert(t, !admitted)
+	_, err := cf.CoreV1().Namespaces().Update(fake.NamespaceWithAnnotations("tenant-ns-1", map[string]string{
+		api.AnnotationTenantRunSettings: `{}`,
+	}))
+	assert.NilError(t, err)
+
+	// EXERCISE
+	admittedCached, _ := admit(t, examinee, cf, "run1")
+	cached := examinee.admissions.limits["tenant-ns-1"]
+	cached.loadedAt = cached.loadedAt.Add(-concurrencyLimitExpiry - time.Second)
+	examinee.admissions.limits["tenant-ns-1"] = cached
+	admittedExpired, _ := admit(t, examinee, cf, "run1")
+
+	// VERIFY
+	assert.Assert(t, !admittedCached)
+	assert.Assert(t, admittedExpired)
+}
@@ -0,0 +1,375 @@
+package runctl
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	steward "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	k8s "github.com/SAP/stewardci-core/pkg/k8s"
+	errors "github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+type runConfig interface {
+	GetNetworkEgressCIDRs() []string
+	GetNetworkEgressExceptCIDRs() []string
+	IsNetworkEgressDNSAllowed() bool
+	GetNetworkEgressServices() []serviceRef
+	GetClientNamespace() string
+	GetResourceQuotaTemplate() string
+	GetLimitRangeTemplate() string
+	GetAllowedClusterTasks() []string
+	GetAllowedImages() []string
+	GetKillGracePeriod() time.Duration
+	GetResultRules() []resultRule
+	GetCacheStorageClass() string
+	GetCacheSize() k8sresource.Quantity
+	GetCacheMaxAge() time.Duration
+	GetCacheMaxTotalSize() *k8sresource.Quantity
+	GetTenantRunSettings() *steward.TenantRunSettings
+}
+
+const killGracePeriodDefault = 30 * time.Second
+
+var cacheSizeDefault = k8sresource.MustParse("5Gi")
+
+// serviceRef references a service in a namespace.
+type serviceRef struct {
+	Namespace string
+	Name      string
+}
+
+type runConfigImpl struct {
+	networkEgressCIDRs       []string
+	networkEgressExceptCIDRs []string
+	networkEgressDNS         *bool
+	networkEgressServices    []serviceRef
+	clientNamespace          string
+	resourceQuotaTemplate    string
+	limitRangeTemplate       string
+	allowedClusterTasks      []string
+	allowedImages            []string
+	killGracePeriod          *time.Duration
+	resultRules              []resultRule
+	cacheStorageClass        string
+	cacheSize                *k8sresource.Quantity
+	cacheMaxAge              time.Duration
+	cacheMaxTotalSize        *k8sresource.Quantity
+	tenantRunSettings        *steward.TenantRunSettings
+}
+
+// getRunConfig returns the configuration for pipeline runs in the given
+// tenant namespace.
+// It is combined from the annotations of the Steward client namespace
+// the tenant belongs to (if known) and the tenant namespace itself.
+// List values are merged, while single values of the tenant namespace
+// take precedence over those of the client namespace.
+// Resource quota and limit range templates, allowed runtimes, the kill
+// grace period, result rules and build cache settings can only be defined
+// by the client namespace. The run settings of the tenant are only read
+// from the tenant namespace.
+func getRunConfig(factory k8s.ClientFactory, tenantNamespace string) (runConfig, error) {
+	if tenantNamespace == "" {
+		panic("must provide a tenant namespace")
+	}
+
+	newConfig := runConfigImpl{}
+
+	namespace, err := factory.CoreV1().Namespaces().Get(tenantNamespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not get namespace '%s'", tenantNamespace)
+	}
+
+	clientNamespace := namespace.GetAnnotations()[steward.AnnotationClientNamespace]
+	if clientNamespace != "" {
+		client, err := factory.CoreV1().Namespaces().Get(clientNamespace, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not get client namespace '%s' of tenant namespace '%s'", clientNamespace, tenantNamespace)
+		}
+		if err = newConfig.addAnnotations(client.GetAnnotations(), clientNamespace); err != nil {
+			return nil, err
+		}
+		newConfig.clientNamespace = clientNamespace
+		newConfig.resourceQuotaTemplate = client.GetAnnotations()[steward.AnnotationRunResourceQuota]
+		newConfig.limitRangeTemplate = client.GetAnnotations()[steward.AnnotationRunLimitRange]
+		newConfig.allowedClusterTasks = splitList(client.GetAnnotations()[steward.AnnotationRunAllowedClusterTasks])
+		newConfig.allowedImages = splitList(client.GetAnnotations()[steward.AnnotationRunAllowedImages])
+		if value, hasKey := client.GetAnnotations()[steward.AnnotationRunKillGracePeriod]; hasKey {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return nil, errors.Errorf(
+					"annotation '%s' on namespace '%s' has an invalid value: '%s': should be a non-negative duration like '30s'",
+					steward.AnnotationRunKillGracePeriod, clientNamespace, value)
+			}
+			newConfig.killGracePeriod = &duration
+		}
+		if value, hasKey := client.GetAnnotations()[steward.AnnotationRunResultRules]; hasKey {
+			rules, err := parseResultRules(value)
+			if err != nil {
+				return nil, errors.WithMessagef(err,
+					"annotation '%s' on namespace '%s' has an invalid value",
+					steward.AnnotationRunResultRules, clientNamespace)
+			}
+			newConfig.resultRules = rules
+		}
+		if err = newConfig.addCacheAnnotations(client.GetAnnotations(), clientNamespace); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = newConfig.addAnnotations(namespace.GetAnnotations(), tenantNamespace); err != nil {
+		return nil, err
+	}
+	if newConfig.tenantRunSettings, err = parseTenantRunSettings(namespace); err != nil {
+		return nil, err
+	}
+	return &newConfig, nil
+}
+
+// parseTenantRunSettings returns the run settings stored at the given
+// tenant namespace, or nil if there are none.
+func parseTenantRunSettings(namespace *v1.Namespace) (*steward.TenantRunSettings, error) {
+	value, hasKey := namespace.GetAnnotations()[steward.AnnotationTenantRunSettings]
+	if !hasKey {
+		return nil, nil
+	}
+	settings := &steward.TenantRunSettings{}
+	if err := json.Unmarshal([]byte(value), settings); err != nil {
+		return nil, errors.WithMessagef(err,
+			"annotation '%s' on namespace '%s' has an invalid value",
+			steward.AnnotationTenantRunSettings, namespace.GetName())
+	}
+	return settings, nil
+}
+
+func (c *runConfigImpl) addAnnotations(annotations map[string]string, namespace string) error {
+	var value string
+	var hasKey bool
+
+	value, hasKey = annotations[steward.AnnotationRunEgressCIDRs]
+	if hasKey {
+		cidrs, err := parseCIDRList(value, steward.AnnotationRunEgressCIDRs, namespace)
+		if err != nil {
+			return err
+		}
+		c.networkEgressCIDRs = append(c.networkEgressCIDRs, cidrs...)
+	}
+
+	value, hasKey = annotations[steward.AnnotationRunEgressExceptCIDRs]
+	if hasKey {
+		cidrs, err := parseCIDRList(value, steward.AnnotationRunEgressExceptCIDRs, namespace)
+		if err != nil {
+			return err
+		}
+		c.networkEgressExceptCIDRs = append(c.networkEgressExceptCIDRs, cidrs...)
+	}
+
+	value, hasKey = annotations[steward.AnnotationRunEgressDNS]
+	if hasKey {
+		allowed, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf(
+				"annotation '%s' on namespace '%s' has an invalid value: '%s': should be 'true' or 'false'",
+				steward.AnnotationRunEgressDNS, namespace, value)
+		}
+		c.networkEgressDNS = &allowed
+	}
+
+	value, hasKey = annotations[steward.AnnotationRunEgressServices]
+	if hasKey {
+		for _, item := range splitList(value) {
+			parts := strings.Split(item, "/")
+			if len(parts) != 2 ||
+				len(validation.IsDNS1123Label(parts[0])) > 0 ||
+				len(validation.IsDNS1035Label(parts[1])) > 0 {
+				return errors.Errorf(
+					"annotation '%s' on namespace '%s' has an invalid value: '%s':"+
+						" should be a service reference of the form '<namespace>/<name>'",
+					steward.AnnotationRunEgressServices, namespace, item)
+			}
+			c.networkEgressServices = append(c.networkEgressServices, serviceRef{Namespace: parts[0], Name: parts[1]})
+		}
+	}
+
+	return nil
+}
+
+func (c *runConfigImpl) addCacheAnnotations(annotations map[string]string, namespace string) error {
+	c.cacheStorageClass = annotations[steward.AnnotationRunCacheStorageClass]
+
+	if value, hasKey := annotations[steward.AnnotationRunCacheSize]; hasKey {
+		quantity, err := parsePositiveQuantity(value, steward.AnnotationRunCacheSize, namespace)
+		if err != nil {
+			return err
+		}
+		c.cacheSize = quantity
+	}
+
+	if value, hasKey := annotations[steward.AnnotationRunCacheMaxAge]; hasKey {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration < 0 {
+			return errors.Errorf(
+				"annotation '%s' on namespace '%s' has an invalid value: '%s': should be a non-negative duration like '168h'",
+				steward.AnnotationRunCacheMaxAge, namespace, value)
+		}
+		c.cacheMaxAge = duration
+	}
+
+	if value, hasKey := annotations[steward.AnnotationRunCacheMaxTotalSize]; hasKey {
+		quantity, err := parsePositiveQuantity(value, steward.AnnotationRunCacheMaxTotalSize, namespace)
+		if err != nil {
+			return err
+		}
+		c.cacheMaxTotalSize = quantity
+	}
+
+	return nil
+}
+
+func parsePositiveQuantity(value string, annotation string, namespace string) (*k8sresource.Quantity, error) {
+	quantity, err := k8sresource.ParseQuantity(value)
+	if err != nil || quantity.Sign() <= 0 {
+		return nil, errors.Errorf(
+			"annotation '%s' on namespace '%s' has an invalid value: '%s': should be a positive quantity like '5Gi'",
+			annotation, namespace, value)
+	}
+	return &quantity, nil
+}
+
+func parseCIDRList(value string, annotation string, namespace string) ([]string, error) {
+	result := []string{}
+	for _, item := range splitList(value) {
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, errors.Errorf(
+				"annotation '%s' on namespace '%s' has an invalid value: '%s': should be a CIDR like '10.0.0.0/8'",
+				annotation, namespace, item)
+		}
+		result = append(result, ipNet.String())
+	}
+	return result, nil
+}
+
+// splitList splits a comma-separated list and removes empty entries.
+func splitList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func (c *runConfigImpl) GetNetworkEgressCIDRs() []string {
+	return c.networkEgressCIDRs
+}
+
+func (c *runConfigImpl) GetNetworkEgressExceptCIDRs() []string {
+	return c.networkEgressExceptCIDRs
+}
+
+// IsNetworkEgressDNSAllowed returns whether pipeline runs may send
+// DNS queries. Defaults to true.
+func (c *runConfigImpl) IsNetworkEgressDNSAllowed() bool {
+	if c.networkEgressDNS == nil {
+		return true
+	}
+	return *c.networkEgressDNS
+}
+
+func (c *runConfigImpl) GetNetworkEgressServices() []serviceRef {
+	return c.networkEgressServices
+}
+
+// GetClientNamespace returns the name of the Steward client namespace the
+// tenant belongs to, or an empty string if not known.
+func (c *runConfigImpl) GetClientNamespace() string {
+	return c.clientNamespace
+}
+
+// GetResourceQuotaTemplate returns the name of the resource quota in the
+// client namespace to be copied to run namespaces, or an empty string if
+// run namespaces should not get a resource quota.
+func (c *runConfigImpl) GetResourceQuotaTemplate() string {
+	return c.resourceQuotaTemplate
+}
+
+// GetLimitRangeTemplate returns the name of the limit range in the client
+// namespace to be copied to run namespaces, or an empty string if run
+// namespaces should not get a limit range.
+func (c *runConfigImpl) GetLimitRangeTemplate() string {
+	return c.limitRangeTemplate
+}
+
+// GetAllowedClusterTasks returns the names of the Tekton ClusterTasks
+// pipeline runs may select in addition to the default one.
+func (c *runConfigImpl) GetAllowedClusterTasks() []string {
+	return c.allowedClusterTasks
+}
+
+// GetAllowedImages returns the Jenkinsfile Runner images pipeline runs
+// may select. Entries ending with '*' are prefix patterns.
+func (c *runConfigImpl) GetAllowedImages() []string {
+	return c.allowedImages
+}
+
+// GetKillGracePeriod returns how long a killed pipeline run is given to
+// terminate before its run namespace gets deleted. Defaults to 30 seconds.
+func (c *runConfigImpl) GetKillGracePeriod() time.Duration {
+	if c.killGracePeriod == nil {
+		return killGracePeriodDefault
+	}
+	return *c.killGracePeriod
+}
+
+// GetResultRules returns the rules to classify the result of pipeline
+// runs. Configured rules come first, followed by the built-in ones.
+func (c *runConfigImpl) GetResultRules() []resultRule {
+	result := make([]resultRule, 0, len(c.resultRules)+len(defaultResultRules))
+	result = append(result, c.resultRules...)
+	return append(result, defaultResultRules...)
+}
+
+// GetCacheStorageClass returns the storage class of build cache volumes,
+// or an empty string if build caches are disabled.
+func (c *runConfigImpl) GetCacheStorageClass() string {
+	return c.cacheStorageClass
+}
+
+// GetCacheSize returns the size of newly created build caches.
+// Defaults to 5Gi.
+func (c *runConfigImpl) GetCacheSize() k8sresource.Quantity {
+	if c.cacheSize == nil {
+		return cacheSizeDefault
+	}
+	return *c.cacheSize
+}
+
+// GetCacheMaxAge returns after which time unused build caches get
+// evicted, or zero if build caches do not expire.
+func (c *runConfigImpl) GetCacheMaxAge() time.Duration {
+	return c.cacheMaxAge
+}
+
+// GetCacheMaxTotalSize returns the maximum total size of the build caches
+// of a tenant, or nil if not limited.
+func (c *runConfigImpl) GetCacheMaxTotalSize() *k8sresource.Quantity {
+	return c.cacheMaxTotalSize
+}
+
+// GetTenantRunSettings returns the quotas and defaults of the tenant for
+// its pipeline runs. It is never nil.
+func (c *runConfigImpl) GetTenantRunSettings() *steward.TenantRunSettings {
+	if c.tenantRunSettings == nil {
+		return &steward.TenantRunSettings{}
+	}
+	return c.tenantRunSettings
+}
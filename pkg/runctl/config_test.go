@@ -0,0 +1,358 @@
+package runctl
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	assert "gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+ * Within this file the annotation keys are written as string literals instead
+ * of using the respective constants from the Steward API package.
+ * The reason is that tests should fail in case the constants are changed
+ * (incompatible API change).
+ */
+
+func Test_getRunConfig_NoAnnotations_ReturnsDefaults(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(fake.Namespace("tenant1"))
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(config.GetNetworkEgressCIDRs()))
+	assert.Equal(t, 0, len(config.GetNetworkEgressExceptCIDRs()))
+	assert.Equal(t, 0, len(config.GetNetworkEgressServices()))
+	assert.Assert(t, config.IsNetworkEgressDNSAllowed())
+	assert.Equal(t, 30*time.Second, config.GetKillGracePeriod())
+	assert.DeepEqual(t, &api.TenantRunSettings{}, config.GetTenantRunSettings())
+}
+
+func Test_getRunConfig_ReturnsValuesFromAnnotations(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/run-egress-cidrs":        "0.0.0.0/0, 192.168.1.1/32",
+			"steward.sap.com/run-egress-except-cidrs": "10.0.0.0/8",
+			"steward.sap.com/run-egress-dns":          "false",
+			"steward.sap.com/run-egress-services":     "logging/elasticsearch",
+		}),
+	)
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"0.0.0.0/0", "192.168.1.1/32"}, config.GetNetworkEgressCIDRs())
+	assert.DeepEqual(t, []string{"10.0.0.0/8"}, config.GetNetworkEgressExceptCIDRs())
+	assert.DeepEqual(t, []serviceRef{{Namespace: "logging", Name: "elasticsearch"}}, config.GetNetworkEgressServices())
+	assert.Assert(t, !config.IsNetworkEgressDNSAllowed())
+}
+
+func Test_getRunConfig_MergesClientAndTenantNamespace(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("client1", map[string]string{
+			"steward.sap.com/run-egress-cidrs": "1.2.3.0/24",
+			"steward.sap.com/run-egress-dns":   "false",
+		}),
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/client-namespace": "client1",
+			"steward.sap.com/run-egress-cidrs": "4.5.6.0/24",
+			"steward.sap.com/run-egress-dns":   "true",
+		}),
+	)
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"1.2.3.0/24", "4.5.6.0/24"}, config.GetNetworkEgressCIDRs())
+	assert.Assert(t, config.IsNetworkEgressDNSAllowed())
+	assert.Equal(t, "client1", config.GetClientNamespace())
+}
+
+func Test_getRunConfig_ResourceLimitTemplatesFromClientNamespaceOnly(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("client1", map[string]string{
+			"steward.sap.com/run-resource-quota": "quota1",
+			"steward.sap.com/run-limit-range":    "limits1",
+		}),
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/client-namespace":   "client1",
+			"steward.sap.com/run-resource-quota": "tenant-quota",
+			"steward.sap.com/run-limit-range":    "tenant-limits",
+		}),
+	)
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "quota1", config.GetResourceQuotaTemplate())
+	assert.Equal(t, "limits1", config.GetLimitRangeTemplate())
+}
+
+func Test_getRunConfig_KillGracePeriod(t *testing.T) {
+	for _, tc := range []struct {
+		value         string
+		expected      time.Duration
+		expectedError string
+	}{
+		{"0s", 0, ""},
+		{"2m", 2 * time.Minute, ""},
+		{"-1s", 0, `.*run-kill-grace-period.* invalid value: '-1s'.*`},
+		{"1", 0, `.*run-kill-grace-period.* invalid value: '1'.*`},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			// SETUP
+			cf := fake.NewClientFactory(
+				fake.NamespaceWithAnnotations("client1", map[string]string{
+					"steward.sap.com/run-kill-grace-period": tc.value,
+				}),
+				fake.NamespaceWithAnnotations("tenant1", map[string]string{
+					"steward.sap.com/client-namespace": "client1",
+				}),
+			)
+
+			// EXERCISE
+			config, err := getRunConfig(cf, "tenant1")
+
+			// VERIFY
+			if tc.expectedError != "" {
+				assert.Assert(t, err != nil)
+				assert.Assert(t, is.Regexp(tc.expectedError, err.Error()))
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expected, config.GetKillGracePeriod())
+			}
+		})
+	}
+}
+
+func Test_getRunConfig_ResultRules(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("client1", map[string]string{
+			"steward.sap.com/run-result-rules": `[{"podReason": "NodeLost", "result": "error_infra", "resultReason": "NodeLost"}]`,
+		}),
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/client-namespace": "client1",
+		}),
+	)
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	rules := config.GetResultRules()
+	assert.Equal(t, len(defaultResultRules)+1, len(rules))
+	assert.Equal(t, "NodeLost", rules[0].PodReason)
+	assert.Equal(t, defaultResultRules[0].ResultReason, rules[1].ResultReason)
+}
+
+func Test_getRunConfig_ResultRules_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		value         string
+		expectedError string
+	}{
+		{"NoJSON", `foo`, `.*run-result-rules.* invalid value: invalid character.*`},
+		{"NoResult", `[{"podReason": "NodeLost"}]`, `.*run-result-rules.* invalid value: rule 0: invalid result ''`},
+		{"InvalidStatus", `[{"conditionStatus": "True", "result": "error_infra"}]`, `.*run-result-rules.* invalid value: rule 0: invalid condition status 'True'`},
+		{"UnknownStatus", `[{"conditionStatus": "Unknown", "result": "error_infra"}]`, `.*run-result-rules.* invalid value: rule 0: invalid condition status 'Unknown'`},
+		{"InvalidPattern", `[{"messagePattern": "(", "result": "error_infra"}]`, `.*run-result-rules.* invalid value: rule 0: invalid message pattern.*`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			cf := fake.NewClientFactory(
+				fake.NamespaceWithAnnotations("client1", map[string]string{
+					"steward.sap.com/run-result-rules": tc.value,
+				}),
+				fake.NamespaceWithAnnotations("tenant1", map[string]string{
+					"steward.sap.com/client-namespace": "client1",
+				}),
+			)
+
+			// EXERCISE
+			_, err := getRunConfig(cf, "tenant1")
+
+			// VERIFY
+			assert.Assert(t, err != nil)
+			assert.Assert(t, is.Regexp(tc.expectedError, err.Error()))
+		})
+	}
+}
+
+func Test_getRunConfig_Caches(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("client1", map[string]string{
+			"steward.sap.com/run-cache-storage-class":  "standard",
+			"steward.sap.com/run-cache-size":           "2Gi",
+			"steward.sap.com/run-cache-max-age":        "168h",
+			"steward.sap.com/run-cache-max-total-size": "20Gi",
+		}),
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/client-namespace":        "client1",
+			"steward.sap.com/run-cache-storage-class": "tenant-class",
+		}),
+	)
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "standard", config.GetCacheStorageClass())
+	cacheSize := config.GetCacheSize()
+	assert.Equal(t, "2Gi", cacheSize.String())
+	assert.Equal(t, 168*time.Hour, config.GetCacheMaxAge())
+	assert.Equal(t, "20Gi", config.GetCacheMaxTotalSize().String())
+}
+
+func Test_getRunConfig_Caches_Defaults(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(fake.Namespace("tenant1"))
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "", config.GetCacheStorageClass())
+	cacheSize := config.GetCacheSize()
+	assert.Equal(t, "5Gi", cacheSize.String())
+	assert.Equal(t, time.Duration(0), config.GetCacheMaxAge())
+	assert.Assert(t, config.GetCacheMaxTotalSize() == nil)
+}
+
+func Test_getRunConfig_Caches_InvalidValues(t *testing.T) {
+	for _, tc := range []struct {
+		annotation    string
+		value         string
+		expectedError string
+	}{
+		{"steward.sap.com/run-cache-size", "0", `.*run-cache-size.* invalid value: '0'.*`},
+		{"steward.sap.com/run-cache-size", "big", `.*run-cache-size.* invalid value: 'big'.*`},
+		{"steward.sap.com/run-cache-max-age", "-1h", `.*run-cache-max-age.* invalid value: '-1h'.*`},
+		{"steward.sap.com/run-cache-max-total-size", "-5Gi", `.*run-cache-max-total-size.* invalid value: '-5Gi'.*`},
+	} {
+		t.Run(tc.annotation+"="+tc.value, func(t *testing.T) {
+			// SETUP
+			cf := fake.NewClientFactory(
+				fake.NamespaceWithAnnotations("client1", map[string]string{
+					tc.annotation: tc.value,
+				}),
+				fake.NamespaceWithAnnotations("tenant1", map[string]string{
+					"steward.sap.com/client-namespace": "client1",
+				}),
+			)
+
+			// EXERCISE
+			_, err := getRunConfig(cf, "tenant1")
+
+			// VERIFY
+			assert.Assert(t, err != nil)
+			assert.Assert(t, is.Regexp(tc.expectedError, err.Error()))
+		})
+	}
+}
+
+func Test_getRunConfig_TenantRunSettingsFromTenantNamespaceOnly(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("client1", map[string]string{
+			"steward.sap.com/tenant-run-settings": `{"maxConcurrent": 5}`,
+		}),
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/client-namespace":    "client1",
+			"steward.sap.com/tenant-run-settings": `{"maxConcurrent": 2, "maxTimeout": "2h", "runtime": {"image": "image1"}}`,
+		}),
+	)
+
+	// EXERCISE
+	config, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &api.TenantRunSettings{
+		MaxConcurrent: 2,
+		MaxTimeout:    &metav1.Duration{Duration: 2 * time.Hour},
+		Runtime:       &api.Runtime{Image: "image1"},
+	}, config.GetTenantRunSettings())
+}
+
+func Test_getRunConfig_ClientNamespaceNotExisting(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations("tenant1", map[string]string{
+			"steward.sap.com/client-namespace": "client1",
+		}),
+	)
+
+	// EXERCISE
+	_, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.Error(t, err, `could not get client namespace 'client1' of tenant namespace 'tenant1': namespaces "client1" not found`)
+}
+
+func Test_getRunConfig_TenantNamespaceNotExisting(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory()
+
+	// EXERCISE
+	_, err := getRunConfig(cf, "tenant1")
+
+	// VERIFY
+	assert.Error(t, err, `could not get namespace 'tenant1': namespaces "tenant1" not found`)
+}
+
+func Test_getRunConfig_InvalidValues(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		annotation    string
+		value         string
+		expectedError string
+	}{
+		{"CIDR", "steward.sap.com/run-egress-cidrs", "1.2.3.4", `.*run-egress-cidrs.* invalid value: '1\.2\.3\.4'.*`},
+		{"ExceptCIDR", "steward.sap.com/run-egress-except-cidrs", "10.0.0.0/8,foo", `.*run-egress-except-cidrs.* invalid value: 'foo'.*`},
+		{"DNS", "steward.sap.com/run-egress-dns", "maybe", `.*run-egress-dns.* invalid value: 'maybe'.*`},
+		{"ServiceNoNamespace", "steward.sap.com/run-egress-services", "elasticsearch", `.*run-egress-services.* invalid value: 'elasticsearch'.*`},
+		{"ServiceInvalidName", "steward.sap.com/run-egress-services", "ns1/Elastic_Search", `.*run-egress-services.* invalid value: 'ns1/Elastic_Search'.*`},
+		{"TenantRunSettings", "steward.sap.com/tenant-run-settings", "foo", `.*tenant-run-settings.* invalid value: invalid character.*`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			cf := fake.NewClientFactory(
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "tenant1",
+						Annotations: map[string]string{tc.annotation: tc.value},
+					},
+				},
+			)
+
+			// EXERCISE
+			_, err := getRunConfig(cf, "tenant1")
+
+			// VERIFY
+			assert.Assert(t, err != nil)
+			assert.Assert(t, is.Regexp(tc.expectedError, err.Error()))
+		})
+	}
+}
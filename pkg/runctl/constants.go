@@ -12,5 +12,3 @@ const pullSecretName string = ""
 const scmCloneSecretName string = ""
 
 const runClusterRoleName k8s.RoleName = "steward-run"
-
-const defaultBuildTimeout = "60m"
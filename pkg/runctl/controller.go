@@ -5,15 +5,23 @@ based on sample-controller from https://github.com/kubernetes/sample-controller/
 package runctl
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"time"
 
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	listers "github.com/SAP/stewardci-core/pkg/client/listers/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/logging"
 	"github.com/SAP/stewardci-core/pkg/metrics"
+	"github.com/SAP/stewardci-core/pkg/server"
+	"github.com/SAP/stewardci-core/pkg/sharding"
+	"github.com/SAP/stewardci-core/pkg/tracing"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
@@ -22,30 +30,56 @@ import (
 
 const kind = "PipelineRuns"
 
+// killPollInterval is the interval in which killed pipeline runs are
+// checked for termination.
+const killPollInterval = 5 * time.Second
+
 // Controller processes PipelineRun resources
 type Controller struct {
-	factory              k8s.ClientFactory
-	pipelineRunFetcher   k8s.PipelineRunFetcher
-	pipelineRunSynced    cache.InformerSynced
-	pipelineRunLister    listers.PipelineRunLister
-	tektonTaskRunsSynced cache.InformerSynced
-	workqueue            workqueue.RateLimitingInterface
-	metrics              metrics.Metrics
+	factory                  k8s.ClientFactory
+	pipelineRunFetcher       k8s.PipelineRunFetcher
+	pipelineRunSynced        cache.InformerSynced
+	pipelineRunLister        listers.PipelineRunLister
+	tektonTaskRunsSynced     cache.InformerSynced
+	workqueue                workqueue.RateLimitingInterface
+	workqueueProbe           *server.WorkqueueProbe
+	metrics                  metrics.Metrics
+	loggers                  *logging.Loggers
+	logger                   *zap.SugaredLogger
+	config                   *controllerconfig.Store
+	runNamespacePrefix       string
+	runNamespaceRandomLength uint8
+	scope                    *k8s.Scope
+	shards                   *sharding.Shards
+	admissions               *admissions
 }
 
 // NewController creates new Controller
-func NewController(factory k8s.ClientFactory, pipelineRunFetcher k8s.PipelineRunFetcher, metrics metrics.Metrics) *Controller {
+func NewController(factory k8s.ClientFactory, pipelineRunFetcher k8s.PipelineRunFetcher, metrics metrics.Metrics, loggers *logging.Loggers, config *controllerconfig.Store, scope *k8s.Scope, shards *sharding.Shards) *Controller {
 	pipelineRunInformer := factory.StewardInformerFactory().Steward().V1alpha1().PipelineRuns()
 	tektonTaskRunInformer := factory.TektonInformerFactory().Tekton().V1alpha1().TaskRuns()
+	// The naming of run namespaces is fixed on start. Otherwise namespaces
+	// created before a change could neither be deleted nor be found by
+	// the garbage collection anymore.
+	startConfig := config.Get()
 	controller := &Controller{
-		factory:              factory,
-		pipelineRunFetcher:   pipelineRunFetcher,
-		pipelineRunSynced:    pipelineRunInformer.Informer().HasSynced,
-		pipelineRunLister:    pipelineRunInformer.Lister(),
-		tektonTaskRunsSynced: tektonTaskRunInformer.Informer().HasSynced,
-		workqueue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), kind),
-		metrics:              metrics,
+		factory:                  factory,
+		pipelineRunFetcher:       pipelineRunFetcher,
+		pipelineRunSynced:        pipelineRunInformer.Informer().HasSynced,
+		pipelineRunLister:        pipelineRunInformer.Lister(),
+		tektonTaskRunsSynced:     tektonTaskRunInformer.Informer().HasSynced,
+		workqueue:                workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), kind),
+		metrics:                  metrics,
+		loggers:                  loggers,
+		logger:                   loggers.Component(logging.ComponentRunController),
+		config:                   config,
+		runNamespacePrefix:       startConfig.RunNamespacePrefix,
+		runNamespaceRandomLength: uint8(startConfig.RunNamespaceRandomLength),
+		scope:                    scope,
+		shards:                   shards,
+		admissions:               newAdmissions(),
 	}
+	controller.workqueueProbe = server.NewWorkqueueProbe(controller.workqueue, server.DefaultWorkqueueTimeout)
 	pipelineRunInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: controller.addPipelineRun,
 		UpdateFunc: func(old, new interface{}) {
@@ -58,6 +92,7 @@ func NewController(factory k8s.ClientFactory, pipelineRunFetcher k8s.PipelineRun
 			controller.handleTektonTaskRun(new)
 		},
 	})
+	shards.OnChange(controller.enqueueOwned)
 	return controller
 }
 
@@ -65,20 +100,35 @@ func NewController(factory k8s.ClientFactory, pipelineRunFetcher k8s.PipelineRun
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
-	log.Printf("Sync cache")
-	if ok := cache.WaitForCacheSync(stopCh, c.pipelineRunSynced, c.tektonTaskRunsSynced); !ok {
+	c.logger.Info("Sync cache")
+	if ok := cache.WaitForCacheSync(stopCh, c.pipelineRunSynced, c.tektonTaskRunsSynced, c.scope.HasSynced, c.shards.HasSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
-	log.Printf("Start workers")
+	c.logger.Info("Start workers")
+	c.workqueueProbe.Activate()
+	go wait.Until(c.updateStateMetrics, stateMetricsInterval, stopCh)
+	if interval := c.config.Get().OrphanedNamespaceInterval; interval > 0 {
+		go wait.Until(c.collectOrphanedNamespaces, interval, stopCh)
+	}
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
-	log.Printf("Workers running")
+	c.logger.Infow("Workers running", "threadiness", threadiness)
 	<-stopCh
-	log.Printf("Workers stopped")
+	c.logger.Info("Workers stopped")
 	return nil
 }
 
+// CheckReadiness returns an error if the informer caches are not synced
+func (c *Controller) CheckReadiness() error {
+	return server.InformersSynced(c.pipelineRunSynced, c.tektonTaskRunsSynced, c.scope.HasSynced, c.shards.HasSynced)()
+}
+
+// CheckLiveness returns an error if the workqueue is not processed
+func (c *Controller) CheckLiveness() error {
+	return c.workqueueProbe.Check()
+}
+
 func (c *Controller) runWorker() {
 	for c.processNextWorkItem() {
 	}
@@ -101,6 +151,7 @@ func (c *Controller) processNextWorkItem() bool {
 		// put back on the workqueue and attempted again after a back-off
 		// period.
 		defer c.workqueue.Done(obj)
+		defer c.workqueueProbe.Processed()
 		var key string
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -126,7 +177,7 @@ func (c *Controller) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
-		log.Printf("Successfully synced '%s'", key)
+		c.logger.Debugw("Successfully synced", logging.KeyPipelineRun, key)
 		return nil
 	}(obj)
 
@@ -138,31 +189,86 @@ func (c *Controller) processNextWorkItem() bool {
 	return true
 }
 
-func (c *Controller) changeState(pipelineRun k8s.PipelineRun, state api.State) error {
-	oldState, err := pipelineRun.UpdateState(state)
-	if err != nil {
-		return err
-	}
+func (c *Controller) changeState(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun, state api.State) {
+	logger.Infow("Changing state", "state", state)
+	oldState := pipelineRun.UpdateState(state)
 	if oldState != nil {
-		err = c.metrics.ObserveDurationByState(oldState)
+		err := c.metrics.ObserveDurationByState(oldState)
 		if err != nil {
-			log.Printf("Faild to measure state '%+v': '%s'", oldState, err)
+			logger.Warnw("Failed to measure state", "state", oldState.State, "error", err)
 		}
 	}
-	return nil
 }
 
-func (c *Controller) createRunManager(pipelineRun k8s.PipelineRun) RunManager {
-	tenant := k8s.NewTenantNamespace(c.factory, pipelineRun.GetNamespace())
+// storeErrorAsMessage logs the error and stores it as message of the
+// pipeline run.
+func storeErrorAsMessage(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun, err error, message string) {
+	logger.Errorw(message, "state", pipelineRun.GetStatus().State, "error", err)
+	pipelineRun.StoreErrorAsMessage(err, message)
+}
+
+// getLogFields returns the fields identifying the log entries of a
+// reconciliation of the given pipeline run.
+func getLogFields(ctx context.Context, pipelineRun k8s.PipelineRun) []interface{} {
+	fields := []interface{}{
+		logging.KeyPipelineRun, pipelineRun.GetKey(),
+		logging.KeyReconcileID, logging.NewReconcileID(),
+	}
+	if runNamespace := pipelineRun.GetRunNamespace(); runNamespace != "" {
+		fields = append(fields, logging.KeyRunNamespace, runNamespace)
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		fields = append(fields, logging.KeyTraceID, traceID)
+	}
+	return fields
+}
+
+// storeTrace stores the trace of the first reconciliation of the given
+// pipeline run in its status, so that subsequent reconciliations link to
+// it, and the trace ID in its annotations, so that users can look it up.
+// The trace is stored in the status, because users cannot modify it.
+func (c *Controller) storeTrace(ctx context.Context, logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun) {
+	status := pipelineRun.GetStatus()
+	if status.State == api.StateFinished || status.TraceParent != "" {
+		return
+	}
+	traceParent := tracing.TraceParent(ctx)
+	if traceParent == "" {
+		return
+	}
+	pipelineRun.UpdateTraceParent(traceParent)
+	if err := pipelineRun.AddAnnotations(tracing.Annotations(ctx)); err != nil {
+		logger.Warnw("Cannot store trace in pipeline run", "error", err)
+	}
+}
+
+func (c *Controller) createRunManager(pipelineRun k8s.PipelineRun, loggers *logging.Loggers) RunManager {
+	config := c.config.Get()
+	tenant := k8s.NewTenantNamespace(c.factory, pipelineRun.GetNamespace(), loggers.Component(logging.ComponentK8s))
 	workFactory := tenant.TargetClientFactory()
-	namespaceManager := k8s.NewNamespaceManager(c.factory, runNamespacePrefix, runNamespaceRandomLength)
-	return NewRunManager(workFactory, tenant, namespaceManager)
+	namespaceManager := c.newNamespaceManager(loggers)
+	return NewRunManager(workFactory, tenant, namespaceManager, config, loggers.Component(logging.ComponentRunManager))
+}
+
+// newNamespaceManager returns the manager of run namespaces, which
+// observes the durations of namespace operations.
+func (c *Controller) newNamespaceManager(loggers *logging.Loggers) k8s.NamespaceManager {
+	return newObservedNamespaceManager(
+		k8s.NewNamespaceManager(c.factory, c.runNamespacePrefix, c.runNamespaceRandomLength,
+			loggers.Component(logging.ComponentNamespaceManager)),
+		c.metrics)
 }
 
 // syncHandler compares the actual state with the desired, and attempts to
 // converge the two. It then updates the Status block of the Foo resource
 // with the current status of the resource.
-func (c *Controller) syncHandler(key string) error {
+func (c *Controller) syncHandler(key string) (err error) {
+	// the key may have been handed over to another replica after it
+	// has been queued
+	if !c.shards.Owns(key) {
+		c.logger.Debugw("Skip pipeline run owned by other shard", logging.KeyPipelineRun, key)
+		return nil
+	}
 	pipelineRun, err := c.pipelineRunFetcher.ByKey(key)
 	if err != nil {
 		return err
@@ -173,63 +279,115 @@ func (c *Controller) syncHandler(key string) error {
 		return nil
 	}
 
+	start := time.Now()
+	state := pipelineRun.GetStatus().State
+	defer func() {
+		c.metrics.ObserveReconcile(state, time.Since(start), err != nil)
+	}()
+
+	// each reconciliation is recorded in its own trace linked to the
+	// trace of the first reconciliation of the pipeline run
+	ctx, span := tracing.StartLinked(pipelineRun.GetStatus().TraceParent,
+		"reconcile PipelineRun", tracing.KeyPipelineRun.String(key), tracing.KeyState.String(string(state)))
+	defer func() { tracing.End(span, err) }()
+
+	// status changes are collected and written once per reconciliation
+	defer func() {
+		if commitErr := pipelineRun.CommitStatus(); commitErr != nil && err == nil {
+			err = commitErr
+		}
+	}()
+
+	loggers := c.loggers.With(getLogFields(ctx, pipelineRun)...)
+	logger := loggers.Component(logging.ComponentRunController)
+
 	// Check if object has deletion timestamp
 	// If not, try to add finalizer if missing
 	if pipelineRun.HasDeletionTimestamp() {
-		runManager := c.createRunManager(pipelineRun)
-		err = runManager.Cleanup(pipelineRun)
+		runManager := c.createRunManager(pipelineRun, loggers)
+		err = runManager.Cleanup(ctx, pipelineRun)
 		if err == nil {
-			pipelineRun.DeleteFinalizerIfExists()
+			// the pipeline run may be gone once the finalizer is removed
+			if err = pipelineRun.CommitStatus(); err == nil {
+				pipelineRun.DeleteFinalizerIfExists()
+			}
 		}
 		return err
 	}
 	pipelineRun.AddFinalizer()
+	c.storeTrace(ctx, logger, pipelineRun)
+
+	runManager := c.createRunManager(pipelineRun, loggers)
 
 	// Check if pipeline run is killed or completed
-	if c.skipKilledOrCompleted(pipelineRun) {
+	if c.handleKill(logger, pipelineRun, runManager) {
 		return nil
 	}
 
-	runManager := c.createRunManager(pipelineRun)
 	// Process pipeline run based on current state
-	switch state := pipelineRun.GetStatus().State; state {
+	switch state {
 	// TODO fix #117
 	// Runs might be left in state `preparing` after a controller crash.
 	// Those must be recovered.
 	case api.StateUndefined:
-		c.changeState(pipelineRun, api.StatePreparing)
-		err = runManager.Start(pipelineRun)
+		invalid, err := c.resolveRerun(pipelineRun)
+		if err != nil {
+			if !invalid {
+				return err
+			}
+			pipelineRun.UpdateResult(api.ResultErrorContent)
+			storeErrorAsMessage(logger, pipelineRun, err, "error syncing resource")
+			c.changeState(logger, pipelineRun, api.StateFinished)
+			return nil
+		}
+		admitted, err := c.admit(logger, pipelineRun)
+		if err != nil {
+			return err
+		}
+		if !admitted {
+			c.workqueue.AddAfter(key, queuedRunPollInterval)
+			return nil
+		}
+		c.changeState(logger, pipelineRun, api.StatePreparing)
+		err = runManager.Start(ctx, pipelineRun)
 		if err != nil {
-			pipelineRun.StoreErrorAsMessage(err, "error syncing resource")
-			c.changeState(pipelineRun, api.StateCleaning)
+			storeErrorAsMessage(logger, pipelineRun, err, "error syncing resource")
+			c.changeState(logger, pipelineRun, api.StateCleaning)
 			return nil
 		}
-		c.metrics.CountStart()
-		c.changeState(pipelineRun, api.StateWaiting)
+		c.metrics.CountStart(c.getRunLabels(logger, pipelineRun))
+		c.changeState(logger, pipelineRun, api.StateWaiting)
 	case api.StateWaiting:
 		run, err := runManager.GetRun(pipelineRun)
 		if err != nil {
-			pipelineRun.StoreErrorAsMessage(err, "error syncing resource")
-			c.changeState(pipelineRun, api.StateCleaning)
+			storeErrorAsMessage(logger, pipelineRun, err, "error syncing resource")
+			c.changeState(logger, pipelineRun, api.StateCleaning)
 			return nil
 		}
 		started := run.GetStartTime()
 		if started != nil {
-			c.changeState(pipelineRun, api.StateRunning)
+			c.changeState(logger, pipelineRun, api.StateRunning)
+			creationTimestamp := pipelineRun.GetCreationTimestamp()
+			// measured until the state changes to running, not until the
+			// start of the TaskRun
+			c.metrics.ObserveTimeToStart(c.getRunLabels(logger, pipelineRun), time.Since(creationTimestamp.Time))
 		}
 	case api.StateRunning:
 		run, err := runManager.GetRun(pipelineRun)
 		if err != nil {
-			pipelineRun.StoreErrorAsMessage(err, "error syncing resource")
-			c.changeState(pipelineRun, api.StateCleaning)
+			storeErrorAsMessage(logger, pipelineRun, err, "error syncing resource")
+			c.changeState(logger, pipelineRun, api.StateCleaning)
 			return nil
 		}
 		containerInfo := run.GetContainerInfo()
 		pipelineRun.UpdateContainer(containerInfo)
+		pipelineRun.UpdateSteps(run.GetSteps())
 		if finished, result := run.IsFinished(); finished {
 			var msg string
+			var outputs map[string]string
+			var outputsErr error
 			if containerInfo != nil && containerInfo.Terminated != nil {
-				msg = containerInfo.Terminated.Message
+				msg, outputs, outputsErr = parseTerminationMessage(containerInfo.Terminated.Message)
 			}
 			if msg == "" {
 				cond := run.GetSucceededCondition()
@@ -238,43 +396,132 @@ func (c *Controller) syncHandler(key string) error {
 				}
 			}
 			pipelineRun.UpdateMessage(msg)
+			if outputsErr != nil {
+				storeErrorAsMessage(logger, pipelineRun, outputsErr, "error processing pipeline outputs")
+			} else if outputs != nil {
+				pipelineRun.UpdateOutputs(outputs)
+			}
 			pipelineRun.UpdateResult(result)
-			c.changeState(pipelineRun, api.StateCleaning)
-			c.metrics.CountResult(result)
+			pipelineRun.UpdateResultReason(run.GetResultReason())
+			c.updateTestSummary(logger, pipelineRun, runManager)
+			c.changeState(logger, pipelineRun, api.StateCleaning)
+			c.metrics.CountResult(result, run.GetResultReason(), c.getRunLabels(logger, pipelineRun))
+		}
+	case api.StateKilling:
+		terminated, err := runManager.IsTerminated(pipelineRun)
+		if err != nil {
+			storeErrorAsMessage(logger, pipelineRun, err, "error syncing resource")
+			c.changeState(logger, pipelineRun, api.StateCleaning)
+			return nil
 		}
+		if !terminated {
+			remaining := c.getKillGracePeriod(logger, pipelineRun) - time.Since(pipelineRun.GetStatus().StateDetails.StartedAt.Time)
+			if remaining > 0 {
+				if remaining > killPollInterval {
+					remaining = killPollInterval
+				}
+				c.workqueue.AddAfter(key, remaining)
+				return nil
+			}
+			logger.Info("Grace period for killed pipeline run expired")
+		}
+		c.changeState(logger, pipelineRun, api.StateCleaning)
 	case api.StateCleaning:
-		err = runManager.Cleanup(pipelineRun)
+		err = runManager.Cleanup(ctx, pipelineRun)
 		if err == nil {
-			c.changeState(pipelineRun, api.StateFinished)
+			c.changeState(logger, pipelineRun, api.StateFinished)
 		}
 		return err
 	default:
-		log.Printf("Skip PipelineRun with state %s", pipelineRun.GetStatus().State)
+		logger.Debugw("Skip PipelineRun", "state", pipelineRun.GetStatus().State)
 	}
 	return nil
 }
 
-// skipKilledOrCompleted checks if pipeline run is killed or completed.
-func (c *Controller) skipKilledOrCompleted(pipelineRun k8s.PipelineRun) bool {
-	intent := pipelineRun.GetSpec().Intent
-	if intent == api.IntentKill {
-		switch result := pipelineRun.GetStatus().Result; result {
-		case api.ResultUndefined:
-			pipelineRun.UpdateMessage("Killed by user")
-			pipelineRun.UpdateResult(api.ResultKilled)
-			c.changeState(pipelineRun, api.StateCleaning)
-			return true
-		case api.ResultKilled:
-			return true
-		default:
-			message := "Cannot kill completed pipeline run"
-			if !(message == pipelineRun.GetStatus().Message) {
-				pipelineRun.UpdateMessage(message)
+// updateTestSummary stores the summary of the test reports of a finished
+// pipeline run. Failures are logged only, as test reports are optional.
+func (c *Controller) updateTestSummary(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun, runManager RunManager) {
+	summary, err := runManager.GetTestSummary(pipelineRun)
+	if err != nil {
+		logger.Warnw("Failed to get test summary", "error", err)
+		return
+	}
+	if summary != nil {
+		pipelineRun.UpdateTestSummary(summary)
+	}
+}
+
+// handleKill processes the kill intent of a pipeline run.
+// A pipeline run which has been started gets cancelled and is given a
+// grace period to terminate before it is cleaned up.
+// Returns true if the pipeline run must not be processed any further.
+func (c *Controller) handleKill(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun, runManager RunManager) bool {
+	spec := pipelineRun.GetSpec()
+	if spec.Intent != api.IntentKill {
+		return false
+	}
+	status := pipelineRun.GetStatus()
+	switch result := status.Result; result {
+	case api.ResultUndefined:
+		switch status.State {
+		case api.StateCleaning, api.StateFinished:
+			// failed before, cleanup already triggered
+			return false
+		}
+		pipelineRun.UpdateMessage(killMessage(spec.KillRequest))
+		pipelineRun.UpdateResult(api.ResultKilled)
+		switch status.State {
+		case api.StateUndefined:
+			c.changeState(logger, pipelineRun, api.StateFinished)
+		case api.StateWaiting, api.StateRunning:
+			if err := runManager.Cancel(pipelineRun); err != nil {
+				storeErrorAsMessage(logger, pipelineRun, err, "error cancelling pipeline run")
+				c.changeState(logger, pipelineRun, api.StateCleaning)
+				return true
 			}
-			return true
+			c.changeState(logger, pipelineRun, api.StateKilling)
+		default:
+			c.changeState(logger, pipelineRun, api.StateCleaning)
 		}
+		return true
+	case api.ResultKilled:
+		// continue killing and cleanup
+		return false
+	default:
+		if status.State != api.StateFinished {
+			return false
+		}
+		message := "Cannot kill completed pipeline run"
+		if !(message == status.Message) {
+			pipelineRun.UpdateMessage(message)
+		}
+		return true
+	}
+}
+
+func killMessage(request *api.KillRequest) string {
+	message := "Killed by user"
+	if request == nil {
+		return message
+	}
+	if request.User != "" {
+		message = fmt.Sprintf("%s '%s'", message, request.User)
+	}
+	if request.Reason != "" {
+		message = fmt.Sprintf("%s: %s", message, request.Reason)
+	}
+	return message
+}
+
+// getKillGracePeriod returns the time a killed pipeline run is given to
+// terminate.
+func (c *Controller) getKillGracePeriod(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun) time.Duration {
+	config, err := getRunConfig(c.factory, pipelineRun.GetNamespace())
+	if err != nil {
+		logger.Warnw("Cannot load configuration, using default kill grace period", "error", err)
+		return killGracePeriodDefault
 	}
-	return false
+	return config.GetKillGracePeriod()
 }
 
 func (c *Controller) addPipelineRun(obj interface{}) {
@@ -284,7 +531,10 @@ func (c *Controller) addPipelineRun(obj interface{}) {
 		utilruntime.HandleError(err)
 		return
 	}
-	log.Printf("Add to workqueue '%s'", key)
+	if !c.scope.Contains(obj.(metav1.Object)) || !c.shards.Owns(key) {
+		return
+	}
+	c.logger.Debugw("Add to workqueue", logging.KeyPipelineRun, key)
 	c.workqueue.Add(key)
 }
 
@@ -306,13 +556,48 @@ func (c *Controller) handleTektonTaskRun(obj interface{}) {
 			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
 			return
 		}
-		log.Printf("Recovered deleted object '%s' from tombstone", object.GetName())
+		c.logger.Debugw("Recovered deleted object from tombstone", "name", object.GetName())
 	}
-	log.Printf("Processing object: %s", object.GetSelfLink())
+	c.logger.Debugw("Processing object", "selfLink", object.GetSelfLink())
 	annotations := object.GetAnnotations()
 	runKey := annotations[annotationPipelineRunKey]
 	if runKey != "" {
-		log.Printf("Add to workqueue '%s'", runKey)
+		if !c.shards.Owns(runKey) || !c.isInScope(runKey) {
+			return
+		}
+		c.logger.Debugw("Add to workqueue", logging.KeyPipelineRun, runKey)
 		c.workqueue.Add(runKey)
 	}
 }
+
+// isInScope returns whether the pipeline run with the given key is known
+// and in the scope of the controller.
+func (c *Controller) isInScope(key string) bool {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return false
+	}
+	pipelineRun, err := c.pipelineRunLister.PipelineRuns(namespace).Get(name)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			utilruntime.HandleError(err)
+		}
+		return false
+	}
+	return c.scope.Contains(pipelineRun)
+}
+
+// enqueueOwned adds all pipeline runs in scope owned by this replica to
+// the workqueue. It is called when the shards of the replica changed to
+// take over pipeline runs of other replicas.
+func (c *Controller) enqueueOwned() {
+	pipelineRuns, err := c.pipelineRunLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, pipelineRun := range pipelineRuns {
+		c.addPipelineRun(pipelineRun)
+	}
+}
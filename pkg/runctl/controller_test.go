@@ -4,19 +4,29 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	k8s "github.com/SAP/stewardci-core/pkg/k8s"
 	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
 	mocks "github.com/SAP/stewardci-core/pkg/k8s/mocks"
+	"github.com/SAP/stewardci-core/pkg/logging"
 	metrics "github.com/SAP/stewardci-core/pkg/metrics"
+	"github.com/SAP/stewardci-core/pkg/sharding"
 	gomock "github.com/golang/mock/gomock"
 	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
 	assert "gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func Test_Controller_MissingSecret(t *testing.T) {
@@ -25,6 +35,7 @@ func Test_Controller_MissingSecret(t *testing.T) {
 		fake.PipelineRun("run1", "ns1", api.PipelineSpec{
 			Secrets: []string{"secret1"},
 		}),
+		fake.Namespace("ns1"),
 		// no "secret1" here
 	)
 
@@ -49,6 +60,7 @@ func Test_Controller_Success(t *testing.T) {
 		fake.PipelineRun("run1", "ns1", api.PipelineSpec{
 			Secrets: []string{"secret1"},
 		}),
+		fake.Namespace("ns1"),
 		fake.Secret("secret1", "ns1"),
 		fake.ClusterRole(string(runClusterRoleName)),
 	)
@@ -72,6 +84,7 @@ func Test_Controller_Running(t *testing.T) {
 		fake.PipelineRun("run1", "ns1", api.PipelineSpec{
 			Secrets: []string{"secret1"},
 		}),
+		fake.Namespace("ns1"),
 		fake.Secret("secret1", "ns1"),
 		fake.ClusterRole(string(runClusterRoleName)),
 	)
@@ -100,6 +113,7 @@ func Test_Controller_Deletion(t *testing.T) {
 	})
 	cf := fake.NewClientFactory(
 		pr,
+		fake.Namespace("ns1"),
 		fake.Secret("secret1", "ns1"),
 		fake.ClusterRole(string(runClusterRoleName)),
 	)
@@ -135,7 +149,7 @@ func Test_Controller_syncHandler_givesUp_onPipelineRunNotFound(t *testing.T) {
 		Return(nil, nil)
 
 	// EXERCISE
-	examinee := NewController(cf, mockPipelineRunFetcher, metrics.NewMetrics())
+	examinee := NewController(cf, mockPipelineRunFetcher, metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
 
 	// VERIFY
 	assert.NilError(t, examinee.syncHandler("foo/bar"))
@@ -154,7 +168,7 @@ func Test_Controller_syncHandler_initiatesRetrying_on500DuringPipelineRunFetch(t
 		Return(nil, k8serrors.NewInternalError(fmt.Errorf(message)))
 
 	// EXERCISE
-	examinee := NewController(cf, mockPipelineRunFetcher, metrics.NewMetrics())
+	examinee := NewController(cf, mockPipelineRunFetcher, metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
 
 	// VERIFY
 	assert.ErrorContains(t, examinee.syncHandler("foo/bar"), message)
@@ -236,13 +250,104 @@ func Test_Controller_syncHandler_OnTimeout(t *testing.T) {
 	assert.Equal(t, api.StateFinished, status.State)
 	assert.Equal(t, status.State, status.StateDetails.State)
 	assert.Equal(t, api.ResultTimeout, status.Result)
+	assert.Equal(t, api.ResultReasonTimeout, status.ResultReason)
 	assert.Equal(t, "message from Succeeded condition", status.Message)
 }
 
+func Test_Controller_syncHandler_OnCompletion_StoresOutputs(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		StewardObjectFromJSON(t, `{
+			"apiVersion": "steward.sap.com/v1alpha1",
+			"kind": "PipelineRun",
+			"metadata": {
+				"name": "run1",
+				"namespace": "tenant-ns-1"
+			},
+			"spec": {},
+			"status": {
+				"namespace": "steward-run-ns-1",
+				"state": "running"
+			}
+		}`),
+		TektonObjectFromJSON(t, `{
+			"apiVersion": "tekton.dev/v1alpha1",
+			"kind": "TaskRun",
+			"metadata": {
+				"name": "steward-jenkinsfile-runner",
+				"namespace": "steward-run-ns-1"
+			},
+			"spec": {},
+			"status": {
+				"conditions": [
+					{
+						"message": "All Steps have completed executing",
+						"reason": "Succeeded",
+						"status": "True",
+						"type": "Succeeded"
+					}
+				],
+				"steps": [
+					{
+						"name": "jenkinsfile-runner",
+						"terminated": {
+							"exitCode": 0,
+							"message": "{\"message\": \"build ok\", \"outputs\": {\"version\": \"1.2.3\"}}"
+						}
+					}
+				]
+			}
+		}`),
+	)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("tenant-ns-1/run1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	status := getPipelineRun("run1", "tenant-ns-1", cf).GetStatus()
+	assert.Equal(t, api.StateCleaning, status.State)
+	assert.Equal(t, api.ResultSuccess, status.Result)
+	assert.Equal(t, "build ok", status.Message)
+	assert.DeepEqual(t, map[string]string{"version": "1.2.3"}, status.Outputs)
+	statusUpdates := 0
+	for _, action := range cf.StewardClientset().Actions() {
+		if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+			statusUpdates++
+		}
+	}
+	assert.Equal(t, 1, statusUpdates)
+}
+
+func Test_Controller_syncHandler_StatusUpdateFails_ReturnsError(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		fake.PipelineRun("run1", "tenant-ns-1", api.PipelineSpec{Intent: api.IntentKill}),
+	)
+	cf.StewardClientset().PrependReactor("update", "pipelineruns", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "status" {
+			return false, nil, nil
+		}
+		return true, nil, fmt.Errorf("status update failed")
+	})
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("tenant-ns-1/run1")
+
+	// VERIFY
+	assert.ErrorContains(t, err, "status update failed")
+	status := getPipelineRun("run1", "tenant-ns-1", cf).GetStatus()
+	assert.Equal(t, api.StateUndefined, status.State)
+}
+
 func startController(t *testing.T, cf *fake.ClientFactory) chan struct{} {
 	stopCh := make(chan struct{}, 0)
 	metrics := metrics.NewMetrics()
-	controller := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics)
+	controller := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics, logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
 	cf.StewardInformerFactory().Start(stopCh)
 	cf.TektonInformerFactory().Start(stopCh)
 	go start(t, controller, stopCh)
@@ -288,3 +393,322 @@ func getTektonTaskRun(namespace string, cf *fake.ClientFactory) (*tekton.TaskRun
 func updateTektonTaskRun(taskRun *tekton.TaskRun, namespace string, cf *fake.ClientFactory) (*tekton.TaskRun, error) {
 	return cf.TektonV1alpha1().TaskRuns(namespace).Update(taskRun)
 }
+
+func Test_Controller_syncHandler_Kill_CancelsTaskRun(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		StewardObjectFromJSON(t, `{
+			"apiVersion": "steward.sap.com/v1alpha1",
+			"kind": "PipelineRun",
+			"metadata": {
+				"name": "run1",
+				"namespace": "tenant-ns-1"
+			},
+			"spec": {
+				"intent": "kill",
+				"killRequest": {
+					"user": "user1",
+					"reason": "not needed anymore"
+				}
+			},
+			"status": {
+				"namespace": "steward-run-ns-1",
+				"state": "running"
+			}
+		}`),
+		TektonObjectFromJSON(t, `{
+			"apiVersion": "tekton.dev/v1alpha1",
+			"kind": "TaskRun",
+			"metadata": {
+				"name": "steward-jenkinsfile-runner",
+				"namespace": "steward-run-ns-1"
+			},
+			"spec": {}
+		}`),
+	)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("tenant-ns-1/run1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	status := getPipelineRun("run1", "tenant-ns-1", cf).GetStatus()
+	assert.Equal(t, api.StateKilling, status.State)
+	assert.Equal(t, api.ResultKilled, status.Result)
+	assert.Equal(t, "Killed by user 'user1': not needed anymore", status.Message)
+	taskRun, err := getTektonTaskRun("steward-run-ns-1", cf)
+	assert.NilError(t, err)
+	assert.Assert(t, taskRun.IsCancelled())
+}
+
+func Test_Controller_syncHandler_Kill_NotStarted(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		fake.PipelineRun("run1", "tenant-ns-1", api.PipelineSpec{Intent: api.IntentKill}),
+	)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("tenant-ns-1/run1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	status := getPipelineRun("run1", "tenant-ns-1", cf).GetStatus()
+	assert.Equal(t, api.StateFinished, status.State)
+	assert.Equal(t, api.ResultKilled, status.Result)
+	assert.Equal(t, "Killed by user", status.Message)
+}
+
+func Test_Controller_syncHandler_Killing(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		gracePeriod   string
+		podPhase      string
+		expectedState api.State
+	}{
+		{"PodRunning", "1h", "Running", api.StateKilling},
+		{"PodTerminated", "1h", "Failed", api.StateCleaning},
+		{"GracePeriodExpired", "0s", "Running", api.StateCleaning},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			cf := fake.NewClientFactory(
+				fake.NamespaceWithAnnotations("client-ns-1", map[string]string{
+					api.AnnotationRunKillGracePeriod: tc.gracePeriod,
+				}),
+				fake.NamespaceWithAnnotations("tenant-ns-1", map[string]string{
+					api.AnnotationClientNamespace: "client-ns-1",
+				}),
+				StewardObjectFromJSON(t, `{
+					"apiVersion": "steward.sap.com/v1alpha1",
+					"kind": "PipelineRun",
+					"metadata": {
+						"name": "run1",
+						"namespace": "tenant-ns-1"
+					},
+					"spec": {
+						"intent": "kill"
+					},
+					"status": {
+						"namespace": "steward-run-ns-1",
+						"state": "killing",
+						"stateDetails": {
+							"state": "killing",
+							"startedAt": "`+metav1.Now().Format(time.RFC3339)+`"
+						},
+						"result": "killed"
+					}
+				}`),
+				TektonObjectFromJSON(t, `{
+					"apiVersion": "tekton.dev/v1alpha1",
+					"kind": "TaskRun",
+					"metadata": {
+						"name": "steward-jenkinsfile-runner",
+						"namespace": "steward-run-ns-1"
+					},
+					"spec": {
+						"status": "TaskRunCancelled"
+					},
+					"status": {
+						"podName": "pod1"
+					}
+				}`),
+				CoreV1ObjectFromJSON(t, `{
+					"apiVersion": "v1",
+					"kind": "Pod",
+					"metadata": {
+						"name": "pod1",
+						"namespace": "steward-run-ns-1"
+					},
+					"status": {
+						"phase": "`+tc.podPhase+`"
+					}
+				}`),
+			)
+			examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+			// EXERCISE
+			err := examinee.syncHandler("tenant-ns-1/run1")
+
+			// VERIFY
+			assert.NilError(t, err)
+			status := getPipelineRun("run1", "tenant-ns-1", cf).GetStatus()
+			assert.Equal(t, tc.expectedState, status.State)
+			assert.Equal(t, api.ResultKilled, status.Result)
+		})
+	}
+}
+
+func Test_Controller_handleTektonTaskRun_SkipsPipelineRunsOutOfScope(t *testing.T) {
+	// SETUP
+	runInScope := fake.PipelineRun("run1", "ns1", api.PipelineSpec{})
+	runInScope.SetLabels(map[string]string{"steward": "prod"})
+	cf := fake.NewClientFactory(runInScope, fake.PipelineRun("run2", "ns1", api.PipelineSpec{}))
+	scope, err := k8s.NewScope(&k8s.ScopeConfig{Selector: "steward=prod"}, cf.CoreV1(), time.Minute)
+	assert.NilError(t, err)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), scope, sharding.NewSingleShard())
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	cf.StewardInformerFactory().Start(stopCh)
+	cf.StewardInformerFactory().WaitForCacheSync(stopCh)
+	newTaskRun := func(runKey string) *tekton.TaskRun {
+		return &tekton.TaskRun{ObjectMeta: metav1.ObjectMeta{
+			Name:        tektonTaskRunName,
+			Namespace:   "run-ns1",
+			Annotations: map[string]string{annotationPipelineRunKey: runKey},
+		}}
+	}
+
+	// EXERCISE
+	examinee.handleTektonTaskRun(newTaskRun("ns1/run2"))
+	examinee.handleTektonTaskRun(newTaskRun("ns1/unknown"))
+	examinee.handleTektonTaskRun(newTaskRun("ns1/run1"))
+
+	// VERIFY
+	assert.Equal(t, 1, examinee.workqueue.Len())
+	key, _ := examinee.workqueue.Get()
+	assert.Equal(t, "ns1/run1", key)
+}
+
+func Test_Controller_addPipelineRun_SkipsPipelineRunsOfOtherShards(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory()
+	config := sharding.NewConfig("group1")
+	config.Enabled = true
+	config.Identity = "member1"
+	// shards without members do not own any pipeline run
+	shards, err := sharding.NewShards(cf.CoordinationV1beta1(), config, zap.NewNop().Sugar())
+	assert.NilError(t, err)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), shards)
+
+	// EXERCISE
+	examinee.addPipelineRun(fake.PipelineRun("run1", "ns1", api.PipelineSpec{}))
+
+	// VERIFY
+	assert.Equal(t, 0, examinee.workqueue.Len())
+}
+
+func Test_Controller_syncHandler_LinksToFirstReconciliation(t *testing.T) {
+	// SETUP
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	pipelineRun := fake.PipelineRun("run1", "ns1", api.PipelineSpec{})
+	pipelineRun.Status.State = api.StateWaiting
+	cf := fake.NewClientFactory(pipelineRun)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("ns1/run1")
+	assert.NilError(t, err)
+	err = examinee.syncHandler("ns1/run1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	run, err := getRun("run1", "ns1", cf)
+	assert.NilError(t, err)
+	var reconciles []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "reconcile PipelineRun" {
+			reconciles = append(reconciles, span)
+		}
+	}
+	assert.Equal(t, 2, len(reconciles))
+	first := reconciles[0].SpanContext()
+	assert.Equal(t, first.TraceID().String(), run.GetAnnotations()[api.AnnotationTraceID])
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", first.TraceID(), first.SpanID()), run.Status.TraceParent)
+	assert.Assert(t, reconciles[1].SpanContext().TraceID() != first.TraceID())
+	assert.Assert(t, !reconciles[1].Parent().IsValid())
+	assert.Equal(t, 1, len(reconciles[1].Links()))
+	assert.Equal(t, first.SpanID(), reconciles[1].Links()[0].SpanContext.SpanID())
+}
+
+func Test_Controller_syncHandler_IgnoresTraceAnnotations(t *testing.T) {
+	// SETUP
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	pipelineRun := fake.PipelineRun("run1", "ns1", api.PipelineSpec{})
+	pipelineRun.Status.State = api.StateWaiting
+	pipelineRun.SetAnnotations(map[string]string{
+		api.AnnotationTraceID:          "0af7651916cd43dd8448eb211c80319c",
+		"steward.sap.com/trace-parent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	})
+	cf := fake.NewClientFactory(pipelineRun)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("ns1/run1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	run, err := getRun("run1", "ns1", cf)
+	assert.NilError(t, err)
+	ended := recorder.Ended()
+	reconcile := ended[len(ended)-1]
+	assert.Equal(t, "reconcile PipelineRun", reconcile.Name())
+	assert.Equal(t, 0, len(reconcile.Links()))
+	assert.Equal(t, reconcile.SpanContext().TraceID().String(), run.GetAnnotations()[api.AnnotationTraceID])
+}
+
+// timeToStartMetrics records the observed time to start of pipeline runs.
+type timeToStartMetrics struct {
+	metrics.Metrics
+	observed []time.Duration
+}
+
+func (m *timeToStartMetrics) ObserveTimeToStart(labels metrics.RunLabels, duration time.Duration) {
+	m.observed = append(m.observed, duration)
+}
+
+func Test_Controller_syncHandler_Started_ObservesTimeToStart(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		StewardObjectFromJSON(t, `{
+			"apiVersion": "steward.sap.com/v1alpha1",
+			"kind": "PipelineRun",
+			"metadata": {
+				"name": "run1",
+				"namespace": "tenant-ns-1",
+				"creationTimestamp": "2019-10-01T10:00:00Z"
+			},
+			"spec": {},
+			"status": {
+				"namespace": "steward-run-ns-1",
+				"state": "waiting"
+			}
+		}`),
+		TektonObjectFromJSON(t, `{
+			"apiVersion": "tekton.dev/v1alpha1",
+			"kind": "TaskRun",
+			"metadata": {
+				"name": "steward-jenkinsfile-runner",
+				"namespace": "steward-run-ns-1"
+			},
+			"spec": {},
+			"status": {
+				"startTime": "2019-10-01T10:05:00Z"
+			}
+		}`),
+	)
+	recorder := &timeToStartMetrics{Metrics: metrics.NewMetrics()}
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), recorder, logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+	created, err := time.Parse(time.RFC3339, "2019-10-01T10:00:00Z")
+	assert.NilError(t, err)
+	minimum := time.Since(created)
+
+	// EXERCISE
+	err = examinee.syncHandler("tenant-ns-1/run1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, api.StateRunning, getPipelineRun("run1", "tenant-ns-1", cf).GetStatus().State)
+	assert.Equal(t, 1, len(recorder.observed))
+	assert.Assert(t, recorder.observed[0] >= minimum)
+}
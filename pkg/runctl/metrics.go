@@ -0,0 +1,75 @@
+package runctl
+
+import (
+	"context"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/metrics"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// stateMetricsInterval is the interval in which the number of pipeline
+// runs per state is updated.
+const stateMetricsInterval = 15 * time.Second
+
+// getRunLabels returns the metrics labels of the given pipeline run.
+// If the Steward client cannot be determined, the client label is empty.
+func (c *Controller) getRunLabels(logger *zap.SugaredLogger, pipelineRun k8s.PipelineRun) metrics.RunLabels {
+	result := metrics.RunLabels{Tenant: pipelineRun.GetNamespace()}
+	config, err := getRunConfig(c.factory, pipelineRun.GetNamespace())
+	if err != nil {
+		logger.Warnw("Cannot determine client of pipeline run for metrics", "error", err)
+		return result
+	}
+	result.Client = config.GetClientNamespace()
+	return result
+}
+
+func (c *Controller) updateStateMetrics() {
+	list, err := c.pipelineRunLister.List(labels.Everything())
+	if err != nil {
+		c.logger.Warnw("Cannot update pipeline run metrics", "error", err)
+		return
+	}
+	counts := map[api.State]int{}
+	for _, pipelineRun := range list {
+		if !c.scope.Contains(pipelineRun) || !c.shards.Owns(pipelineRun.GetNamespace()+"/"+pipelineRun.GetName()) {
+			continue
+		}
+		counts[pipelineRun.Status.State]++
+	}
+	c.metrics.SetRunsByState(counts)
+}
+
+// observedNamespaceManager is a k8s.NamespaceManager observing the
+// durations of namespace operations.
+type observedNamespaceManager struct {
+	k8s.NamespaceManager
+	metrics metrics.Metrics
+}
+
+func newObservedNamespaceManager(namespaceManager k8s.NamespaceManager, metrics metrics.Metrics) k8s.NamespaceManager {
+	return &observedNamespaceManager{
+		NamespaceManager: namespaceManager,
+		metrics:          metrics,
+	}
+}
+
+// Create creates a namespace and observes the duration
+func (m *observedNamespaceManager) Create(ctx context.Context, name string, annotations map[string]string) (string, error) {
+	defer m.observe("create", time.Now())
+	return m.NamespaceManager.Create(ctx, name, annotations)
+}
+
+// Delete deletes a namespace and observes the duration
+func (m *observedNamespaceManager) Delete(ctx context.Context, name string) error {
+	defer m.observe("delete", time.Now())
+	return m.NamespaceManager.Delete(ctx, name)
+}
+
+func (m *observedNamespaceManager) observe(operation string, start time.Time) {
+	m.metrics.ObserveNamespaceOperation(operation, time.Since(start))
+}
@@ -0,0 +1,128 @@
+package runctl
+
+import (
+	"context"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/logging"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Results of deletions of orphaned run namespaces reported as metrics
+const (
+	orphanDeleted = "deleted"
+	orphanDryRun  = "dry-run"
+	orphanFailed  = "failed"
+)
+
+// collectOrphanedNamespaces deletes run namespaces which are not used by
+// a pipeline run anymore. They are left over if the controller crashes
+// before the namespace is stored in the pipeline run, or if a pipeline
+// run is deleted without its finalizer.
+// In dry-run mode orphaned namespaces are only logged.
+func (c *Controller) collectOrphanedNamespaces() {
+	config := c.config.Get()
+	logger := c.loggers.Component(logging.ComponentNamespaceGC)
+	list, err := c.factory.CoreV1().Namespaces().List(metav1.ListOptions{
+		LabelSelector: k8s.NamespaceSelector(c.runNamespacePrefix).String(),
+	})
+	if err != nil {
+		logger.Warnw("Cannot list run namespaces", "error", err)
+		return
+	}
+	namespaceManager := c.newNamespaceManager(c.loggers)
+	now := time.Now()
+	orphaned := 0
+	for i := range list.Items {
+		namespace := &list.Items[i]
+		reason, err := c.getOrphanReason(namespace, config, now)
+		if err != nil {
+			logger.Warnw("Cannot check run namespace", "namespace", namespace.GetName(), "error", err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+		orphaned++
+		namespaceLogger := logger.With("namespace", namespace.GetName(), "reason", reason,
+			logging.KeyPipelineRun, namespace.GetAnnotations()[api.AnnotationPipelineRun])
+		if config.OrphanedNamespaceDryRun {
+			namespaceLogger.Info("Found orphaned run namespace (dry run)")
+			c.metrics.CountOrphanedNamespaceDeletion(orphanDryRun)
+			continue
+		}
+		if err := namespaceManager.Delete(context.Background(), namespace.GetName()); err != nil {
+			namespaceLogger.Warnw("Cannot delete orphaned run namespace", "error", err)
+			c.metrics.CountOrphanedNamespaceDeletion(orphanFailed)
+			continue
+		}
+		namespaceLogger.Info("Deleted orphaned run namespace")
+		c.metrics.CountOrphanedNamespaceDeletion(orphanDeleted)
+	}
+	c.metrics.SetOrphanedNamespaces(orphaned)
+	logger.Debugw("Checked run namespaces", "count", len(list.Items), "orphaned", orphaned)
+}
+
+// getOrphanReason returns why the given run namespace is orphaned, or an
+// empty string if it is not orphaned or this replica is not responsible
+// for it.
+// Namespaces younger than the grace period are never orphaned, as the
+// pipeline run may not reference them yet. Namespaces without the
+// pipeline run annotation are kept, as their owner is unknown.
+func (c *Controller) getOrphanReason(namespace *v1.Namespace, config *controllerconfig.Config, now time.Time) (string, error) {
+	if namespace.GetDeletionTimestamp() != nil || now.Sub(namespace.GetCreationTimestamp().Time) < config.OrphanedNamespaceGrace {
+		return "", nil
+	}
+	key := namespace.GetAnnotations()[api.AnnotationPipelineRun]
+	if key == "" || !c.shards.Owns(key) {
+		return "", nil
+	}
+	pipelineRun, err := c.getPipelineRun(key)
+	if err != nil {
+		return "", err
+	}
+	if pipelineRun == nil {
+		return "pipeline run does not exist", nil
+	}
+	// pipeline runs of other Steward installations are not touched
+	if !c.scope.Contains(pipelineRun) {
+		return "", nil
+	}
+	status := pipelineRun.Status
+	if status.Namespace != namespace.GetName() {
+		return "pipeline run uses another namespace", nil
+	}
+	if status.State == api.StateFinished && now.Sub(status.StateDetails.StartedAt.Time) >= config.OrphanedNamespaceGrace {
+		return "pipeline run is finished", nil
+	}
+	return "", nil
+}
+
+// getPipelineRun returns the pipeline run with the given key, or nil if
+// it does not exist. Pipeline runs missing in the informer cache are
+// fetched from Kubernetes, as the cache only contains pipeline runs in
+// the scope of the controller.
+func (c *Controller) getPipelineRun(key string) (*api.PipelineRun, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+	pipelineRun, err := c.pipelineRunLister.PipelineRuns(namespace).Get(name)
+	if err == nil {
+		return pipelineRun, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	pipelineRun, err = c.factory.StewardV1alpha1().PipelineRuns(namespace).Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return pipelineRun, err
+}
@@ -0,0 +1,89 @@
+package runctl
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"github.com/SAP/stewardci-core/pkg/logging"
+	"github.com/SAP/stewardci-core/pkg/metrics"
+	"github.com/SAP/stewardci-core/pkg/sharding"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRunNamespace(name string, age time.Duration, pipelineRunKey string) *v1.Namespace {
+	namespace := fake.Namespace(name)
+	namespace.SetLabels(map[string]string{"prefix": controllerconfig.DefaultRunNamespacePrefix})
+	namespace.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-age)))
+	if pipelineRunKey != "" {
+		namespace.SetAnnotations(map[string]string{api.AnnotationPipelineRun: pipelineRunKey})
+	}
+	return namespace
+}
+
+func newPipelineRunWithStatus(name string, status api.PipelineStatus) *api.PipelineRun {
+	pipelineRun := fake.PipelineRun(name, "tenant-ns-1", api.PipelineSpec{})
+	pipelineRun.Status = status
+	return pipelineRun
+}
+
+func Test_Controller_collectOrphanedNamespaces(t *testing.T) {
+	// SETUP
+	finishedAt := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	cf := fake.NewClientFactory(
+		newPipelineRunWithStatus("running", api.PipelineStatus{Namespace: "steward-run-running", State: api.StateRunning}),
+		newPipelineRunWithStatus("finished", api.PipelineStatus{Namespace: "steward-run-finished", State: api.StateFinished,
+			StateDetails: api.StateItem{State: api.StateFinished, StartedAt: finishedAt}}),
+		newPipelineRunWithStatus("restarted", api.PipelineStatus{Namespace: "steward-run-restarted-2", State: api.StateRunning}),
+		newRunNamespace("steward-run-running", 2*time.Hour, "tenant-ns-1/running"),
+		newRunNamespace("steward-run-finished", 3*time.Hour, "tenant-ns-1/finished"),
+		newRunNamespace("steward-run-restarted-1", 2*time.Hour, "tenant-ns-1/restarted"),
+		newRunNamespace("steward-run-restarted-2", 2*time.Hour, "tenant-ns-1/restarted"),
+		newRunNamespace("steward-run-deleted", 2*time.Hour, "tenant-ns-1/deleted"),
+		newRunNamespace("steward-run-young", time.Minute, "tenant-ns-1/deleted"),
+		newRunNamespace("steward-run-unknown", 2*time.Hour, ""),
+	)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	examinee.collectOrphanedNamespaces()
+
+	// VERIFY
+	for name, expectDeleted := range map[string]bool{
+		"steward-run-running":     false,
+		"steward-run-finished":    true,
+		"steward-run-restarted-1": true,
+		"steward-run-restarted-2": false,
+		"steward-run-deleted":     true,
+		"steward-run-young":       false,
+		"steward-run-unknown":     false,
+	} {
+		_, err := cf.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+		if expectDeleted {
+			assert.Assert(t, k8serrors.IsNotFound(err), "namespace %s not deleted", name)
+		} else {
+			assert.NilError(t, err, "namespace %s deleted", name)
+		}
+	}
+}
+
+func Test_Controller_collectOrphanedNamespaces_DryRun(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(newRunNamespace("steward-run-deleted", 2*time.Hour, "tenant-ns-1/deleted"))
+	config := newTestConfig()
+	config.OrphanedNamespaceDryRun = true
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(config), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	examinee.collectOrphanedNamespaces()
+
+	// VERIFY
+	_, err := cf.CoreV1().Namespaces().Get("steward-run-deleted", metav1.GetOptions{})
+	assert.NilError(t, err)
+}
@@ -0,0 +1,189 @@
+package runctl
+
+import (
+	"context"
+	"net"
+
+	"github.com/SAP/stewardci-core/pkg/tracing"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// networkPolicyDefaultDenyName is the name of the network policy
+	// denying all ingress and egress traffic in a run namespace.
+	networkPolicyDefaultDenyName = "steward-default-deny"
+
+	// networkPolicyAllowName is the name of the network policy allowing
+	// the configured traffic in a run namespace.
+	networkPolicyAllowName = "steward-allow"
+)
+
+// createNetworkPolicies isolates the run namespace from the rest of
+// the cluster. All traffic is denied except traffic between pods of the
+// run namespace and egress traffic allowed by the configuration.
+func (c *runManager) createNetworkPolicies(ctx context.Context, runNamespace string, config runConfig) (err error) {
+	_, span := tracing.Start(ctx, "create network policies")
+	defer func() { tracing.End(span, err) }()
+	allowPolicy, err := c.buildAllowNetworkPolicy(runNamespace, config)
+	if err != nil {
+		return err
+	}
+	policies := []*networkingv1.NetworkPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: networkPolicyDefaultDenyName, Namespace: runNamespace},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			},
+		},
+		allowPolicy,
+	}
+	client := c.factory.NetworkingV1().NetworkPolicies(runNamespace)
+	for _, policy := range policies {
+		if _, err := client.Create(policy); err != nil {
+			return errors.WithMessagef(err, "could not create network policy '%s' in namespace '%s'", policy.GetName(), runNamespace)
+		}
+	}
+	return nil
+}
+
+func (c *runManager) buildAllowNetworkPolicy(runNamespace string, config runConfig) (*networkingv1.NetworkPolicy, error) {
+	sameNamespace := []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: networkPolicyAllowName, Namespace: runNamespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: sameNamespace}},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{{To: sameNamespace}},
+		},
+	}
+
+	if config.IsNetworkEgressDNSAllowed() {
+		policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{
+				networkPolicyPort(v1.ProtocolUDP, intstr.FromInt(53)),
+				networkPolicyPort(v1.ProtocolTCP, intstr.FromInt(53)),
+			},
+		})
+	}
+
+	if cidrs := config.GetNetworkEgressCIDRs(); len(cidrs) > 0 {
+		rule := networkingv1.NetworkPolicyEgressRule{}
+		for _, cidr := range cidrs {
+			rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{
+				IPBlock: &networkingv1.IPBlock{
+					CIDR:   cidr,
+					Except: containedCIDRs(cidr, config.GetNetworkEgressExceptCIDRs()),
+				},
+			})
+		}
+		policy.Spec.Egress = append(policy.Spec.Egress, rule)
+	}
+
+	for _, ref := range config.GetNetworkEgressServices() {
+		rule, err := c.buildServiceEgressRule(ref)
+		if err != nil {
+			return nil, err
+		}
+		policy.Spec.Egress = append(policy.Spec.Egress, *rule)
+	}
+
+	return policy, nil
+}
+
+// buildServiceEgressRule returns an egress rule allowing connections to
+// the given service.
+// Services with a pod selector are allowed via namespace and pod selectors,
+// services without selector via the IP addresses of their endpoints.
+func (c *runManager) buildServiceEgressRule(ref serviceRef) (*networkingv1.NetworkPolicyEgressRule, error) {
+	service, err := c.factory.CoreV1().Services(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not get service '%s' in namespace '%s'", ref.Name, ref.Namespace)
+	}
+
+	rule := &networkingv1.NetworkPolicyEgressRule{}
+
+	if len(service.Spec.Selector) > 0 {
+		namespace, err := c.factory.CoreV1().Namespaces().Get(ref.Namespace, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not get namespace '%s'", ref.Namespace)
+		}
+		if len(namespace.GetLabels()) == 0 {
+			return nil, errors.Errorf("cannot allow egress to service '%s' in namespace '%s': namespace has no labels", ref.Name, ref.Namespace)
+		}
+		rule.To = []networkingv1.NetworkPolicyPeer{{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: namespace.GetLabels()},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: service.Spec.Selector},
+		}}
+		for _, port := range service.Spec.Ports {
+			target := port.TargetPort
+			if target.Type == intstr.Int && target.IntVal == 0 {
+				target = intstr.FromInt(int(port.Port))
+			}
+			rule.Ports = append(rule.Ports, networkPolicyPort(port.Protocol, target))
+		}
+		return rule, nil
+	}
+
+	endpoints, err := c.factory.CoreV1().Endpoints(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not get endpoints of service '%s' in namespace '%s'", ref.Name, ref.Namespace)
+	}
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{
+				IPBlock: &networkingv1.IPBlock{CIDR: hostCIDR(address.IP)},
+			})
+		}
+		for _, port := range subset.Ports {
+			rule.Ports = append(rule.Ports, networkPolicyPort(port.Protocol, intstr.FromInt(int(port.Port))))
+		}
+	}
+	if len(rule.To) == 0 {
+		return nil, errors.Errorf("cannot allow egress to service '%s' in namespace '%s': service has neither selector nor endpoint addresses", ref.Name, ref.Namespace)
+	}
+	return rule, nil
+}
+
+func networkPolicyPort(protocol v1.Protocol, port intstr.IntOrString) networkingv1.NetworkPolicyPort {
+	if protocol == "" {
+		protocol = v1.ProtocolTCP
+	}
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port}
+}
+
+// containedCIDRs returns those of the candidate CIDRs which are true
+// subsets of the given CIDR. Kubernetes rejects IP block exceptions
+// outside the IP block.
+func containedCIDRs(cidr string, candidates []string) []string {
+	_, outer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil
+	}
+	outerOnes, outerBits := outer.Mask.Size()
+	var result []string
+	for _, candidate := range candidates {
+		_, inner, err := net.ParseCIDR(candidate)
+		if err != nil {
+			continue
+		}
+		innerOnes, innerBits := inner.Mask.Size()
+		if innerBits == outerBits && innerOnes > outerOnes && outer.Contains(inner.IP) {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// hostCIDR returns a CIDR matching exactly the given IP address.
+func hostCIDR(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
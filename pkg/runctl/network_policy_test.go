@@ -0,0 +1,173 @@
+package runctl
+
+import (
+	"context"
+	"testing"
+
+	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"go.uber.org/zap"
+	assert "gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func Test_createNetworkPolicies_CreatesDenyAndAllowPolicy(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory()
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err := examinee.createNetworkPolicies(context.Background(), "run1", &runConfigImpl{})
+
+	// VERIFY
+	assert.NilError(t, err)
+	deny, err := cf.NetworkingV1().NetworkPolicies("run1").Get(networkPolicyDefaultDenyName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(deny.Spec.PolicyTypes))
+	assert.Equal(t, 0, len(deny.Spec.Ingress))
+	assert.Equal(t, 0, len(deny.Spec.Egress))
+	allow, err := cf.NetworkingV1().NetworkPolicies("run1").Get(networkPolicyAllowName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(allow.Spec.Ingress))
+	// same namespace and DNS
+	assert.Equal(t, 2, len(allow.Spec.Egress))
+	assert.Equal(t, 2, len(allow.Spec.Egress[1].Ports))
+	assert.Equal(t, 0, len(allow.Spec.Egress[1].To))
+}
+
+func Test_buildAllowNetworkPolicy_DNSNotAllowed(t *testing.T) {
+	// SETUP
+	examinee := &runManager{factory: fake.NewClientFactory(), config: newTestConfig(), logger: zap.NewNop().Sugar()}
+	dnsAllowed := false
+	config := &runConfigImpl{networkEgressDNS: &dnsAllowed}
+
+	// EXERCISE
+	policy, err := examinee.buildAllowNetworkPolicy("run1", config)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(policy.Spec.Egress))
+}
+
+func Test_buildAllowNetworkPolicy_CIDRs(t *testing.T) {
+	// SETUP
+	examinee := &runManager{factory: fake.NewClientFactory(), config: newTestConfig(), logger: zap.NewNop().Sugar()}
+	dnsAllowed := false
+	config := &runConfigImpl{
+		networkEgressDNS:         &dnsAllowed,
+		networkEgressCIDRs:       []string{"0.0.0.0/0", "192.168.1.0/24"},
+		networkEgressExceptCIDRs: []string{"10.0.0.0/8", "192.168.0.0/16"},
+	}
+
+	// EXERCISE
+	policy, err := examinee.buildAllowNetworkPolicy("run1", config)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(policy.Spec.Egress))
+	assert.DeepEqual(t, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: []string{"10.0.0.0/8", "192.168.0.0/16"}}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "192.168.1.0/24"}},
+	}, policy.Spec.Egress[1].To)
+}
+
+func Test_buildServiceEgressRule_ServiceWithSelector(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "logging",
+			Labels: map[string]string{"name": "logging"},
+		}},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch", Namespace: "logging"},
+			Spec: v1.ServiceSpec{
+				Selector: map[string]string{"app": "elasticsearch"},
+				Ports: []v1.ServicePort{
+					{Port: 9200, TargetPort: intstr.FromInt(8080)},
+					{Port: 9300, Protocol: v1.ProtocolUDP},
+				},
+			},
+		},
+	)
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	rule, err := examinee.buildServiceEgressRule(serviceRef{Namespace: "logging", Name: "elasticsearch"})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []networkingv1.NetworkPolicyPeer{{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "logging"}},
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "elasticsearch"}},
+	}}, rule.To)
+	assert.DeepEqual(t, []networkingv1.NetworkPolicyPort{
+		networkPolicyPort(v1.ProtocolTCP, intstr.FromInt(8080)),
+		networkPolicyPort(v1.ProtocolUDP, intstr.FromInt(9300)),
+	}, rule.Ports)
+}
+
+func Test_buildServiceEgressRule_NamespaceWithoutLabels(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("logging"),
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "elasticsearch", Namespace: "logging"},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "elasticsearch"}},
+		},
+	)
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	_, err := examinee.buildServiceEgressRule(serviceRef{Namespace: "logging", Name: "elasticsearch"})
+
+	// VERIFY
+	assert.Error(t, err, "cannot allow egress to service 'elasticsearch' in namespace 'logging': namespace has no labels")
+}
+
+func Test_buildServiceEgressRule_ServiceWithoutSelector(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "ns1"},
+		},
+		&v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "ns1"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{{IP: "1.2.3.4"}, {IP: "fd00::1"}},
+				Ports:     []v1.EndpointPort{{Port: 443}},
+			}},
+		},
+	)
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	rule, err := examinee.buildServiceEgressRule(serviceRef{Namespace: "ns1", Name: "external"})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "1.2.3.4/32"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "fd00::1/128"}},
+	}, rule.To)
+	assert.DeepEqual(t, []networkingv1.NetworkPolicyPort{
+		networkPolicyPort(v1.ProtocolTCP, intstr.FromInt(443)),
+	}, rule.Ports)
+}
+
+func Test_containedCIDRs(t *testing.T) {
+	for _, tc := range []struct {
+		cidr       string
+		candidates []string
+		expected   []string
+	}{
+		{"10.0.0.0/8", []string{"10.1.0.0/16", "11.0.0.0/16", "10.0.0.0/8"}, []string{"10.1.0.0/16"}},
+		{"10.0.0.0/8", []string{"fd00::/64"}, nil},
+		{"0.0.0.0/0", nil, nil},
+	} {
+		t.Run(tc.cidr, func(t *testing.T) {
+			assert.DeepEqual(t, tc.expected, containedCIDRs(tc.cidr, tc.candidates))
+		})
+	}
+}
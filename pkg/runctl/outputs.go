@@ -0,0 +1,65 @@
+package runctl
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	errors "github.com/pkg/errors"
+)
+
+const (
+	// maxOutputs is the maximum number of outputs a pipeline may report.
+	maxOutputs = 32
+
+	// maxOutputKeyLength is the maximum length of an output key.
+	maxOutputKeyLength = 63
+
+	// maxOutputValueLength is the maximum length of an output value in bytes.
+	maxOutputValueLength = 1024
+)
+
+var outputKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// terminationMessage is the structured form of the termination message of
+// the Jenkinsfile Runner container. A termination message which is not a
+// JSON object is taken as plain text message.
+type terminationMessage struct {
+	Message string            `json:"message"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+// parseTerminationMessage returns the message and the validated outputs
+// contained in the termination message of the Jenkinsfile Runner container.
+// If the outputs are invalid, the message is returned together with an
+// error.
+func parseTerminationMessage(text string) (string, map[string]string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(text), "{") {
+		return text, nil, nil
+	}
+	var parsed terminationMessage
+	if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+		return text, nil, nil
+	}
+	if err := validateOutputs(parsed.Outputs); err != nil {
+		return parsed.Message, nil, err
+	}
+	return parsed.Message, parsed.Outputs, nil
+}
+
+func validateOutputs(outputs map[string]string) error {
+	if len(outputs) > maxOutputs {
+		return errors.Errorf("invalid pipeline outputs: %d outputs exceed the limit of %d", len(outputs), maxOutputs)
+	}
+	for key, value := range outputs {
+		if len(key) > maxOutputKeyLength || !outputKeyRegexp.MatchString(key) {
+			return errors.Errorf(
+				"invalid pipeline outputs: key '%s' must consist of at most %d alphanumeric characters, '-', '_' or '.', and start and end with an alphanumeric character",
+				key, maxOutputKeyLength)
+		}
+		if len(value) > maxOutputValueLength {
+			return errors.Errorf("invalid pipeline outputs: value of key '%s' exceeds the limit of %d bytes", key, maxOutputValueLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package runctl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func Test_parseTerminationMessage_PlainText(t *testing.T) {
+	for _, text := range []string{"", "ok", "{not json"} {
+		t.Run(text, func(t *testing.T) {
+			// EXERCISE
+			message, outputs, err := parseTerminationMessage(text)
+
+			// VERIFY
+			assert.NilError(t, err)
+			assert.Equal(t, text, message)
+			assert.Assert(t, outputs == nil)
+		})
+	}
+}
+
+func Test_parseTerminationMessage_Structured(t *testing.T) {
+	// SETUP
+	text := `{"message": "ok", "outputs": {"version": "1.2.3", "image.digest": "sha256:abc"}}`
+
+	// EXERCISE
+	message, outputs, err := parseTerminationMessage(text)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "ok", message)
+	assert.DeepEqual(t, map[string]string{"version": "1.2.3", "image.digest": "sha256:abc"}, outputs)
+}
+
+func Test_parseTerminationMessage_InvalidOutputs(t *testing.T) {
+	tooMany := []string{}
+	for i := 0; i <= maxOutputs; i++ {
+		tooMany = append(tooMany, fmt.Sprintf(`"key%d": "value"`, i))
+	}
+	for _, tc := range []struct {
+		name          string
+		outputs       string
+		expectedError string
+	}{
+		{"InvalidKey", `{"-key": "value"}`, `invalid pipeline outputs: key '-key' must consist of .*`},
+		{"KeyTooLong", fmt.Sprintf(`{"%s": "value"}`, strings.Repeat("k", maxOutputKeyLength+1)), `invalid pipeline outputs: key 'k+' must consist of at most 63 .*`},
+		{"ValueTooLong", fmt.Sprintf(`{"key": "%s"}`, strings.Repeat("v", maxOutputValueLength+1)), `invalid pipeline outputs: value of key 'key' exceeds the limit of 1024 bytes`},
+		{"TooMany", "{" + strings.Join(tooMany, ",") + "}", `invalid pipeline outputs: 33 outputs exceed the limit of 32`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			text := fmt.Sprintf(`{"message": "ok", "outputs": %s}`, tc.outputs)
+
+			// EXERCISE
+			message, outputs, err := parseTerminationMessage(text)
+
+			// VERIFY
+			assert.Equal(t, "ok", message)
+			assert.Assert(t, outputs == nil)
+			assert.Assert(t, err != nil)
+			assert.Assert(t, is.Regexp(tc.expectedError, err.Error()))
+		})
+	}
+}
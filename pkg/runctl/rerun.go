@@ -0,0 +1,71 @@
+package runctl
+
+import (
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/pkg/errors"
+)
+
+// resolveRerun completes the spec of a pipeline run re-running another
+// pipeline run (spec.rerunOf) and records the lineage in the status.
+// Does nothing if the pipeline run is no re-run or has been resolved
+// already.
+// Returns true together with the error if the re-run is invalid, e.g.
+// because the original pipeline run does not exist.
+func (c *Controller) resolveRerun(pipelineRun k8s.PipelineRun) (bool, error) {
+	spec := pipelineRun.GetSpec()
+	if spec.RerunOf == "" || pipelineRun.GetStatus().RerunOf != "" {
+		return false, nil
+	}
+	original, err := c.pipelineRunFetcher.ByName(pipelineRun.GetNamespace(), spec.RerunOf)
+	if err != nil {
+		return false, err
+	}
+	if original == nil {
+		return true, errors.Errorf("pipeline run '%s' referenced in spec.rerunOf does not exist", spec.RerunOf)
+	}
+	if err = pipelineRun.UpdateSpec(rerunSpec(original.GetSpec(), spec)); err != nil {
+		return false, err
+	}
+	attempt := original.GetStatus().Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+	pipelineRun.UpdateRerunOf(spec.RerunOf, attempt+1)
+	return false, nil
+}
+
+// rerunSpec returns the spec of a re-run. Fields not specified for the
+// re-run are taken from the original pipeline run. Args are merged, with
+// args of the re-run overriding those of the original pipeline run.
+func rerunSpec(original *api.PipelineSpec, rerun *api.PipelineSpec) *api.PipelineSpec {
+	result := rerun.DeepCopy()
+	if result.JenkinsFile == (api.JenkinsFile{}) {
+		result.JenkinsFile = original.JenkinsFile
+	}
+	if original.Args != nil || rerun.Args != nil {
+		result.Args = map[string]string{}
+		for key, value := range original.Args {
+			result.Args[key] = value
+		}
+		for key, value := range rerun.Args {
+			result.Args[key] = value
+		}
+	}
+	if result.Secrets == nil && original.Secrets != nil {
+		result.Secrets = append([]string{}, original.Secrets...)
+	}
+	if result.Caches == nil && original.Caches != nil {
+		result.Caches = append([]api.Cache{}, original.Caches...)
+	}
+	if result.Timeout == nil {
+		result.Timeout = original.Timeout.DeepCopy()
+	}
+	if result.Logging == nil {
+		result.Logging = original.Logging.DeepCopy()
+	}
+	if result.Runtime == nil {
+		result.Runtime = original.Runtime.DeepCopy()
+	}
+	return result
+}
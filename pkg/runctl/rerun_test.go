@@ -0,0 +1,148 @@
+package runctl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
+	k8s "github.com/SAP/stewardci-core/pkg/k8s"
+	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"github.com/SAP/stewardci-core/pkg/logging"
+	metrics "github.com/SAP/stewardci-core/pkg/metrics"
+	"github.com/SAP/stewardci-core/pkg/sharding"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_rerunSpec_TakesOverUnspecifiedFields(t *testing.T) {
+	// SETUP
+	original := &api.PipelineSpec{
+		JenkinsFile: api.JenkinsFile{URL: "url1", Revision: "master", Path: "Jenkinsfile"},
+		Args:        map[string]string{"arg1": "value1", "arg2": "value2"},
+		Secrets:     []string{"secret1"},
+		Caches:      []api.Cache{{Name: "maven", Mode: api.CacheModeReadOnly}},
+		Runtime:     &api.Runtime{Image: "image1"},
+	}
+	rerun := &api.PipelineSpec{
+		Args:    map[string]string{"arg2": "override2", "arg3": "value3"},
+		RerunOf: "run1",
+	}
+
+	// EXERCISE
+	result := rerunSpec(original, rerun)
+
+	// VERIFY
+	assert.DeepEqual(t, &api.PipelineSpec{
+		JenkinsFile: api.JenkinsFile{URL: "url1", Revision: "master", Path: "Jenkinsfile"},
+		Args:        map[string]string{"arg1": "value1", "arg2": "override2", "arg3": "value3"},
+		Secrets:     []string{"secret1"},
+		Caches:      []api.Cache{{Name: "maven", Mode: api.CacheModeReadOnly}},
+		Runtime:     &api.Runtime{Image: "image1"},
+		RerunOf:     "run1",
+	}, result)
+}
+
+func Test_rerunSpec_KeepsSpecifiedFields(t *testing.T) {
+	// SETUP
+	original := &api.PipelineSpec{
+		JenkinsFile: api.JenkinsFile{URL: "url1"},
+		Secrets:     []string{"secret1"},
+		Caches:      []api.Cache{{Name: "maven"}},
+	}
+	rerun := &api.PipelineSpec{
+		JenkinsFile: api.JenkinsFile{URL: "url2"},
+		Secrets:     []string{},
+		Caches:      []api.Cache{},
+		RerunOf:     "run1",
+	}
+
+	// EXERCISE
+	result := rerunSpec(original, rerun)
+
+	// VERIFY
+	assert.Equal(t, "url2", result.JenkinsFile.URL)
+	assert.Equal(t, 0, len(result.Secrets))
+	assert.Equal(t, 0, len(result.Caches))
+	assert.Assert(t, result.Args == nil)
+}
+
+func Test_rerunSpec_Timeout(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		originalTimeout *metav1.Duration
+		rerunTimeout    *metav1.Duration
+		expected        *metav1.Duration
+	}{
+		{"TakenOver", duration(20 * time.Minute), nil, duration(20 * time.Minute)},
+		{"Specified", duration(20 * time.Minute), duration(30 * time.Minute), duration(30 * time.Minute)},
+		{"NotSpecified", nil, nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			original := &api.PipelineSpec{Timeout: tc.originalTimeout}
+			rerun := &api.PipelineSpec{Timeout: tc.rerunTimeout, RerunOf: "run1"}
+
+			// EXERCISE
+			result := rerunSpec(original, rerun)
+
+			// VERIFY
+			assert.DeepEqual(t, tc.expected, result.Timeout)
+			if result.Timeout != nil {
+				assert.Assert(t, result.Timeout != tc.originalTimeout)
+			}
+		})
+	}
+}
+
+func Test_Controller_syncHandler_Rerun(t *testing.T) {
+	// SETUP
+	original := fake.PipelineRun("run1", "tenant-ns-1", api.PipelineSpec{
+		JenkinsFile: api.JenkinsFile{URL: "url1"},
+		Args:        map[string]string{"arg1": "value1"},
+	})
+	original.Status.RerunOf = "run0"
+	original.Status.Attempt = 2
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		original,
+		fake.PipelineRun("run2", "tenant-ns-1", api.PipelineSpec{
+			RerunOf: "run1",
+			Args:    map[string]string{"arg2": "value2"},
+		}),
+		fake.ClusterRole(string(runClusterRoleName)),
+	)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("tenant-ns-1/run2")
+
+	// VERIFY
+	assert.NilError(t, err)
+	run, err := getRun("run2", "tenant-ns-1", cf)
+	assert.NilError(t, err)
+	assert.Equal(t, "url1", run.Spec.JenkinsFile.URL)
+	assert.DeepEqual(t, map[string]string{"arg1": "value1", "arg2": "value2"}, run.Spec.Args)
+	assert.Equal(t, "run1", run.Status.RerunOf)
+	assert.Equal(t, int32(3), run.Status.Attempt)
+}
+
+func Test_Controller_syncHandler_Rerun_OriginalNotExisting(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		fake.Namespace("tenant-ns-1"),
+		fake.PipelineRun("run2", "tenant-ns-1", api.PipelineSpec{RerunOf: "run1"}),
+	)
+	examinee := NewController(cf, k8s.NewPipelineRunFetcher(cf), metrics.NewMetrics(), logging.NewNop(), controllerconfig.NewStaticStore(newTestConfig()), k8s.NewClusterScope(), sharding.NewSingleShard())
+
+	// EXERCISE
+	err := examinee.syncHandler("tenant-ns-1/run2")
+
+	// VERIFY
+	assert.NilError(t, err)
+	status := getPipelineRun("run2", "tenant-ns-1", cf).GetStatus()
+	assert.Equal(t, api.StateFinished, status.State)
+	assert.Equal(t, api.ResultErrorContent, status.Result)
+	assert.Assert(t, strings.Contains(status.Message, "pipeline run 'run1' referenced in spec.rerunOf does not exist"), status.Message)
+}
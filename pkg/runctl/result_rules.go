@@ -0,0 +1,228 @@
+package runctl
+
+import (
+	"encoding/json"
+	"regexp"
+
+	steward "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	errors "github.com/pkg/errors"
+	tektonStatus "github.com/tektoncd/pipeline/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// resultRule classifies a pipeline run which did not succeed.
+// All criteria that are set must match for the rule to apply.
+type resultRule struct {
+	// ConditionStatus is the status of the Tekton TaskRun condition
+	// 'Succeeded' the rule applies to. Defaults to 'False', i.e. the
+	// rule applies to failed TaskRuns. Built-in rules with 'Unknown' apply
+	// to TaskRuns which did not finish yet and let the pipeline run finish
+	// immediately.
+	ConditionStatus corev1.ConditionStatus `json:"conditionStatus,omitempty"`
+	// ConditionReason is the reason of the Tekton TaskRun condition
+	// 'Succeeded', e.g. 'TaskRunTimeout'.
+	ConditionReason string `json:"conditionReason,omitempty"`
+	// PodReason is the reason of the pod status, e.g. 'Evicted', or the
+	// reason why the pod is not scheduled, e.g. 'Unschedulable'.
+	PodReason string `json:"podReason,omitempty"`
+	// StepReason is the reason of the waiting or terminated state of the
+	// Jenkinsfile Runner step, e.g. 'OOMKilled'.
+	StepReason string `json:"stepReason,omitempty"`
+	// StepExitCode is the exit code of the Jenkinsfile Runner step.
+	StepExitCode *int32 `json:"stepExitCode,omitempty"`
+	// StepFailed requires the Jenkinsfile Runner step to have terminated
+	// with a non-zero exit code.
+	StepFailed bool `json:"stepFailed,omitempty"`
+	// MessagePattern is a regular expression which must match the
+	// termination message of the Jenkinsfile Runner step, the message of
+	// the Tekton TaskRun condition 'Succeeded' or the pod status message.
+	MessagePattern string `json:"messagePattern,omitempty"`
+
+	Result       steward.Result       `json:"result"`
+	ResultReason steward.ResultReason `json:"resultReason,omitempty"`
+
+	messageRegexp *regexp.Regexp
+}
+
+// defaultResultRules are the built-in rules to classify the result of
+// pipeline runs. They are evaluated in order after the configured rules.
+var defaultResultRules = mustCompileResultRules([]resultRule{
+	// Tekton retries to create the pod while the resource quota is
+	// exceeded. As a run namespace contains nothing but the run
+	// itself, the quota will not become available again.
+	{
+		ConditionStatus: corev1.ConditionUnknown,
+		ConditionReason: tektonStatus.ReasonExceededResourceQuota,
+		Result:          steward.ResultErrorQuota,
+		ResultReason:    steward.ResultReasonQuotaExceeded,
+	},
+	{
+		ConditionStatus: corev1.ConditionUnknown,
+		StepReason:      "InvalidImageName",
+		Result:          steward.ResultErrorContent,
+		ResultReason:    steward.ResultReasonImagePullFailed,
+	},
+	{
+		PodReason:    "Evicted",
+		Result:       steward.ResultErrorInfra,
+		ResultReason: steward.ResultReasonPodEvicted,
+	},
+	{
+		StepReason:   "OOMKilled",
+		Result:       steward.ResultErrorContent,
+		ResultReason: steward.ResultReasonOOMKilled,
+	},
+	{
+		StepReason:   "ErrImagePull",
+		Result:       steward.ResultErrorInfra,
+		ResultReason: steward.ResultReasonImagePullFailed,
+	},
+	{
+		StepReason:   "ImagePullBackOff",
+		Result:       steward.ResultErrorInfra,
+		ResultReason: steward.ResultReasonImagePullFailed,
+	},
+	{
+		PodReason:    corev1.PodReasonUnschedulable,
+		Result:       steward.ResultErrorInfra,
+		ResultReason: steward.ResultReasonUnschedulable,
+	},
+	{
+		ConditionReason: tektonStatus.ReasonTimedOut,
+		Result:          steward.ResultTimeout,
+		ResultReason:    steward.ResultReasonTimeout,
+	},
+	{
+		ConditionReason: tektonStatus.ReasonFailed,
+		StepFailed:      true,
+		MessagePattern:  `(?i)git clone|could not read from remote repository|repository .* not found`,
+		Result:          steward.ResultErrorContent,
+		ResultReason:    steward.ResultReasonGitCloneFailed,
+	},
+	{
+		ConditionReason: tektonStatus.ReasonFailed,
+		StepFailed:      true,
+		Result:          steward.ResultErrorContent,
+		ResultReason:    steward.ResultReasonPipelineScriptError,
+	},
+})
+
+func mustCompileResultRules(rules []resultRule) []resultRule {
+	result, err := compileResultRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// parseResultRules parses a JSON list of configured result rules.
+// Configured rules are only applied to finished TaskRuns and therefore
+// must not match the condition status 'Unknown'.
+func parseResultRules(value string) ([]resultRule, error) {
+	var rules []resultRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if rule.ConditionStatus == corev1.ConditionUnknown {
+			return nil, errors.Errorf("rule %d: invalid condition status '%s'", i, rule.ConditionStatus)
+		}
+	}
+	return compileResultRules(rules)
+}
+
+func compileResultRules(rules []resultRule) ([]resultRule, error) {
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.ConditionStatus {
+		case "", corev1.ConditionFalse, corev1.ConditionUnknown:
+		default:
+			return nil, errors.Errorf("rule %d: invalid condition status '%s'", i, rule.ConditionStatus)
+		}
+		if rule.Result == steward.ResultUndefined || rule.Result == steward.ResultSuccess {
+			return nil, errors.Errorf("rule %d: invalid result '%s'", i, rule.Result)
+		}
+		if rule.MessagePattern != "" {
+			re, err := regexp.Compile(rule.MessagePattern)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "rule %d: invalid message pattern", i)
+			}
+			rule.messageRegexp = re
+		}
+	}
+	return rules, nil
+}
+
+// runFacts are the observations on a run the result rules are matched
+// against.
+type runFacts struct {
+	conditionStatus  corev1.ConditionStatus
+	conditionReason  string
+	conditionMessage string
+	podReason        string
+	podMessage       string
+	step             *corev1.ContainerState
+}
+
+func (f *runFacts) stepReason() string {
+	if f.step == nil {
+		return ""
+	}
+	if f.step.Waiting != nil {
+		return f.step.Waiting.Reason
+	}
+	if f.step.Terminated != nil {
+		return f.step.Terminated.Reason
+	}
+	return ""
+}
+
+func (f *runFacts) stepExitCode() *int32 {
+	if f.step == nil || f.step.Terminated == nil {
+		return nil
+	}
+	return &f.step.Terminated.ExitCode
+}
+
+func (f *runFacts) messages() []string {
+	result := []string{f.conditionMessage, f.podMessage}
+	if f.step != nil && f.step.Terminated != nil {
+		result = append(result, f.step.Terminated.Message)
+	}
+	return result
+}
+
+func (r *resultRule) matches(facts *runFacts) bool {
+	conditionStatus := r.ConditionStatus
+	if conditionStatus == "" {
+		conditionStatus = corev1.ConditionFalse
+	}
+	if conditionStatus != facts.conditionStatus {
+		return false
+	}
+	if r.ConditionReason != "" && r.ConditionReason != facts.conditionReason {
+		return false
+	}
+	if r.PodReason != "" && r.PodReason != facts.podReason {
+		return false
+	}
+	if r.StepReason != "" && r.StepReason != facts.stepReason() {
+		return false
+	}
+	exitCode := facts.stepExitCode()
+	if r.StepExitCode != nil && (exitCode == nil || *exitCode != *r.StepExitCode) {
+		return false
+	}
+	if r.StepFailed && (exitCode == nil || *exitCode == 0) {
+		return false
+	}
+	if r.messageRegexp != nil {
+		for _, message := range facts.messages() {
+			if message != "" && r.messageRegexp.MatchString(message) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
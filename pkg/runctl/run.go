@@ -3,7 +3,6 @@ package runctl
 import (
 	steward "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
-	tektonStatus "github.com/tektoncd/pipeline/pkg/status"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	knativeapis "knative.dev/pkg/apis"
@@ -13,17 +12,27 @@ import (
 type Run interface {
 	GetStartTime() *metav1.Time
 	IsFinished() (bool, steward.Result)
+	GetResultReason() steward.ResultReason
 	GetSucceededCondition() *knativeapis.Condition
 	GetContainerInfo() *corev1.ContainerState
+	GetSteps() []steward.StepStatus
 }
 
 type run struct {
 	tektonTaskRun *tekton.TaskRun
+	pod           *corev1.Pod
+	resultRules   []resultRule
 }
 
 // NewRun returns new Run
 func NewRun(tektonTaskRun *tekton.TaskRun) Run {
-	return &run{tektonTaskRun: tektonTaskRun}
+	return newRun(tektonTaskRun, nil, defaultResultRules)
+}
+
+// newRun returns a new Run classified by the given result rules.
+// The pod executing the TaskRun is optional.
+func newRun(tektonTaskRun *tekton.TaskRun, pod *corev1.Pod, resultRules []resultRule) Run {
+	return &run{tektonTaskRun: tektonTaskRun, pod: pod, resultRules: resultRules}
 }
 
 // GetStartTime returns start time of run if already started
@@ -41,32 +50,91 @@ func (r *run) GetContainerInfo() *corev1.ContainerState {
 	return &stepState.ContainerState
 }
 
+// GetSteps returns the status of all steps of the Tekton TaskRun
+func (r *run) GetSteps() []steward.StepStatus {
+	result := []steward.StepStatus{}
+	for _, stepState := range r.tektonTaskRun.Status.Steps {
+		step := steward.StepStatus{Name: stepState.Name}
+		switch {
+		case stepState.Terminated != nil:
+			terminated := stepState.Terminated
+			exitCode := terminated.ExitCode
+			step.State = steward.StepStateTerminated
+			step.Reason = terminated.Reason
+			step.ExitCode = &exitCode
+			step.StartedAt = terminated.StartedAt.DeepCopy()
+			step.FinishedAt = terminated.FinishedAt.DeepCopy()
+		case stepState.Running != nil:
+			step.State = steward.StepStateRunning
+			step.StartedAt = stepState.Running.StartedAt.DeepCopy()
+		case stepState.Waiting != nil:
+			step.State = steward.StepStateWaiting
+			step.Reason = stepState.Waiting.Reason
+		}
+		result = append(result, step)
+	}
+	return result
+}
+
 func (r *run) GetSucceededCondition() *knativeapis.Condition {
 	return r.tektonTaskRun.Status.GetCondition(knativeapis.ConditionSucceeded)
 }
 
 // IsFinished returns true if run is finished
 func (r *run) IsFinished() (bool, steward.Result) {
+	finished, result, _ := r.classify()
+	return finished, result
+}
+
+// GetResultReason returns the reason for the result of a finished run
+func (r *run) GetResultReason() steward.ResultReason {
+	_, _, reason := r.classify()
+	return reason
+}
+
+// classify applies the first matching result rule. A run which did not
+// succeed and is not matched by any rule is considered an infrastructure
+// error.
+func (r *run) classify() (bool, steward.Result, steward.ResultReason) {
 	condition := r.GetSucceededCondition()
-	if condition.IsUnknown() {
-		return false, steward.ResultUndefined
-	}
 	if condition.IsTrue() {
-		return true, steward.ResultSuccess
+		return true, steward.ResultSuccess, steward.ResultReasonUndefined
 	}
-	// TaskRun finished unsuccessfully, check reason...
-	switch condition.Reason {
-	case tektonStatus.ReasonTimedOut:
-		return true, steward.ResultTimeout
-	case tektonStatus.ReasonFailed:
-		jfrStepState := r.getJenkinsfileRunnerStepState()
-		if jfrStepState != nil && jfrStepState.Terminated != nil && jfrStepState.Terminated.ExitCode != 0 {
-			return true, steward.ResultErrorContent
+	facts := r.getFacts(condition)
+	for _, rule := range r.resultRules {
+		if rule.matches(facts) {
+			return true, rule.Result, rule.ResultReason
 		}
-	default:
-		// TODO handle other failure reasons like quota exceedance
 	}
-	return true, steward.ResultErrorInfra
+	if facts.conditionStatus == corev1.ConditionUnknown {
+		return false, steward.ResultUndefined, steward.ResultReasonUndefined
+	}
+	return true, steward.ResultErrorInfra, steward.ResultReasonUndefined
+}
+
+func (r *run) getFacts(condition *knativeapis.Condition) *runFacts {
+	facts := &runFacts{conditionStatus: corev1.ConditionUnknown}
+	if condition != nil {
+		facts.conditionStatus = condition.Status
+		facts.conditionReason = condition.Reason
+		facts.conditionMessage = condition.Message
+	}
+	if r.pod != nil {
+		facts.podReason = r.pod.Status.Reason
+		facts.podMessage = r.pod.Status.Message
+		if facts.podReason == "" {
+			for _, podCondition := range r.pod.Status.Conditions {
+				if podCondition.Type == corev1.PodScheduled && podCondition.Status == corev1.ConditionFalse {
+					facts.podReason = podCondition.Reason
+					facts.podMessage = podCondition.Message
+				}
+			}
+		}
+	}
+	if stepState := r.getJenkinsfileRunnerStepState(); stepState != nil {
+		facts.step = &stepState.ContainerState
+	}
+	return facts
 }
 
 func (r *run) getJenkinsfileRunnerStepState() *tekton.StepState {
@@ -1,31 +1,29 @@
 package runctl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	"github.com/SAP/stewardci-core/pkg/k8s"
+	"github.com/SAP/stewardci-core/pkg/logging"
+	"github.com/SAP/stewardci-core/pkg/tracing"
 	"github.com/pkg/errors"
 	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	knativeapis "knative.dev/pkg/apis"
 )
 
 const (
-	runNamespacePrefix       = "steward-run"
-	runNamespaceRandomLength = 16
-	serviceAccountName       = "run-bot"
-
 	annotationPipelineRunKey = "steward.sap.com/pipeline-run-key"
 
-	// tektonClusterTaskName is the name of the Tekton ClusterTask
-	// that should be used to execute the Jenkinsfile Runner
-	tektonClusterTaskName = "steward-jenkinsfile-runner"
-
 	// tektonClusterTaskJenkinsfileRunnerStep is the name of the step
 	// in the Tekton TaskRun that executes the Jenkinsfile Runner
 	tektonClusterTaskJenkinsfileRunnerStep = "jenkinsfile-runner"
@@ -37,36 +35,69 @@ const (
 
 // RunManager manages runs
 type RunManager interface {
-	Start(pipelineRun k8s.PipelineRun) error
+	Start(ctx context.Context, pipelineRun k8s.PipelineRun) error
 	GetRun(pipelineRun k8s.PipelineRun) (Run, error)
-	Cleanup(pipelineRun k8s.PipelineRun) error
+	Cancel(pipelineRun k8s.PipelineRun) error
+	IsTerminated(pipelineRun k8s.PipelineRun) (bool, error)
+	GetTestSummary(pipelineRun k8s.PipelineRun) (*v1alpha1.TestSummary, error)
+	Cleanup(ctx context.Context, pipelineRun k8s.PipelineRun) error
 }
 
 type runManager struct {
 	secretProvider   k8s.SecretProvider
 	factory          k8s.ClientFactory
 	namespaceManager k8s.NamespaceManager
+	config           *controllerconfig.Config
+	logger           *zap.SugaredLogger
 }
 
 // NewRunManager creates a new RunManager.
-func NewRunManager(factory k8s.ClientFactory, secretProvider k8s.SecretProvider, namespaceManager k8s.NamespaceManager) RunManager {
+func NewRunManager(factory k8s.ClientFactory, secretProvider k8s.SecretProvider, namespaceManager k8s.NamespaceManager, config *controllerconfig.Config, logger *zap.SugaredLogger) RunManager {
 	return &runManager{
 		secretProvider:   secretProvider,
 		factory:          factory,
 		namespaceManager: namespaceManager,
+		config:           config,
+		logger:           logger,
 	}
 }
 
 // Start prepares the isolated environment for a new run and starts
 // the run in this environment.
-func (c *runManager) Start(pipelineRun k8s.PipelineRun) error {
-	var err error
+func (c *runManager) Start(ctx context.Context, pipelineRun k8s.PipelineRun) (err error) {
+	ctx, span := tracing.Start(ctx, "start run")
+	defer func() { tracing.End(span, err) }()
 
-	err = c.prepareRunNamespace(pipelineRun)
+	config, err := getRunConfig(c.factory, pipelineRun.GetNamespace())
+	if err != nil {
+		return errors.Wrap(err, "Failed to load configuration.")
+	}
+	runtime, err := getRuntime(pipelineRun.GetSpec(), config, c.config.TektonClusterTaskName)
+	if err != nil {
+		pipelineRun.UpdateResult(v1alpha1.ResultErrorContent)
+		return err
+	}
+	err = validateCaches(pipelineRun.GetSpec(), config)
 	if err != nil {
+		pipelineRun.UpdateResult(v1alpha1.ResultErrorContent)
 		return err
 	}
-	err = c.createTektonTaskRun(pipelineRun)
+	timeout, err := getTimeout(pipelineRun.GetSpec(), config, c.config.BuildTimeout)
+	if err != nil {
+		pipelineRun.UpdateResult(v1alpha1.ResultErrorContent)
+		return err
+	}
+
+	err = c.prepareRunNamespace(ctx, pipelineRun, config)
+	if err != nil {
+		return err
+	}
+	caches, err := c.provideCaches(ctx, pipelineRun, config)
+	if err != nil {
+		return errors.Wrap(err, "Failed to provide caches.")
+	}
+	loggingConfig := getLogging(pipelineRun.GetSpec(), config)
+	err = c.createTektonTaskRun(ctx, pipelineRun, config.GetClientNamespace(), runtime, timeout, loggingConfig, caches)
 	if err != nil {
 		return err
 	}
@@ -76,22 +107,29 @@ func (c *runManager) Start(pipelineRun k8s.PipelineRun) error {
 
 // prepareRunNamespace creates a new namespace for the pipeline run
 // and populates it with needed resource.
-func (c *runManager) prepareRunNamespace(pipelineRun k8s.PipelineRun) error {
-	var err error
+func (c *runManager) prepareRunNamespace(ctx context.Context, pipelineRun k8s.PipelineRun, config runConfig) (err error) {
+	ctx, span := tracing.Start(ctx, "prepare run namespace")
+	defer func() { tracing.End(span, err) }()
 
 	//Create Run Namespace
-	runNamespace, err := c.namespaceManager.Create("", nil)
+	// the annotation allows to identify orphaned run namespaces
+	runNamespace, err := c.namespaceManager.Create(ctx, "", map[string]string{
+		v1alpha1.AnnotationPipelineRun: pipelineRun.GetKey(),
+	})
 	if err != nil {
 		return errors.Wrap(err, "Failed to create run namespace.")
 	}
+	span.SetAttributes(tracing.KeyRunNamespace.String(runNamespace))
 
 	//Assign namespace to Run
 	pipelineRun.UpdateRunNamespace(runNamespace)
+	c.logger = c.logger.With(logging.KeyRunNamespace, runNamespace)
+	c.logger.Info("Run namespace created")
 
 	// If something goes wrong while creating objects inside the namespaces, we delete everything.
 	cleanupOnError := func() {
 		if err != nil {
-			c.Cleanup(pipelineRun)
+			c.Cleanup(ctx, pipelineRun)
 		}
 	}
 	defer cleanupOnError()
@@ -104,7 +142,7 @@ func (c *runManager) prepareRunNamespace(pipelineRun k8s.PipelineRun) error {
 	if pullSecretName != "" {
 		secretNames = append(secretNames, pullSecretName)
 	}
-	err = c.copySecrets(runNamespace, secretNames, pipelineRun)
+	err = c.copySecrets(ctx, runNamespace, secretNames, pipelineRun)
 	if err != nil {
 		return errors.Wrap(err, "Failed to copy secrets.")
 	}
@@ -112,35 +150,77 @@ func (c *runManager) prepareRunNamespace(pipelineRun k8s.PipelineRun) error {
 	//Create Service Account in Run Namespace
 	accountManager := k8s.NewServiceAccountManager(c.factory, runNamespace)
 
-	serviceAccount, err := accountManager.CreateServiceAccount(serviceAccountName, scmCloneSecretName, pullSecretName)
+	serviceAccount, err := accountManager.CreateServiceAccount(ctx, c.config.RunServiceAccountName, scmCloneSecretName, pullSecretName)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create service account.")
 	}
 
 	//Add Role Binding to Service Account
-	_, err = serviceAccount.AddRoleBinding(runClusterRoleName, runNamespace)
+	_, err = serviceAccount.AddRoleBinding(ctx, runClusterRoleName, runNamespace)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create role binding")
 	}
 
+	//Isolate Run Namespace
+	err = c.createNetworkPolicies(ctx, runNamespace, config)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create network policies.")
+	}
+
+	//Limit resources of Run Namespace
+	err = c.applyResourceLimits(ctx, runNamespace, config)
+	if err != nil {
+		return errors.Wrap(err, "Failed to apply resource limits.")
+	}
+
+	return nil
+}
+
+// applyResourceLimits copies the resource quota and limit range templates
+// configured for the client to the run namespace. The default resources
+// of the tenant replace the defaults of the limit range template, or are
+// applied by a limit range of their own if there is no template. Multiple
+// limit ranges with defaults would be applied in an undefined order.
+func (c *runManager) applyResourceLimits(ctx context.Context, runNamespace string, config runConfig) (err error) {
+	_, span := tracing.Start(ctx, "apply resource limits")
+	defer func() { tracing.End(span, err) }()
+	if name := config.GetResourceQuotaTemplate(); name != "" {
+		if err = k8s.CopyResourceQuota(c.factory, name, config.GetClientNamespace(), runNamespace); err != nil {
+			return err
+		}
+	}
+	resources := config.GetTenantRunSettings().DefaultResources
+	if name := config.GetLimitRangeTemplate(); name != "" {
+		if err = k8s.CopyLimitRangeWithDefaults(c.factory, name, config.GetClientNamespace(), runNamespace, resources); err != nil {
+			return err
+		}
+	} else if resources != nil {
+		limitRange := newDefaultResourcesLimitRange(runNamespace, resources)
+		if _, err = c.factory.CoreV1().LimitRanges(runNamespace).Create(limitRange); err != nil {
+			return errors.WithMessage(err, "could not create limit range for default resources")
+		}
+	}
 	return nil
 }
 
-func (c *runManager) copySecrets(targetNamespace string, secretNames []string, pipelineRun k8s.PipelineRun) error {
+func (c *runManager) copySecrets(ctx context.Context, targetNamespace string, secretNames []string, pipelineRun k8s.PipelineRun) (err error) {
+	_, span := tracing.Start(ctx, "copy secrets")
+	defer func() { tracing.End(span, err) }()
 	for _, secretName := range secretNames {
 		targetClient := c.factory.CoreV1().Secrets(targetNamespace)
-		secret, err := c.secretProvider.GetSecret(secretName)
+		var secret *v1.Secret
+		secret, err = c.secretProvider.GetSecret(secretName)
 		if err != nil {
 			pipelineRun.UpdateResult(v1alpha1.ResultErrorContent)
 			pipelineRun.UpdateMessage(err.Error())
 			return err
 		}
-		createSecret(targetClient, targetNamespace, secretName, secret)
+		c.createSecret(targetClient, targetNamespace, secretName, secret)
 	}
 	return nil
 }
 
-func createSecret(client corev1.SecretInterface, namespace string, name string, secret *v1.Secret) {
+func (c *runManager) createSecret(client corev1.SecretInterface, namespace string, name string, secret *v1.Secret) {
 	newSecret := &v1.Secret{Data: secret.Data, StringData: secret.StringData, Type: secret.Type}
 	newSecret.SetName(name)
 	newSecret.SetNamespace(namespace)
@@ -148,13 +228,15 @@ func createSecret(client corev1.SecretInterface, namespace string, name string,
 	newSecret.SetAnnotations(secret.GetAnnotations())
 	_, err := client.Create(newSecret)
 	if err != nil {
-		log.Printf("Cannot create secret '%s' in namespace '%s': %s", name, namespace, err)
+		c.logger.Warnw("Cannot create secret", "secret", name, "error", err)
+		return
 	}
-	log.Printf("Copy secret: %s", name)
+	c.logger.Debugw("Copied secret", "secret", name)
 }
 
-func (c *runManager) createTektonTaskRun(pipelineRun k8s.PipelineRun) error {
-	var err error
+func (c *runManager) createTektonTaskRun(ctx context.Context, pipelineRun k8s.PipelineRun, clientNamespace string, runtime *v1alpha1.Runtime, timeout time.Duration, loggingConfig *v1alpha1.Logging, caches []cacheVolume) (err error) {
+	_, span := tracing.Start(ctx, "create TaskRun")
+	defer func() { tracing.End(span, err) }()
 
 	namespace := pipelineRun.GetRunNamespace()
 
@@ -162,33 +244,67 @@ func (c *runManager) createTektonTaskRun(pipelineRun k8s.PipelineRun) error {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      tektonTaskRunName,
 			Namespace: namespace,
+			Labels:    tektonTaskRunLabels(pipelineRun, clientNamespace),
 			Annotations: map[string]string{
 				annotationPipelineRunKey: pipelineRun.GetKey(),
 			},
 		},
 		Spec: tekton.TaskRunSpec{
-			ServiceAccount: serviceAccountName,
+			ServiceAccount: c.config.RunServiceAccountName,
 			TaskRef: &tekton.TaskRef{
 				Kind: tekton.ClusterTaskKind,
-				Name: tektonClusterTaskName,
+				Name: runtime.ClusterTask,
 			},
 			Inputs: tekton.TaskRunInputs{
 				Params: []tekton.Param{
 					tektonStringParam("RUN_NAMESPACE", namespace),
 				},
 			},
-			Timeout: toDuration(defaultBuildTimeout),
+			Timeout: &metav1.Duration{Duration: timeout},
 		},
 	}
 
 	c.addTektonTaskRunParamsForPipeline(pipelineRun, &tektonTaskRun)
-	c.addTektonTaskRunParamsForLoggingElasticsearch(pipelineRun, &tektonTaskRun)
+	c.addTektonTaskRunParamsForLoggingElasticsearch(loggingConfig, &tektonTaskRun)
+	if runtime.Image != "" {
+		tektonTaskRun.Spec.Inputs.Params = append(tektonTaskRun.Spec.Inputs.Params,
+			tektonStringParam("JFR_IMAGE", runtime.Image))
+	}
 
 	tektonClient := c.factory.TektonV1alpha1()
+	if len(caches) > 0 {
+		// Steps of a referenced task cannot get additional volume mounts,
+		// therefore the task spec gets embedded.
+		clusterTask, err := tektonClient.ClusterTasks().Get(runtime.ClusterTask, metav1.GetOptions{})
+		if err != nil {
+			return errors.WithMessagef(err, "could not get ClusterTask '%s'", runtime.ClusterTask)
+		}
+		taskSpec := clusterTask.Spec.DeepCopy()
+		if err = addCacheVolumes(taskSpec, caches); err != nil {
+			return errors.WithMessagef(err, "could not mount caches to ClusterTask '%s'", runtime.ClusterTask)
+		}
+		tektonTaskRun.Spec.TaskRef = nil
+		tektonTaskRun.Spec.TaskSpec = taskSpec
+	}
 	_, err = tektonClient.TaskRuns(tektonTaskRun.GetNamespace()).Create(&tektonTaskRun)
 	return err
 }
 
+// tektonTaskRunLabels returns the labels of the Tekton TaskRun of the given
+// pipeline run. The TaskRun carries the labels of the pipeline run and the
+// client namespace, so that the TaskRun informer can be restricted to the
+// scope of the controller like the pipeline run informer.
+func tektonTaskRunLabels(pipelineRun k8s.PipelineRun, clientNamespace string) map[string]string {
+	labels := map[string]string{}
+	for key, value := range pipelineRun.GetLabels() {
+		labels[key] = value
+	}
+	if clientNamespace != "" {
+		labels[v1alpha1.LabelClientNamespace] = clientNamespace
+	}
+	return labels
+}
+
 func (c *runManager) addTektonTaskRunParamsForPipeline(
 	pipelineRun k8s.PipelineRun,
 	tektonTaskRun *tekton.TaskRun,
@@ -217,13 +333,12 @@ func (c *runManager) addTektonTaskRunParamsForPipeline(
 }
 
 func (c *runManager) addTektonTaskRunParamsForLoggingElasticsearch(
-	pipelineRun k8s.PipelineRun,
+	loggingConfig *v1alpha1.Logging,
 	tektonTaskRun *tekton.TaskRun,
 ) error {
-	spec := pipelineRun.GetSpec()
 	var params []tekton.Param
 
-	if spec.Logging == nil || spec.Logging.Elasticsearch == nil {
+	if loggingConfig == nil || loggingConfig.Elasticsearch == nil {
 		params = []tekton.Param{
 			// overide the index URL hardcoded in the template by
 			// the empty string to effective disable logging to
@@ -231,7 +346,7 @@ func (c *runManager) addTektonTaskRunParamsForLoggingElasticsearch(
 			tektonStringParam("PIPELINE_LOG_ELASTICSEARCH_INDEX_URL", ""),
 		}
 	} else {
-		runIDJSON, err := toJSONString(&spec.Logging.Elasticsearch.RunID)
+		runIDJSON, err := toJSONString(&loggingConfig.Elasticsearch.RunID)
 		if err != nil {
 			return errors.WithMessage(err,
 				"could not serialize spec.logging.elasticsearch.runid to JSON",
@@ -252,18 +367,130 @@ func (c *runManager) addTektonTaskRunParamsForLoggingElasticsearch(
 func (c *runManager) GetRun(pipelineRun k8s.PipelineRun) (Run, error) {
 	namespace := pipelineRun.GetRunNamespace()
 	run, err := c.factory.TektonV1alpha1().TaskRuns(namespace).Get(tektonTaskRunName, metav1.GetOptions{})
-	return NewRun(run), err
+	if err != nil {
+		return NewRun(run), err
+	}
+	var pod *v1.Pod
+	if run.Status.PodName != "" {
+		pod, err = c.factory.CoreV1().Pods(namespace).Get(run.Status.PodName, metav1.GetOptions{})
+		if err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, errors.WithMessagef(err, "could not get pod '%s' in namespace '%s'", run.Status.PodName, namespace)
+			}
+			pod = nil
+		}
+	}
+	resultRules := defaultResultRules
+	if isTaskRunFinished(run) {
+		resultRules = c.getResultRules(pipelineRun)
+	}
+	return newRun(run, pod, resultRules), nil
+}
+
+// isTaskRunFinished returns whether the Tekton TaskRun has succeeded or
+// failed.
+func isTaskRunFinished(taskRun *tekton.TaskRun) bool {
+	condition := taskRun.Status.GetCondition(knativeapis.ConditionSucceeded)
+	return condition != nil && condition.Status != v1.ConditionUnknown
+}
+
+// getResultRules returns the result rules configured for the client
+// namespace of the pipeline run. If the configuration cannot be loaded,
+// the error is logged and the built-in rules are returned.
+func (c *runManager) getResultRules(pipelineRun k8s.PipelineRun) []resultRule {
+	config, err := getRunConfig(c.factory, pipelineRun.GetNamespace())
+	if err != nil {
+		c.logger.Warnw("Could not load result rules, using built-in rules", "error", err)
+		return defaultResultRules
+	}
+	return config.GetResultRules()
+}
+
+// Cancel cancels the Tekton TaskRun of a pipelineRun.
+// Tekton then deletes the pod executing the pipeline, which gives its
+// containers the chance to terminate gracefully.
+func (c *runManager) Cancel(pipelineRun k8s.PipelineRun) error {
+	namespace := pipelineRun.GetRunNamespace()
+	client := c.factory.TektonV1alpha1().TaskRuns(namespace)
+	taskRun, err := client.Get(tektonTaskRunName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.WithMessagef(err, "could not get Tekton TaskRun in namespace '%s'", namespace)
+	}
+	if taskRun.IsCancelled() {
+		return nil
+	}
+	taskRun.Spec.Status = tekton.TaskRunSpecStatusCancelled
+	if _, err = client.Update(taskRun); err != nil {
+		return errors.WithMessagef(err, "could not cancel Tekton TaskRun in namespace '%s'", namespace)
+	}
+	return nil
+}
+
+// IsTerminated returns true if the pod executing the pipeline of a
+// pipelineRun does not exist (anymore) or has terminated.
+func (c *runManager) IsTerminated(pipelineRun k8s.PipelineRun) (bool, error) {
+	namespace := pipelineRun.GetRunNamespace()
+	taskRun, err := c.factory.TektonV1alpha1().TaskRuns(namespace).Get(tektonTaskRunName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if taskRun.Status.PodName == "" {
+		return true, nil
+	}
+	pod, err := c.factory.CoreV1().Pods(namespace).Get(taskRun.Status.PodName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	phase := pod.Status.Phase
+	return phase == v1.PodSucceeded || phase == v1.PodFailed, nil
+}
+
+// GetTestSummary returns a summary of the JUnit test reports the pipeline
+// of a pipelineRun wrote to the test reports ConfigMap in the run namespace.
+// Returns nil if no test reports exist.
+func (c *runManager) GetTestSummary(pipelineRun k8s.PipelineRun) (*v1alpha1.TestSummary, error) {
+	namespace := pipelineRun.GetRunNamespace()
+	configMap, err := c.factory.CoreV1().ConfigMaps(namespace).Get(testReportsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessagef(err, "could not get test reports in namespace '%s'", namespace)
+	}
+	return summarizeTestReports(configMap.Data)
 }
 
 // Cleanup a run based on a pipelineRun
-func (c *runManager) Cleanup(pipelineRun k8s.PipelineRun) error {
+func (c *runManager) Cleanup(ctx context.Context, pipelineRun k8s.PipelineRun) (err error) {
+	ctx, span := tracing.Start(ctx, "cleanup run")
+	defer func() { tracing.End(span, err) }()
+	if len(pipelineRun.GetSpec().Caches) > 0 {
+		if err = c.releaseCaches(pipelineRun); err != nil {
+			storeErrorAsMessage(c.logger, pipelineRun, err, "error releasing caches")
+			return err
+		}
+		if config, err := getRunConfig(c.factory, pipelineRun.GetNamespace()); err != nil {
+			c.logger.Warnw("Skipping cache eviction", "error", err)
+		} else if err = c.evictCaches(pipelineRun.GetNamespace(), config); err != nil {
+			c.logger.Warnw("Failed to evict caches", "error", err)
+		}
+	}
 	namespace := pipelineRun.GetRunNamespace()
 	if namespace == "" {
-		pipelineRun.StoreErrorAsMessage(fmt.Errorf("Nothing to clean up as namespace not set"), "")
+		storeErrorAsMessage(c.logger, pipelineRun, fmt.Errorf("Nothing to clean up as namespace not set"), "")
 	} else {
-		err := c.namespaceManager.Delete(namespace)
+		err = c.namespaceManager.Delete(ctx, namespace)
 		if err != nil {
-			pipelineRun.StoreErrorAsMessage(err, "error deleting namespace")
+			storeErrorAsMessage(c.logger, pipelineRun, err, "error deleting namespace")
 			return err
 		}
 	}
@@ -279,17 +506,6 @@ func toJSONString(value interface{}) (string, error) {
 	return string(bytes), nil
 }
 
-// toDuration converts a duration string (see time.ParseDuration) into
-// a "k8s.io/apimachinery/pkg/apis/meta/v1".Duration object.
-// Panics in case of errors.
-func toDuration(duration string) *metav1.Duration {
-	d, err := time.ParseDuration(duration)
-	if err != nil {
-		panic(err)
-	}
-	return &metav1.Duration{Duration: d}
-}
-
 func tektonStringParam(name string, value string) tekton.Param {
 	return tekton.Param{
 		Name: name,
@@ -1,12 +1,15 @@
 package runctl
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	steward "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	fsteward "github.com/SAP/stewardci-core/pkg/client/clientset/versioned/fake"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	"github.com/SAP/stewardci-core/pkg/k8s"
 	k8sfake "github.com/SAP/stewardci-core/pkg/k8s/fake"
 	mocks "github.com/SAP/stewardci-core/pkg/k8s/mocks"
@@ -14,9 +17,11 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	gomock "github.com/golang/mock/gomock"
 	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"go.uber.org/zap"
 	"gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
 	v1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 )
@@ -31,14 +36,144 @@ func Test_RunManager_PrepareRunNamespace_CreatesNamespace(t *testing.T) {
 	preparePredefinedSecrets(mockSecretProvider)
 	preparePredefinedClusterRole(t, mockFactory, mockPipelineRun)
 
-	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager).(*runManager)
+	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager, newTestConfig(), zap.NewNop().Sugar()).(*runManager)
 
 	// EXERCISE
-	err := examinee.prepareRunNamespace(mockPipelineRun)
+	err := examinee.prepareRunNamespace(context.Background(), mockPipelineRun, &runConfigImpl{})
 	assert.NilError(t, err)
 
 	// VERIFY
-	assert.Assert(t, strings.HasPrefix(mockPipelineRun.GetRunNamespace(), runNamespacePrefix))
+	assert.Assert(t, strings.HasPrefix(mockPipelineRun.GetRunNamespace(), controllerconfig.DefaultRunNamespacePrefix))
+	namespace, err := mockFactory.CoreV1().Namespaces().Get(mockPipelineRun.GetRunNamespace(), metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "key", namespace.GetAnnotations()[steward.AnnotationPipelineRun])
+}
+
+func Test_RunManager_PrepareRunNamespace_NoAnnotations_CreatesNetworkPolicies(t *testing.T) {
+	// SETUP
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockFactory, mockPipelineRun, mockSecretProvider, mockNamespaceManager := prepareMocks(mockCtrl)
+	preparePredefinedSecrets(mockSecretProvider)
+	preparePredefinedClusterRole(t, mockFactory, mockPipelineRun)
+	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager, newTestConfig(), zap.NewNop().Sugar()).(*runManager)
+
+	// EXERCISE
+	err := examinee.prepareRunNamespace(context.Background(), mockPipelineRun, &runConfigImpl{})
+
+	// VERIFY
+	assert.NilError(t, err)
+	policies, err := mockFactory.NetworkingV1().NetworkPolicies(mockPipelineRun.GetRunNamespace()).List(metav1.ListOptions{})
+	assert.NilError(t, err)
+	names := []string{}
+	for _, policy := range policies.Items {
+		names = append(names, policy.GetName())
+	}
+	assert.DeepEqual(t, []string{networkPolicyDefaultDenyName, networkPolicyAllowName}, names)
+}
+
+func Test_RunManager_applyResourceLimits_CopiesTemplates(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	cf := k8sfake.NewClientFactory(
+		&v1.ResourceQuota{ObjectMeta: k8sfake.ObjectMeta("quota1", "client1")},
+		&v1.LimitRange{ObjectMeta: k8sfake.ObjectMeta("limits1", "client1")},
+	)
+	config := &runConfigImpl{
+		clientNamespace:       "client1",
+		resourceQuotaTemplate: "quota1",
+		limitRangeTemplate:    "limits1",
+	}
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err := examinee.applyResourceLimits(context.Background(), "run1", config)
+
+	// VERIFY
+	assert.NilError(t, err)
+	_, err = cf.CoreV1().ResourceQuotas("run1").Get("quota1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	_, err = cf.CoreV1().LimitRanges("run1").Get("limits1", metav1.GetOptions{})
+	assert.NilError(t, err)
+}
+
+func Test_RunManager_applyResourceLimits_NoTemplates(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	cf := k8sfake.NewClientFactory()
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err := examinee.applyResourceLimits(context.Background(), "run1", &runConfigImpl{})
+
+	// VERIFY
+	assert.NilError(t, err)
+	quotas, err := cf.CoreV1().ResourceQuotas("run1").List(metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(quotas.Items))
+}
+
+func Test_RunManager_applyResourceLimits_DefaultResources(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	cf := k8sfake.NewClientFactory()
+	resources := &v1.ResourceRequirements{
+		Limits:   v1.ResourceList{v1.ResourceMemory: k8sresource.MustParse("2Gi")},
+		Requests: v1.ResourceList{v1.ResourceCPU: k8sresource.MustParse("500m")},
+	}
+	config := &runConfigImpl{tenantRunSettings: &steward.TenantRunSettings{DefaultResources: resources}}
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err := examinee.applyResourceLimits(context.Background(), "run1", config)
+
+	// VERIFY
+	assert.NilError(t, err)
+	limitRange, err := cf.CoreV1().LimitRanges("run1").Get(defaultResourcesLimitRangeName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(limitRange.Spec.Limits))
+	limits := limitRange.Spec.Limits[0]
+	assert.Equal(t, v1.LimitTypeContainer, limits.Type)
+	assert.Equal(t, "2Gi", limits.Default.Memory().String())
+	assert.Equal(t, "500m", limits.DefaultRequest.Cpu().String())
+}
+
+func Test_RunManager_applyResourceLimits_DefaultResourcesWithTemplate(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	cf := k8sfake.NewClientFactory(&v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits1", Namespace: "client1"},
+		Spec: v1.LimitRangeSpec{
+			Limits: []v1.LimitRangeItem{{
+				Type:    v1.LimitTypeContainer,
+				Default: v1.ResourceList{v1.ResourceMemory: k8sresource.MustParse("1Gi")},
+			}},
+		},
+	})
+	resources := &v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceMemory: k8sresource.MustParse("2Gi")},
+	}
+	config := &runConfigImpl{
+		clientNamespace:    "client1",
+		limitRangeTemplate: "limits1",
+		tenantRunSettings:  &steward.TenantRunSettings{DefaultResources: resources},
+	}
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err := examinee.applyResourceLimits(context.Background(), "run1", config)
+
+	// VERIFY
+	assert.NilError(t, err)
+	limitRanges, err := cf.CoreV1().LimitRanges("run1").List(metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(limitRanges.Items))
+	assert.Equal(t, "limits1", limitRanges.Items[0].GetName())
+	assert.Equal(t, "2Gi", limitRanges.Items[0].Spec.Limits[0].Default.Memory().String())
 }
 
 func Test_RunManager_Start_CreatesTektonTaskRun(t *testing.T) {
@@ -51,10 +186,10 @@ func Test_RunManager_Start_CreatesTektonTaskRun(t *testing.T) {
 	preparePredefinedSecrets(mockSecretProvider)
 	preparePredefinedClusterRole(t, mockFactory, mockPipelineRun)
 
-	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager)
+	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager, newTestConfig(), zap.NewNop().Sugar())
 
 	// EXERCISE
-	err := examinee.Start(mockPipelineRun)
+	err := examinee.Start(context.Background(), mockPipelineRun)
 	assert.NilError(t, err)
 
 	// VERIFY
@@ -74,10 +209,10 @@ func Test_RunManager_Start_DoesNotSetPipelineRunStatus(t *testing.T) {
 	preparePredefinedSecrets(mockSecretProvider)
 	preparePredefinedClusterRole(t, mockFactory, mockPipelineRun)
 
-	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager)
+	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager, newTestConfig(), zap.NewNop().Sugar())
 
 	// EXERCISE
-	err := examinee.Start(mockPipelineRun)
+	err := examinee.Start(context.Background(), mockPipelineRun)
 	assert.NilError(t, err)
 
 	// VERIFY
@@ -96,13 +231,13 @@ func Test_RunManager_Cleanup_RemovesNamespace(t *testing.T) {
 	preparePredefinedClusterRole(t, mockFactory, mockPipelineRun)
 	mockPipelineRun.EXPECT().FinishState()
 
-	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager).(*runManager)
-	err := examinee.prepareRunNamespace(mockPipelineRun)
+	examinee := NewRunManager(mockFactory, mockSecretProvider, mockNamespaceManager, newTestConfig(), zap.NewNop().Sugar()).(*runManager)
+	err := examinee.prepareRunNamespace(context.Background(), mockPipelineRun, &runConfigImpl{})
 	assert.NilError(t, err)
 	//TODO: mockNamespaceManager.EXPECT().Create()...
 
 	// EXERCISE
-	examinee.Cleanup(mockPipelineRun)
+	examinee.Cleanup(context.Background(), mockPipelineRun)
 	//TODO: mockNamespaceManager.EXPECT().Delete()...
 }
 
@@ -143,8 +278,10 @@ func Test_RunManager_Log_Elasticsearch(t *testing.T) {
 		assert.NilError(t, err)
 		examinee = NewRunManager(
 			cf,
-			k8s.NewTenantNamespace(cf, pipelineRun.GetNamespace()),
-			k8s.NewNamespaceManager(cf, "prefix1", 0),
+			k8s.NewTenantNamespace(cf, pipelineRun.GetNamespace(), zap.NewNop().Sugar()),
+			k8s.NewNamespaceManager(cf, "prefix1", 0, zap.NewNop().Sugar()),
+			newTestConfig(),
+			zap.NewNop().Sugar(),
 		).(*runManager)
 		return
 	}
@@ -220,7 +357,7 @@ func Test_RunManager_Log_Elasticsearch(t *testing.T) {
 			examinee, k8sPipelineRun, cf := setupExaminee(t, pipelineRunJSON)
 
 			// exercise
-			err = examinee.createTektonTaskRun(k8sPipelineRun)
+			err = examinee.createTektonTaskRun(context.Background(), k8sPipelineRun, "", &steward.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, time.Hour, k8sPipelineRun.GetSpec().Logging, nil)
 			assert.NilError(t, err)
 
 			// verify
@@ -276,7 +413,7 @@ func Test_RunManager_Log_Elasticsearch(t *testing.T) {
 			examinee, k8sPipelineRun, cf := setupExaminee(t, pipelineRunJSON)
 
 			// exercise
-			err = examinee.createTektonTaskRun(k8sPipelineRun)
+			err = examinee.createTektonTaskRun(context.Background(), k8sPipelineRun, "", &steward.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, time.Hour, k8sPipelineRun.GetSpec().Logging, nil)
 			assert.NilError(t, err)
 
 			// verify
@@ -314,8 +451,9 @@ func preparePredefinedClusterRole(t *testing.T, factory *mocks.MockClientFactory
 func prepareMocks(ctrl *gomock.Controller) (*mocks.MockClientFactory, *mocks.MockPipelineRun, *mocks.MockSecretProvider, k8s.NamespaceManager) {
 	mockFactory := mocks.NewMockClientFactory(ctrl)
 
-	coreClientSet := kubefake.NewSimpleClientset()
+	coreClientSet := kubefake.NewSimpleClientset(k8sfake.Namespace("tenant-ns-1"))
 	mockFactory.EXPECT().CoreV1().Return(coreClientSet.CoreV1()).AnyTimes()
+	mockFactory.EXPECT().NetworkingV1().Return(coreClientSet.NetworkingV1()).AnyTimes()
 	mockFactory.EXPECT().RbacV1beta1().Return(coreClientSet.RbacV1beta1()).AnyTimes()
 
 	stewardClientset := fsteward.NewSimpleClientset()
@@ -329,6 +467,8 @@ func prepareMocks(ctrl *gomock.Controller) (*mocks.MockClientFactory, *mocks.Moc
 	mockPipelineRun.EXPECT().GetSpec().Return(&steward.PipelineSpec{}).AnyTimes()
 	mockPipelineRun.EXPECT().GetStatus().Return(&steward.PipelineStatus{}).AnyTimes()
 	mockPipelineRun.EXPECT().GetKey().Return("key").AnyTimes()
+	mockPipelineRun.EXPECT().GetLabels().Return(nil).AnyTimes()
+	mockPipelineRun.EXPECT().GetNamespace().Return("tenant-ns-1").AnyTimes()
 	mockPipelineRun.EXPECT().GetRunNamespace().DoAndReturn(func() string {
 		return runNamespace
 	}).AnyTimes()
@@ -340,7 +480,182 @@ func prepareMocks(ctrl *gomock.Controller) (*mocks.MockClientFactory, *mocks.Moc
 	mockSecretProvider := mocks.NewMockSecretProvider(ctrl)
 
 	//TODO: Mock when required
-	namespaceManager := k8s.NewNamespaceManager(mockFactory, runNamespacePrefix, runNamespaceRandomLength)
+	namespaceManager := k8s.NewNamespaceManager(mockFactory, controllerconfig.DefaultRunNamespacePrefix, controllerconfig.DefaultRunNamespaceRandomLength, zap.NewNop().Sugar())
 
 	return mockFactory, mockPipelineRun, mockSecretProvider, namespaceManager
 }
+
+func Test_RunManager_createTektonTaskRun_Runtime(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name                string
+		runtime             *steward.Runtime
+		expectedClusterTask string
+		expectedImageParams int
+	}{
+		{"Default", &steward.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, controllerconfig.DefaultTektonClusterTaskName, 0},
+		{"Custom", &steward.Runtime{ClusterTask: "task1", Image: "image1"}, "task1", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			pipelineRun := k8sfake.PipelineRun("run1", "ns1", steward.PipelineSpec{})
+			cf := k8sfake.NewClientFactory(pipelineRun)
+			k8sPipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("ns1", "run1")
+			assert.NilError(t, err)
+			k8sPipelineRun.UpdateRunNamespace("run-ns1")
+			examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+			// EXERCISE
+			err = examinee.createTektonTaskRun(context.Background(), k8sPipelineRun, "", tc.runtime, time.Hour, nil, nil)
+
+			// VERIFY
+			assert.NilError(t, err)
+			taskRun, err := cf.TektonV1alpha1().TaskRuns("run-ns1").Get(tektonTaskRunName, metav1.GetOptions{})
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expectedClusterTask, taskRun.Spec.TaskRef.Name)
+			imageParams := 0
+			for _, param := range taskRun.Spec.Inputs.Params {
+				if param.Name == "JFR_IMAGE" {
+					imageParams++
+					assert.Equal(t, tc.runtime.Image, param.Value.StringVal)
+				}
+			}
+			assert.Equal(t, tc.expectedImageParams, imageParams)
+		})
+	}
+}
+
+func Test_RunManager_createTektonTaskRun_Timeout(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	pipelineRun := k8sfake.PipelineRun("run1", "ns1", steward.PipelineSpec{})
+	cf := k8sfake.NewClientFactory(pipelineRun)
+	k8sPipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("ns1", "run1")
+	assert.NilError(t, err)
+	k8sPipelineRun.UpdateRunNamespace("run-ns1")
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err = examinee.createTektonTaskRun(context.Background(), k8sPipelineRun, "", &steward.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, 15*time.Minute, nil, nil)
+
+	// VERIFY
+	assert.NilError(t, err)
+	taskRun, err := cf.TektonV1alpha1().TaskRuns("run-ns1").Get(tektonTaskRunName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, 15*time.Minute, taskRun.Spec.Timeout.Duration)
+}
+
+func Test_RunManager_createTektonTaskRun_Labels(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	pipelineRun := k8sfake.PipelineRun("run1", "ns1", steward.PipelineSpec{})
+	pipelineRun.SetLabels(map[string]string{"steward": "prod"})
+	cf := k8sfake.NewClientFactory(pipelineRun)
+	k8sPipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("ns1", "run1")
+	assert.NilError(t, err)
+	k8sPipelineRun.UpdateRunNamespace("run-ns1")
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	err = examinee.createTektonTaskRun(context.Background(), k8sPipelineRun, "client1", &steward.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, time.Hour, nil, nil)
+
+	// VERIFY
+	assert.NilError(t, err)
+	taskRun, err := cf.TektonV1alpha1().TaskRuns("run-ns1").Get(tektonTaskRunName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{
+		"steward":                    "prod",
+		steward.LabelClientNamespace: "client1",
+	}, taskRun.GetLabels())
+}
+
+func Test_RunManager_GetTestSummary(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	pipelineRun := k8sfake.PipelineRun("run1", "ns1", steward.PipelineSpec{})
+	cf := k8sfake.NewClientFactory(pipelineRun, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: testReportsConfigMapName, Namespace: "run-ns1"},
+		Data: map[string]string{
+			"TEST-suite1.xml": `<testsuite><testcase classname="pkg.Test1" name="passes"/><testcase classname="pkg.Test1" name="fails"><failure/></testcase></testsuite>`,
+		},
+	})
+	k8sPipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("ns1", "run1")
+	assert.NilError(t, err)
+	k8sPipelineRun.UpdateRunNamespace("run-ns1")
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	summary, err := examinee.GetTestSummary(k8sPipelineRun)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &steward.TestSummary{Total: 2, Passed: 1, Failed: 1, FailedTests: []string{"pkg.Test1.fails"}}, summary)
+}
+
+func Test_RunManager_GetTestSummary_NoReports(t *testing.T) {
+	t.Parallel()
+
+	// SETUP
+	pipelineRun := k8sfake.PipelineRun("run1", "ns1", steward.PipelineSpec{})
+	cf := k8sfake.NewClientFactory(pipelineRun)
+	k8sPipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("ns1", "run1")
+	assert.NilError(t, err)
+	k8sPipelineRun.UpdateRunNamespace("run-ns1")
+	examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+	// EXERCISE
+	summary, err := examinee.GetTestSummary(k8sPipelineRun)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Assert(t, summary == nil)
+}
+
+func Test_RunManager_GetRun_ResultRules(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		taskRun        string
+		resultRules    string
+		expectedResult steward.Result
+		expectedReason steward.ResultReason
+	}{
+		{"FinishedConfiguredRule", gitCloneFailed,
+			`[{"stepExitCode": 1, "result": "error_infra", "resultReason": "GitServerDown"}]`,
+			steward.ResultErrorInfra, steward.ResultReason("GitServerDown")},
+		{"FinishedInvalidConfigUsesBuiltInRules", gitCloneFailed, `foo`,
+			steward.ResultErrorContent, steward.ResultReasonGitCloneFailed},
+		{"RunningInvalidConfigUsesBuiltInRules", invalidImageName, `foo`,
+			steward.ResultErrorContent, steward.ResultReasonImagePullFailed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			taskRun := fakeTektonTaskRun(tc.taskRun)
+			taskRun.ObjectMeta = k8sfake.ObjectMeta(tektonTaskRunName, "run-ns1")
+			cf := k8sfake.NewClientFactory(
+				k8sfake.NamespaceWithAnnotations("tenant1", map[string]string{steward.AnnotationClientNamespace: "client1"}),
+				k8sfake.NamespaceWithAnnotations("client1", map[string]string{steward.AnnotationRunResultRules: tc.resultRules}),
+				k8sfake.PipelineRun("run1", "tenant1", steward.PipelineSpec{}),
+			)
+			_, err := cf.TektonV1alpha1().TaskRuns("run-ns1").Create(taskRun)
+			assert.NilError(t, err)
+			pipelineRun, err := k8s.NewPipelineRunFetcher(cf).ByName("tenant1", "run1")
+			assert.NilError(t, err)
+			pipelineRun.UpdateRunNamespace("run-ns1")
+			examinee := &runManager{factory: cf, config: newTestConfig(), logger: zap.NewNop().Sugar()}
+
+			// EXERCISE
+			run, err := examinee.GetRun(pipelineRun)
+
+			// VERIFY
+			assert.NilError(t, err)
+			finished, result := run.IsFinished()
+			assert.Assert(t, finished)
+			assert.Equal(t, tc.expectedResult, result)
+			assert.Equal(t, tc.expectedReason, run.GetResultReason())
+		})
+	}
+}
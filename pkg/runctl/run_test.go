@@ -10,6 +10,7 @@ import (
 	"github.com/ghodss/yaml"
 	tekton "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -21,6 +22,12 @@ const (
 	completedSuccess          = `{"status": {"conditions": [{"message": "message1", "reason": "Succeeded", "status": "True", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "terminated": {"reason": "Completed", "message": "ok", "exitCode": 0}}]}}`
 	completedFail             = `{"status": {"conditions": [{"message": "message1", "reason": "Failed", "status": "False", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "terminated": {"reason": "Error", "message": "ko", "exitCode": 1}}]}}`
 	completedValidationFailed = `{"status": {"conditions": [{"message": "message1", "reason": "TaskRunValidationFailed", "status": "False", "type": "Succeeded"}]}}`
+	gitCloneFailed            = `{"status": {"conditions": [{"message": "message1", "reason": "Failed", "status": "False", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "terminated": {"reason": "Error", "message": "fatal: could not read from remote repository", "exitCode": 1}}]}}`
+	oomKilled                 = `{"status": {"conditions": [{"message": "message1", "reason": "Failed", "status": "False", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "terminated": {"reason": "OOMKilled", "exitCode": 137}}]}}`
+	invalidImageName          = `{"status": {"conditions": [{"message": "message1", "reason": "Pending", "status": "Unknown", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "waiting": {"reason": "InvalidImageName"}}]}}`
+	imagePullBackOff          = `{"status": {"conditions": [{"message": "message1", "reason": "Pending", "status": "Unknown", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "waiting": {"reason": "ImagePullBackOff"}}]}}`
+	timeoutImagePullBackOff   = `{"status": {"conditions": [{"message": "message1", "reason": "TaskRunTimeout", "status": "False", "type": "Succeeded"}], "steps": [{"name": "jenkinsfile-runner", "waiting": {"reason": "ImagePullBackOff"}}]}}`
+	exceededQuota             = `{"status": {"conditions": [{"message": "TaskRun pod \"steward-jenkinsfile-runner\" exceeded available resources", "reason": "ExceededResourceQuota", "status": "Unknown", "type": "Succeeded"}]}}`
 	//See issue https://github.com/SAP/stewardci-core/issues/? TODO: create public issue. internal: 21
 	timeout = `{"status": {"conditions": [{"message": "TaskRun \"steward-jenkinsfile-runner\" failed to finish within \"10m0s\"", "reason": "TaskRunTimeout", "status": "False", "type": "Succeeded"}]}}`
 
@@ -102,6 +109,46 @@ func Test__IsFinished_RunningUpdatesContainer(t *testing.T) {
 	assert.Assert(t, finished == false)
 }
 
+func Test__GetSteps_MultipleSteps(t *testing.T) {
+	// SETUP
+	build := fakeTektonTaskRun(`{"status": {"steps": [
+		{"name": "git-clone", "terminated": {"reason": "Completed", "exitCode": 0, "startedAt": "2019-05-14T08:24:09Z", "finishedAt": "2019-05-14T08:24:10Z"}},
+		{"name": "jenkinsfile-runner", "running": {"startedAt": "2019-05-14T08:24:11Z"}},
+		{"name": "log-shipper", "waiting": {"reason": "PodInitializing"}}]}}`)
+	run := NewRun(build)
+
+	// EXERCISE
+	steps := run.GetSteps()
+
+	// VERIFY
+	exitCode := int32(0)
+	assert.DeepEqual(t, []api.StepStatus{
+		{
+			Name:       "git-clone",
+			State:      api.StepStateTerminated,
+			Reason:     "Completed",
+			ExitCode:   &exitCode,
+			StartedAt:  generateTime("2019-05-14T08:24:09Z"),
+			FinishedAt: generateTime("2019-05-14T08:24:10Z"),
+		},
+		{
+			Name:      "jenkinsfile-runner",
+			State:     api.StepStateRunning,
+			StartedAt: generateTime("2019-05-14T08:24:11Z"),
+		},
+		{
+			Name:   "log-shipper",
+			State:  api.StepStateWaiting,
+			Reason: "PodInitializing",
+		},
+	}, steps)
+}
+
+func Test__GetSteps_NoSteps(t *testing.T) {
+	run := NewRun(fakeTektonTaskRun(emptyBuild))
+	assert.Equal(t, 0, len(run.GetSteps()))
+}
+
 func Test__IsFinished_CompletedSuccess(t *testing.T) {
 	build := fakeTektonTaskRunYaml(realCompletedSuccess)
 	run := NewRun(build)
@@ -118,6 +165,7 @@ func Test__IsFinished_CompletedFail(t *testing.T) {
 	assert.Assert(t, run.GetContainerInfo().Terminated != nil)
 	assert.Assert(t, finished == true)
 	assert.Equal(t, result, api.ResultErrorContent)
+	assert.Equal(t, run.GetResultReason(), api.ResultReasonPipelineScriptError)
 }
 
 func Test__IsFinished_CompletedValidationFail(t *testing.T) {
@@ -134,4 +182,68 @@ func Test__IsFinished_Timeout(t *testing.T) {
 	assert.Assert(t, run.GetContainerInfo() == nil)
 	assert.Assert(t, finished == true)
 	assert.Equal(t, result, api.ResultTimeout)
+	assert.Equal(t, run.GetResultReason(), api.ResultReasonTimeout)
+}
+
+func Test__IsFinished_ExceededResourceQuota(t *testing.T) {
+	run := NewRun(fakeTektonTaskRun(exceededQuota))
+	finished, result := run.IsFinished()
+	assert.Assert(t, finished == true)
+	assert.Equal(t, result, api.ResultErrorQuota)
+	assert.Equal(t, run.GetResultReason(), api.ResultReasonQuotaExceeded)
+}
+
+func Test__IsFinished_Classification(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		taskRun          string
+		pod              *corev1.Pod
+		expectedFinished bool
+		expectedResult   api.Result
+		expectedReason   api.ResultReason
+	}{
+		{"GitCloneFailed", gitCloneFailed, nil, true, api.ResultErrorContent, api.ResultReasonGitCloneFailed},
+		{"OOMKilled", oomKilled, nil, true, api.ResultErrorContent, api.ResultReasonOOMKilled},
+		{"InvalidImageName", invalidImageName, nil, true, api.ResultErrorContent, api.ResultReasonImagePullFailed},
+		{"ImagePullBackOffWaits", imagePullBackOff, nil, false, api.ResultUndefined, api.ResultReasonUndefined},
+		{"TimeoutImagePullBackOff", timeoutImagePullBackOff, nil, true, api.ResultErrorInfra, api.ResultReasonImagePullFailed},
+		{"PodEvicted", completedFail,
+			&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}},
+			true, api.ResultErrorInfra, api.ResultReasonPodEvicted},
+		{"Unschedulable", timeout,
+			&corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable"},
+			}}},
+			true, api.ResultErrorInfra, api.ResultReasonUnschedulable},
+		{"ValidationFailed", completedValidationFailed, nil, true, api.ResultErrorInfra, api.ResultReasonUndefined},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			run := newRun(fakeTektonTaskRun(tc.taskRun), tc.pod, defaultResultRules)
+
+			// EXERCISE
+			finished, result := run.IsFinished()
+			reason := run.GetResultReason()
+
+			// VERIFY
+			assert.Equal(t, tc.expectedFinished, finished)
+			assert.Equal(t, tc.expectedResult, result)
+			assert.Equal(t, tc.expectedReason, reason)
+		})
+	}
+}
+
+func Test__IsFinished_ConfiguredRuleTakesPrecedence(t *testing.T) {
+	// SETUP
+	rules, err := parseResultRules(`[{"stepExitCode": 1, "messagePattern": "remote repository", "result": "error_infra", "resultReason": "GitServerDown"}]`)
+	assert.NilError(t, err)
+	run := newRun(fakeTektonTaskRun(gitCloneFailed), nil, append(rules, defaultResultRules...))
+
+	// EXERCISE
+	finished, result := run.IsFinished()
+
+	// VERIFY
+	assert.Assert(t, finished)
+	assert.Equal(t, api.ResultErrorInfra, result)
+	assert.Equal(t, api.ResultReason("GitServerDown"), run.GetResultReason())
 }
@@ -0,0 +1,62 @@
+package runctl
+
+import (
+	"strings"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/pkg/errors"
+)
+
+// getRuntime returns the runtime requested by the given pipeline run spec
+// with defaults applied.
+// If the tenant defines a runtime, it is used for all pipeline runs of the
+// tenant, and pipeline runs requesting another one are rejected.
+// An error is returned if the runtime is not allowed by the configuration.
+func getRuntime(spec *api.PipelineSpec, config runConfig, defaultClusterTask string) (*api.Runtime, error) {
+	runtime := &api.Runtime{}
+	if spec.Runtime != nil {
+		*runtime = *spec.Runtime
+	}
+
+	if tenantRuntime := config.GetTenantRunSettings().Runtime; tenantRuntime != nil {
+		allowed := *tenantRuntime
+		if allowed.ClusterTask == "" {
+			allowed.ClusterTask = defaultClusterTask
+		}
+		if runtime.ClusterTask != "" && runtime.ClusterTask != allowed.ClusterTask {
+			return nil, errors.Errorf("ClusterTask '%s' in spec.runtime.clusterTask is not allowed for the tenant", runtime.ClusterTask)
+		}
+		if runtime.Image != "" && runtime.Image != allowed.Image {
+			return nil, errors.Errorf("image '%s' in spec.runtime.image is not allowed for the tenant", runtime.Image)
+		}
+		runtime = &allowed
+	}
+
+	if runtime.ClusterTask == "" {
+		runtime.ClusterTask = defaultClusterTask
+	} else if runtime.ClusterTask != defaultClusterTask && !isAllowed(runtime.ClusterTask, config.GetAllowedClusterTasks()) {
+		return nil, errors.Errorf("ClusterTask '%s' in spec.runtime.clusterTask is not allowed", runtime.ClusterTask)
+	}
+
+	if runtime.Image != "" && !isAllowed(runtime.Image, config.GetAllowedImages()) {
+		return nil, errors.Errorf("image '%s' in spec.runtime.image is not allowed", runtime.Image)
+	}
+
+	return runtime, nil
+}
+
+// isAllowed returns whether the value matches one of the given patterns.
+// A pattern ending with '*' matches all values starting with the preceding
+// string, any other pattern must match exactly.
+func isAllowed(value string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(value, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if value == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package runctl
+
+import (
+	"testing"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
+	"gotest.tools/assert"
+)
+
+func Test_getRuntime(t *testing.T) {
+	config := &runConfigImpl{
+		allowedClusterTasks: []string{"task1"},
+		allowedImages:       []string{"image1:v1", "repo/image2:*"},
+	}
+	for _, tc := range []struct {
+		name          string
+		runtime       *api.Runtime
+		expected      *api.Runtime
+		expectedError string
+	}{
+		{"NoRuntime", nil, &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, ""},
+		{"EmptyRuntime", &api.Runtime{}, &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, ""},
+		{"DefaultClusterTask", &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, ""},
+		{"AllowedClusterTask", &api.Runtime{ClusterTask: "task1"}, &api.Runtime{ClusterTask: "task1"}, ""},
+		{"AllowedImage", &api.Runtime{Image: "image1:v1"}, &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName, Image: "image1:v1"}, ""},
+		{"AllowedImagePrefix", &api.Runtime{Image: "repo/image2:v2"}, &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName, Image: "repo/image2:v2"}, ""},
+		{"ForbiddenClusterTask", &api.Runtime{ClusterTask: "task2"}, nil, "ClusterTask 'task2' in spec.runtime.clusterTask is not allowed"},
+		{"ForbiddenImage", &api.Runtime{Image: "image1:v2"}, nil, "image 'image1:v2' in spec.runtime.image is not allowed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// EXERCISE
+			runtime, err := getRuntime(&api.PipelineSpec{Runtime: tc.runtime}, config, controllerconfig.DefaultTektonClusterTaskName)
+
+			// VERIFY
+			if tc.expectedError != "" {
+				assert.Error(t, err, tc.expectedError)
+			} else {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, tc.expected, runtime)
+			}
+		})
+	}
+}
+
+func Test_getRuntime_TenantRuntime(t *testing.T) {
+	config := &runConfigImpl{
+		allowedClusterTasks: []string{"task1"},
+		allowedImages:       []string{"image1:*"},
+		tenantRunSettings: &api.TenantRunSettings{
+			Runtime: &api.Runtime{ClusterTask: "task1", Image: "image1:v1"},
+		},
+	}
+	tenantRuntime := &api.Runtime{ClusterTask: "task1", Image: "image1:v1"}
+	for _, tc := range []struct {
+		name          string
+		runtime       *api.Runtime
+		expected      *api.Runtime
+		expectedError string
+	}{
+		{"NoRuntime", nil, tenantRuntime, ""},
+		{"SameRuntime", &api.Runtime{ClusterTask: "task1", Image: "image1:v1"}, tenantRuntime, ""},
+		{"SameImage", &api.Runtime{Image: "image1:v1"}, tenantRuntime, ""},
+		{"OtherClusterTask", &api.Runtime{ClusterTask: controllerconfig.DefaultTektonClusterTaskName}, nil,
+			"ClusterTask '" + controllerconfig.DefaultTektonClusterTaskName + "' in spec.runtime.clusterTask is not allowed for the tenant"},
+		{"OtherImage", &api.Runtime{Image: "image1:v2"}, nil, "image 'image1:v2' in spec.runtime.image is not allowed for the tenant"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// EXERCISE
+			runtime, err := getRuntime(&api.PipelineSpec{Runtime: tc.runtime}, config, controllerconfig.DefaultTektonClusterTaskName)
+
+			// VERIFY
+			if tc.expectedError != "" {
+				assert.Error(t, err, tc.expectedError)
+			} else {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, tc.expected, runtime)
+			}
+		})
+	}
+}
+
+func Test_getRuntime_TenantRuntimeNotAllowedForClient(t *testing.T) {
+	// SETUP
+	config := &runConfigImpl{
+		tenantRunSettings: &api.TenantRunSettings{Runtime: &api.Runtime{Image: "image1:v1"}},
+	}
+
+	// EXERCISE
+	_, err := getRuntime(&api.PipelineSpec{}, config, controllerconfig.DefaultTektonClusterTaskName)
+
+	// VERIFY
+	assert.Error(t, err, "image 'image1:v1' in spec.runtime.image is not allowed")
+}
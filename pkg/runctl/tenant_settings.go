@@ -0,0 +1,66 @@
+package runctl
+
+import (
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultResourcesLimitRangeName is the name of the limit range in run
+// namespaces defining the default resources of the tenant.
+const defaultResourcesLimitRangeName = "steward-default-resources"
+
+// getTimeout returns the timeout of the given pipeline run. It defaults to
+// the default timeout of the tenant, and must not exceed the maximum
+// timeout of the tenant. Both default to the given build timeout, which
+// tenants cannot exceed.
+func getTimeout(spec *api.PipelineSpec, config runConfig, buildTimeout time.Duration) (time.Duration, error) {
+	settings := config.GetTenantRunSettings()
+	maxTimeout := buildTimeout
+	if settings.MaxTimeout != nil && settings.MaxTimeout.Duration < buildTimeout {
+		maxTimeout = settings.MaxTimeout.Duration
+	}
+	if spec.Timeout == nil {
+		if settings.DefaultTimeout != nil && settings.DefaultTimeout.Duration < maxTimeout {
+			return settings.DefaultTimeout.Duration, nil
+		}
+		return maxTimeout, nil
+	}
+	if spec.Timeout.Duration <= 0 {
+		return 0, errors.Errorf("timeout '%s' in spec.timeout must be positive", spec.Timeout.Duration)
+	}
+	if spec.Timeout.Duration > maxTimeout {
+		return 0, errors.Errorf("timeout '%s' in spec.timeout exceeds the maximum timeout '%s' of the tenant", spec.Timeout.Duration, maxTimeout)
+	}
+	return spec.Timeout.Duration, nil
+}
+
+// getLogging returns the logging configuration of the given pipeline run,
+// which defaults to the one of the tenant.
+func getLogging(spec *api.PipelineSpec, config runConfig) *api.Logging {
+	if spec.Logging != nil {
+		return spec.Logging
+	}
+	return config.GetTenantRunSettings().Logging
+}
+
+// newDefaultResourcesLimitRange returns a limit range applying the given
+// default resources to the containers in the run namespace.
+func newDefaultResourcesLimitRange(runNamespace string, resources *v1.ResourceRequirements) *v1.LimitRange {
+	return &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      defaultResourcesLimitRangeName,
+			Namespace: runNamespace,
+		},
+		Spec: v1.LimitRangeSpec{
+			Limits: []v1.LimitRangeItem{{
+				Type:           v1.LimitTypeContainer,
+				Default:        resources.Limits,
+				DefaultRequest: resources.Requests,
+			}},
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package runctl
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func duration(value time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: value}
+}
+
+func Test_getTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		settings      *api.TenantRunSettings
+		timeout       *metav1.Duration
+		expected      time.Duration
+		expectedError string
+	}{
+		{"NoSettings", nil, nil, time.Hour, ""},
+		{"NoSettingsWithTimeout", nil, duration(10 * time.Minute), 10 * time.Minute, ""},
+		{"NoSettingsTimeoutTooLong", nil, duration(2 * time.Hour), 0, "timeout '2h0m0s' in spec.timeout exceeds the maximum timeout '1h0m0s' of the tenant"},
+		{"DefaultTimeout", &api.TenantRunSettings{DefaultTimeout: duration(20 * time.Minute)}, nil, 20 * time.Minute, ""},
+		{"DefaultTimeoutCapped", &api.TenantRunSettings{DefaultTimeout: duration(2 * time.Hour)}, nil, time.Hour, ""},
+		{"MaxTimeout", &api.TenantRunSettings{MaxTimeout: duration(30 * time.Minute)}, nil, 30 * time.Minute, ""},
+		{"MaxTimeoutWithTimeout", &api.TenantRunSettings{MaxTimeout: duration(30 * time.Minute)}, duration(20 * time.Minute), 20 * time.Minute, ""},
+		{"MaxTimeoutCapped", &api.TenantRunSettings{MaxTimeout: duration(3 * time.Hour)}, nil, time.Hour, ""},
+		{"MaxTimeoutCappedWithTimeout", &api.TenantRunSettings{MaxTimeout: duration(3 * time.Hour)}, duration(2 * time.Hour), 0, "timeout '2h0m0s' in spec.timeout exceeds the maximum timeout '1h0m0s' of the tenant"},
+		{"TimeoutTooLong", &api.TenantRunSettings{MaxTimeout: duration(10 * time.Minute)}, duration(11 * time.Minute), 0, "timeout '11m0s' in spec.timeout exceeds the maximum timeout '10m0s' of the tenant"},
+		{"TimeoutNotPositive", nil, duration(0), 0, "timeout '0s' in spec.timeout must be positive"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			config := &runConfigImpl{tenantRunSettings: tc.settings}
+
+			// EXERCISE
+			timeout, err := getTimeout(&api.PipelineSpec{Timeout: tc.timeout}, config, time.Hour)
+
+			// VERIFY
+			if tc.expectedError != "" {
+				assert.Error(t, err, tc.expectedError)
+			} else {
+				assert.NilError(t, err)
+				assert.Equal(t, tc.expected, timeout)
+			}
+		})
+	}
+}
+
+func Test_getLogging(t *testing.T) {
+	runLogging := &api.Logging{Elasticsearch: &api.Elasticsearch{RunID: &api.CustomJSON{}}}
+	tenantLogging := &api.Logging{}
+	for _, tc := range []struct {
+		name     string
+		spec     *api.Logging
+		tenant   *api.Logging
+		expected *api.Logging
+	}{
+		{"None", nil, nil, nil},
+		{"RunOnly", runLogging, nil, runLogging},
+		{"TenantOnly", nil, tenantLogging, tenantLogging},
+		{"RunOverridesTenant", runLogging, tenantLogging, runLogging},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			config := &runConfigImpl{tenantRunSettings: &api.TenantRunSettings{Logging: tc.tenant}}
+
+			// EXERCISE
+			result := getLogging(&api.PipelineSpec{Logging: tc.spec}, config)
+
+			// VERIFY
+			assert.Assert(t, tc.expected == result)
+		})
+	}
+}
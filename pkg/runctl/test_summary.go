@@ -0,0 +1,86 @@
+package runctl
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	errors "github.com/pkg/errors"
+)
+
+const (
+	// testReportsConfigMapName is the name of the ConfigMap in the run
+	// namespace the Jenkinsfile Runner writes JUnit XML reports to.
+	// Each data entry with suffix '.xml' is a report.
+	testReportsConfigMapName = "steward-test-reports"
+
+	// maxFailedTests is the maximum number of failed test names in a
+	// test summary.
+	maxFailedTests = 10
+)
+
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string    `xml:"name,attr"`
+	ClassName string    `xml:"classname,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+}
+
+// summarizeTestReports creates a summary of JUnit XML reports keyed by
+// file name. Test cases with failures or errors count as failed.
+func summarizeTestReports(reports map[string]string) (*api.TestSummary, error) {
+	names := []string{}
+	for name := range reports {
+		if strings.HasSuffix(name, ".xml") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	summary := &api.TestSummary{}
+	for _, name := range names {
+		var suite junitSuite
+		if err := xml.Unmarshal([]byte(reports[name]), &suite); err != nil {
+			return nil, errors.WithMessagef(err, "could not parse test report '%s'", name)
+		}
+		addTestSuite(summary, &suite)
+	}
+	return summary, nil
+}
+
+func addTestSuite(summary *api.TestSummary, suite *junitSuite) {
+	for i := range suite.Suites {
+		addTestSuite(summary, &suite.Suites[i])
+	}
+	for _, testCase := range suite.Cases {
+		summary.Total++
+		switch {
+		case testCase.Failure != nil || testCase.Error != nil:
+			summary.Failed++
+			if len(summary.FailedTests) < maxFailedTests {
+				summary.FailedTests = append(summary.FailedTests, testCase.fullName())
+			}
+		case testCase.Skipped != nil:
+			summary.Skipped++
+		default:
+			summary.Passed++
+		}
+	}
+}
+
+func (c *junitCase) fullName() string {
+	if c.ClassName == "" {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
@@ -0,0 +1,77 @@
+package runctl
+
+import (
+	"fmt"
+	"testing"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func Test_summarizeTestReports_NoReports(t *testing.T) {
+	// EXERCISE
+	summary, err := summarizeTestReports(map[string]string{"README.txt": "foo"})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Assert(t, summary == nil)
+}
+
+func Test_summarizeTestReports_CountsTestCases(t *testing.T) {
+	// SETUP
+	reports := map[string]string{
+		"a.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="suite1">
+    <testcase classname="pkg.Test1" name="passes" time="0.1"/>
+    <testcase classname="pkg.Test1" name="fails"><failure message="expected 1">trace</failure></testcase>
+    <testcase classname="pkg.Test1" name="errors"><error message="boom"/></testcase>
+  </testsuite>
+  <testsuite name="suite2">
+    <testsuite name="nested">
+      <testcase name="skipped"><skipped/></testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>`,
+		"b.xml": `<testsuite name="suite3"><testcase classname="pkg.Test2" name="passes"/></testsuite>`,
+	}
+
+	// EXERCISE
+	summary, err := summarizeTestReports(reports)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &api.TestSummary{
+		Total:       5,
+		Passed:      2,
+		Failed:      2,
+		Skipped:     1,
+		FailedTests: []string{"pkg.Test1.fails", "pkg.Test1.errors"},
+	}, summary)
+}
+
+func Test_summarizeTestReports_LimitsFailedTests(t *testing.T) {
+	// SETUP
+	report := "<testsuite>"
+	for i := 0; i < maxFailedTests+5; i++ {
+		report += fmt.Sprintf(`<testcase name="test%d"><failure/></testcase>`, i)
+	}
+	report += "</testsuite>"
+
+	// EXERCISE
+	summary, err := summarizeTestReports(map[string]string{"report.xml": report})
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, int32(maxFailedTests+5), summary.Failed)
+	assert.Equal(t, maxFailedTests, len(summary.FailedTests))
+}
+
+func Test_summarizeTestReports_InvalidReport(t *testing.T) {
+	// EXERCISE
+	_, err := summarizeTestReports(map[string]string{"report.xml": "<testsuite>"})
+
+	// VERIFY
+	assert.Assert(t, is.Regexp(`could not parse test report 'report.xml': .*`, err.Error()))
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/SAP/stewardci-core/pkg/controllerconfig"
 	"gotest.tools/assert/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -17,6 +18,11 @@ import (
 	k8sScheme "k8s.io/client-go/kubernetes/scheme"
 )
 
+// newTestConfig returns the default controller configuration
+func newTestConfig() *controllerconfig.Config {
+	return controllerconfig.NewConfig(30 * time.Second)
+}
+
 // fixIndent removes common leading whitespace from all lines
 // and replaces all tabs by spaces
 func fixIndent(s string) (out string) {
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// DefaultWorkqueueTimeout is the default time after which a workqueue
+// probe fails if no item has been processed while items are queued.
+const DefaultWorkqueueTimeout = 5 * time.Minute
+
+// InformersSynced returns a check failing until all given informers
+// have synced.
+func InformersSynced(synced ...cache.InformerSynced) Check {
+	return func() error {
+		for _, hasSynced := range synced {
+			if !hasSynced() {
+				return fmt.Errorf("informer caches not synced")
+			}
+		}
+		return nil
+	}
+}
+
+// WorkqueueProbe checks whether the items of a workqueue get processed.
+// Workers must call Processed whenever they finished processing an item.
+type WorkqueueProbe struct {
+	queue   workqueue.Interface
+	timeout time.Duration
+	// lastActivity is the time in Unix nanoseconds an item was processed
+	// or the queue was found empty
+	lastActivity int64
+	// active is 1 once workers process the queue
+	active int32
+}
+
+// NewWorkqueueProbe creates a probe for the given workqueue. The probe
+// fails if the queue is shut down or, once activated, if no item has been
+// processed within the timeout while items are queued.
+func NewWorkqueueProbe(queue workqueue.Interface, timeout time.Duration) *WorkqueueProbe {
+	return &WorkqueueProbe{
+		queue:        queue,
+		timeout:      timeout,
+		lastActivity: time.Now().UnixNano(),
+	}
+}
+
+// Activate records that workers started to process the queue. Before, a
+// filled queue is not considered a failure, e.g. for controllers not
+// being the leader.
+func (p *WorkqueueProbe) Activate() {
+	atomic.StoreInt64(&p.lastActivity, time.Now().UnixNano())
+	atomic.StoreInt32(&p.active, 1)
+}
+
+// Processed records that an item has been processed.
+func (p *WorkqueueProbe) Processed() {
+	atomic.StoreInt64(&p.lastActivity, time.Now().UnixNano())
+}
+
+// Check is the Check of the probe.
+func (p *WorkqueueProbe) Check() error {
+	if p.queue.ShuttingDown() {
+		return fmt.Errorf("workqueue is shut down")
+	}
+	if atomic.LoadInt32(&p.active) == 0 {
+		return nil
+	}
+	now := time.Now()
+	length := p.queue.Len()
+	if length == 0 {
+		atomic.StoreInt64(&p.lastActivity, now.UnixNano())
+		return nil
+	}
+	idle := now.Sub(time.Unix(0, atomic.LoadInt64(&p.lastActivity)))
+	if idle > p.timeout {
+		return fmt.Errorf("no workqueue item processed for %s while %d items are queued", idle.Round(time.Second), length)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func Test_InformersSynced(t *testing.T) {
+	synced := func() bool { return true }
+	notSynced := func() bool { return false }
+
+	assert.NilError(t, InformersSynced(synced, synced)())
+	assert.Error(t, InformersSynced(synced, cache.InformerSynced(notSynced))(), "informer caches not synced")
+}
+
+func Test_WorkqueueProbe_EmptyQueue(t *testing.T) {
+	// SETUP
+	queue := workqueue.New()
+	examinee := NewWorkqueueProbe(queue, 0)
+
+	// EXERCISE
+	err := examinee.Check()
+
+	// VERIFY
+	assert.NilError(t, err)
+}
+
+func Test_WorkqueueProbe_ItemsNotProcessed(t *testing.T) {
+	// SETUP
+	queue := workqueue.New()
+	queue.Add("item1")
+	examinee := NewWorkqueueProbe(queue, time.Millisecond)
+	examinee.Activate()
+	time.Sleep(2 * time.Millisecond)
+
+	// EXERCISE
+	err := examinee.Check()
+
+	// VERIFY
+	assert.ErrorContains(t, err, "while 1 items are queued")
+}
+
+func Test_WorkqueueProbe_NotActivated(t *testing.T) {
+	// SETUP
+	queue := workqueue.New()
+	queue.Add("item1")
+	examinee := NewWorkqueueProbe(queue, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	// EXERCISE
+	err := examinee.Check()
+
+	// VERIFY
+	assert.NilError(t, err)
+}
+
+func Test_WorkqueueProbe_ItemsProcessed(t *testing.T) {
+	// SETUP
+	queue := workqueue.New()
+	queue.Add("item1")
+	examinee := NewWorkqueueProbe(queue, time.Hour)
+	examinee.Activate()
+	examinee.Processed()
+
+	// EXERCISE
+	err := examinee.Check()
+
+	// VERIFY
+	assert.NilError(t, err)
+}
+
+func Test_WorkqueueProbe_ShutDown(t *testing.T) {
+	// SETUP
+	queue := workqueue.New()
+	examinee := NewWorkqueueProbe(queue, time.Hour)
+	queue.ShutDown()
+
+	// EXERCISE
+	err := examinee.Check()
+
+	// VERIFY
+	assert.Error(t, err, "workqueue is shut down")
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// DefaultAddress is the default listen address of the server.
+const DefaultAddress = ":9090"
+
+// shutdownTimeout is the time given to active requests to complete
+// when the server is shut down.
+const shutdownTimeout = 5 * time.Second
+
+// Check is a health check returning an error if unhealthy.
+type Check func() error
+
+// Server serves the metrics and the health endpoints of a controller.
+// Metrics are registered on a registry owned by the server and provided
+// at `/metrics`. Liveness checks are provided at `/healthz`, readiness
+// checks at `/readyz`.
+type Server struct {
+	address         string
+	registry        *prometheus.Registry
+	mutex           sync.RWMutex
+	livenessChecks  map[string]Check
+	readinessChecks map[string]Check
+	logger          *zap.SugaredLogger
+}
+
+// NewServer creates a new server listening on the given address.
+func NewServer(address string, logger *zap.SugaredLogger) *Server {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGoCollector())
+	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	return &Server{
+		address:         address,
+		registry:        registry,
+		livenessChecks:  map[string]Check{},
+		readinessChecks: map[string]Check{},
+		logger:          logger,
+	}
+}
+
+// Registry returns the registry metrics must be registered at.
+func (s *Server) Registry() prometheus.Registerer {
+	return s.registry
+}
+
+// AddLivenessCheck adds a check to the `/healthz` endpoint.
+func (s *Server) AddLivenessCheck(name string, check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.livenessChecks[name] = check
+}
+
+// AddReadinessCheck adds a check to the `/readyz` endpoint.
+func (s *Server) AddReadinessCheck(name string, check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.readinessChecks[name] = check
+}
+
+// Handler returns the HTTP handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		s.serveChecks(w, s.livenessChecks)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s.serveChecks(w, s.readinessChecks)
+	})
+	return mux
+}
+
+// Run serves HTTP requests until the stop channel is closed and shuts
+// down the server gracefully afterwards.
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	httpServer := &http.Server{
+		Addr:    s.address,
+		Handler: s.Handler(),
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Infow("Server listening", "address", s.address)
+		errCh <- httpServer.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-stopCh:
+	}
+	s.logger.Info("Shutting down server")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return httpServer.Shutdown(ctx)
+}
+
+func (s *Server) serveChecks(w http.ResponseWriter, checks map[string]Check) {
+	s.mutex.RLock()
+	names := make([]string, 0, len(checks))
+	checksCopy := make(map[string]Check, len(checks))
+	for name, check := range checks {
+		names = append(names, name)
+		checksCopy[name] = check
+	}
+	s.mutex.RUnlock()
+	sort.Strings(names)
+
+	failures := []string{}
+	for _, name := range names {
+		if err := checksCopy[name](); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if len(failures) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(failures, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
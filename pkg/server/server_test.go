@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := ioutil.ReadAll(recorder.Result().Body)
+	assert.NilError(t, err)
+	return recorder.Code, string(body)
+}
+
+func Test_Server_Metrics_ProvidesRegisteredMetrics(t *testing.T) {
+	// SETUP
+	examinee := NewServer(DefaultAddress, zap.NewNop().Sugar())
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "steward_test_total_count",
+		Help: "test counter",
+	})
+	assert.NilError(t, examinee.Registry().Register(counter))
+	counter.Inc()
+
+	// EXERCISE
+	code, body := get(t, examinee.Handler(), "/metrics")
+
+	// VERIFY
+	assert.Equal(t, http.StatusOK, code)
+	assert.Assert(t, is.Contains(body, "steward_test_total_count 1"))
+}
+
+func Test_Server_SeparateRegistries(t *testing.T) {
+	// SETUP
+	counter := func() prometheus.Collector {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "steward_test_total_count",
+			Help: "test counter",
+		})
+	}
+
+	// EXERCISE
+	err1 := NewServer(DefaultAddress, zap.NewNop().Sugar()).Registry().Register(counter())
+	err2 := NewServer(DefaultAddress, zap.NewNop().Sugar()).Registry().Register(counter())
+
+	// VERIFY
+	assert.NilError(t, err1)
+	assert.NilError(t, err2)
+}
+
+func Test_Server_Healthz(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		check        Check
+		expectedCode int
+		expectedBody string
+	}{
+		{"healthy", func() error { return nil }, http.StatusOK, "ok\n"},
+		{"unhealthy", func() error { return fmt.Errorf("err1") }, http.StatusServiceUnavailable, "check1: err1\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// SETUP
+			examinee := NewServer(DefaultAddress, zap.NewNop().Sugar())
+			examinee.AddLivenessCheck("check1", tc.check)
+			examinee.AddReadinessCheck("check2", func() error { return fmt.Errorf("not ready") })
+
+			// EXERCISE
+			code, body := get(t, examinee.Handler(), "/healthz")
+
+			// VERIFY
+			assert.Equal(t, tc.expectedCode, code)
+			assert.Equal(t, tc.expectedBody, body)
+		})
+	}
+}
+
+func Test_Server_Readyz_ReportsAllFailures(t *testing.T) {
+	// SETUP
+	examinee := NewServer(DefaultAddress, zap.NewNop().Sugar())
+	examinee.AddReadinessCheck("b", func() error { return fmt.Errorf("err2") })
+	examinee.AddReadinessCheck("a", func() error { return fmt.Errorf("err1") })
+	examinee.AddReadinessCheck("c", func() error { return nil })
+
+	// EXERCISE
+	code, body := get(t, examinee.Handler(), "/readyz")
+
+	// VERIFY
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "a: err1\nb: err2\n", body)
+}
+
+func Test_Server_Run_ShutsDownOnStop(t *testing.T) {
+	// SETUP
+	examinee := NewServer("127.0.0.1:0", zap.NewNop().Sugar())
+	stopCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- examinee.Run(stopCh)
+	}()
+
+	// EXERCISE
+	close(stopCh)
+
+	// VERIFY
+	select {
+	case err := <-errCh:
+		assert.NilError(t, err)
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
@@ -0,0 +1,385 @@
+package sharding
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	coordination "k8s.io/api/coordination/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+)
+
+// labelShardGroup is the label of the member Leases identifying the
+// group of replicas sharing the work.
+const labelShardGroup = "steward.sap.com/shard-group"
+
+// staleLeaseFactor is the multiple of the lease duration after which the
+// Lease of a member which stopped renewing it is deleted by the other
+// members. Such Leases are left over by crashed replicas.
+const staleLeaseFactor = 4
+
+// Config is the configuration of the sharding.
+type Config struct {
+	// Enabled defines whether the work is sharded across replicas.
+	// If disabled, the replica owns all keys.
+	Enabled bool
+	// Group is the name of the group of replicas sharing the work
+	Group string
+	// LeaseNamespace is the namespace of the member Leases
+	LeaseNamespace string
+	// LeaseDuration is the time after the last renewal a member Lease
+	// is considered expired
+	LeaseDuration time.Duration
+	// RenewInterval is the interval in which the own member Lease is
+	// renewed and the members are updated
+	RenewInterval time.Duration
+	// Identity is the identity of the replica. Defaults to the host
+	// name with a unique suffix.
+	Identity string
+}
+
+// NewConfig returns the default configuration for the given group.
+func NewConfig(group string) *Config {
+	return &Config{
+		Group:          group,
+		LeaseNamespace: "steward-system",
+		LeaseDuration:  15 * time.Second,
+		RenewInterval:  5 * time.Second,
+	}
+}
+
+// AddFlags adds command line flags for the configuration to the given
+// flag set.
+func (c *Config) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.BoolVar(&c.Enabled, "sharding", c.Enabled, "shard the work across all replicas instead of electing a leader")
+	flagSet.StringVar(&c.LeaseNamespace, "sharding-namespace", c.LeaseNamespace, "namespace of the Lease objects of the shard members")
+	flagSet.DurationVar(&c.LeaseDuration, "sharding-lease-duration", c.LeaseDuration, "time after which a shard member without renewal is considered gone")
+	flagSet.DurationVar(&c.RenewInterval, "sharding-renew-interval", c.RenewInterval, "interval in which shard members renew their Lease and update the members")
+}
+
+// member is an observed member Lease.
+type member struct {
+	renewTime  metav1.MicroTime
+	observedAt time.Time
+}
+
+// Shards assigns keys of namespaced objects to the members of a group of
+// replicas. Each member holds a Lease. Keys are assigned by rendezvous
+// hashing of their namespace, so that only the keys of a joining or
+// leaving member are reassigned.
+// A joining member only takes over keys after the other members had the
+// chance to observe it, so that keys are not processed by two members at
+// the same time. A member which cannot renew its Lease owns no keys.
+type Shards struct {
+	client    coordinationv1beta1.LeasesGetter
+	config    Config
+	identity  string
+	leaseName string
+	logger    *zap.SugaredLogger
+	clock     func() time.Time
+
+	mutex     sync.RWMutex
+	observed  map[string]*member
+	members   []string
+	synced    bool
+	joinedAt  time.Time
+	renewedAt time.Time
+	listeners []func()
+}
+
+// NewSingleShard returns shards owning all keys.
+func NewSingleShard() *Shards {
+	return &Shards{}
+}
+
+// NewShards creates new shards for the given configuration. If sharding
+// is disabled, all keys are owned.
+func NewShards(client coordinationv1beta1.LeasesGetter, config *Config, logger *zap.SugaredLogger) (*Shards, error) {
+	if !config.Enabled {
+		return NewSingleShard(), nil
+	}
+	identity := config.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		identity = strings.ToLower(hostname) + "-" + rand.String(5)
+	}
+	leaseName := config.Group + "-" + identity
+	if errs := validation.IsDNS1123Subdomain(leaseName); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid shard member Lease name '%s': %s", leaseName, strings.Join(errs, ", "))
+	}
+	return &Shards{
+		client:    client,
+		config:    *config,
+		identity:  identity,
+		leaseName: leaseName,
+		logger:    logger.With("identity", identity),
+		clock:     time.Now,
+		observed:  map[string]*member{},
+	}, nil
+}
+
+// OnChange registers a function called after the keys owned have changed.
+func (s *Shards) OnChange(listener func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// HasSynced returns whether the members are known.
+func (s *Shards) HasSynced() bool {
+	if s.client == nil {
+		return true
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.synced
+}
+
+// Owns returns whether the object with the given key (namespace/name) is
+// owned by this replica.
+func (s *Shards) Owns(key string) bool {
+	if s.client == nil {
+		return true
+	}
+	namespace := strings.SplitN(key, "/", 2)[0]
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.clock().Sub(s.renewedAt) > s.config.LeaseDuration {
+		return false
+	}
+	return ownerOf(namespace, s.members) == s.identity
+}
+
+// Run holds the member Lease of this replica and updates the members
+// until the stop channel is closed. The Lease is deleted afterwards, so
+// that the other members take over immediately.
+func (s *Shards) Run(stopCh <-chan struct{}) error {
+	if s.client == nil {
+		<-stopCh
+		return nil
+	}
+	if err := s.join(); err != nil {
+		return err
+	}
+	s.logger.Infow("Joined shard group", "group", s.config.Group)
+	wait.Until(s.update, s.config.RenewInterval, stopCh)
+	return s.leave()
+}
+
+func (s *Shards) join() error {
+	now := s.clock()
+	lease := &coordination.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.leaseName,
+			Namespace: s.config.LeaseNamespace,
+			Labels:    map[string]string{labelShardGroup: s.config.Group},
+		},
+		Spec: s.leaseSpec(now),
+	}
+	if _, err := s.client.Leases(s.config.LeaseNamespace).Create(lease); err != nil {
+		return errors.WithMessagef(err, "could not create shard member Lease '%s'", s.leaseName)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.joinedAt = now
+	s.renewedAt = now
+	return nil
+}
+
+func (s *Shards) leave() error {
+	s.mutex.Lock()
+	s.members = nil
+	s.mutex.Unlock()
+	err := s.client.Leases(s.config.LeaseNamespace).Delete(s.leaseName, &metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.WithMessagef(err, "could not delete shard member Lease '%s'", s.leaseName)
+	}
+	s.logger.Infow("Left shard group", "group", s.config.Group)
+	return nil
+}
+
+func (s *Shards) leaseSpec(now time.Time) coordination.LeaseSpec {
+	holder := s.identity
+	durationSeconds := int32(s.config.LeaseDuration.Seconds())
+	renewTime := metav1.NewMicroTime(now)
+	return coordination.LeaseSpec{
+		HolderIdentity:       &holder,
+		LeaseDurationSeconds: &durationSeconds,
+		RenewTime:            &renewTime,
+	}
+}
+
+// update renews the own Lease, updates the members and deletes the
+// Leases of members gone for long.
+func (s *Shards) update() {
+	if err := s.renew(); err != nil {
+		s.logger.Warnw("Failed to renew shard member Lease", "lease", s.leaseName, "error", err)
+	}
+	list, err := s.client.Leases(s.config.LeaseNamespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{labelShardGroup: s.config.Group}).String(),
+	})
+	if err != nil {
+		s.logger.Warnw("Failed to list shard member Leases", "error", err)
+		return
+	}
+	s.updateMembers(list.Items)
+	s.deleteStaleLeases(list.Items)
+}
+
+// renew renews the own Lease. If the Lease has been deleted by the other
+// members in the meantime, this replica joins again.
+func (s *Shards) renew() error {
+	leases := s.client.Leases(s.config.LeaseNamespace)
+	lease, err := leases.Get(s.leaseName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		s.logger.Infow("Shard member Lease has been deleted, joining again", "lease", s.leaseName)
+		return s.join()
+	}
+	if err != nil {
+		return err
+	}
+	now := s.clock()
+	lease.Spec = s.leaseSpec(now)
+	if _, err = leases.Update(lease); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.renewedAt = now
+	return nil
+}
+
+func (s *Shards) updateMembers(leases []coordination.Lease) {
+	s.mutex.Lock()
+	now := s.clock()
+	observed := map[string]*member{}
+	for _, lease := range leases {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil {
+			continue
+		}
+		identity := *lease.Spec.HolderIdentity
+		previous := s.observed[identity]
+		if previous != nil && previous.renewTime.Equal(lease.Spec.RenewTime) {
+			observed[identity] = previous
+		} else {
+			observed[identity] = &member{renewTime: *lease.Spec.RenewTime, observedAt: now}
+		}
+	}
+	s.observed = observed
+
+	members := []string{}
+	for identity, member := range observed {
+		if identity != s.identity && now.Sub(member.observedAt) <= s.config.LeaseDuration {
+			members = append(members, identity)
+		}
+	}
+	// this replica only takes over keys once the other members had the
+	// chance to observe it, and gives up all keys if its Lease may have
+	// expired in the view of the other members
+	joinDelay := 2 * s.config.RenewInterval
+	if now.Sub(s.joinedAt) >= joinDelay && now.Sub(s.renewedAt) <= s.config.LeaseDuration {
+		members = append(members, s.identity)
+	}
+	sort.Strings(members)
+
+	changed := !s.synced || !equal(members, s.members)
+	s.members = members
+	s.synced = true
+	listeners := s.listeners
+	s.mutex.Unlock()
+
+	if changed {
+		s.logger.Infow("Shard members changed", "members", members)
+		for _, listener := range listeners {
+			listener()
+		}
+	}
+}
+
+// deleteStaleLeases deletes the Leases of other members which have not
+// been renewed for longer than staleLeaseFactor times the lease duration.
+// As for the expiry of members, the renewals are timed with the own clock.
+func (s *Shards) deleteStaleLeases(leases []coordination.Lease) {
+	s.mutex.RLock()
+	now := s.clock()
+	stale := []coordination.Lease{}
+	for _, lease := range leases {
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == s.identity {
+			continue
+		}
+		member := s.observed[*lease.Spec.HolderIdentity]
+		if member != nil && now.Sub(member.observedAt) > staleLeaseFactor*s.config.LeaseDuration {
+			stale = append(stale, lease)
+		}
+	}
+	s.mutex.RUnlock()
+
+	for _, lease := range stale {
+		uid := lease.GetUID()
+		err := s.client.Leases(s.config.LeaseNamespace).Delete(lease.GetName(), &metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			s.logger.Warnw("Failed to delete stale shard member Lease", "lease", lease.GetName(), "error", err)
+			continue
+		}
+		s.logger.Infow("Deleted stale shard member Lease", "lease", lease.GetName())
+	}
+}
+
+// ownerOf returns the member owning the given namespace using rendezvous
+// hashing, or an empty string if there are no members.
+func ownerOf(namespace string, members []string) string {
+	owner := ""
+	var maxWeight uint64
+	for _, member := range members {
+		hash := fnv.New64a()
+		hash.Write([]byte(member))
+		hash.Write([]byte{0})
+		hash.Write([]byte(namespace))
+		if weight := mix(hash.Sum64()); owner == "" || weight > maxWeight {
+			owner = member
+			maxWeight = weight
+		}
+	}
+	return owner
+}
+
+// mix applies the finalizer of MurmurHash3 to the given hash, so that
+// similar member names and namespaces result in unrelated weights.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SAP/stewardci-core/pkg/k8s/fake"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+	coordination "k8s.io/api/coordination/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const waitTimeout = 5 * time.Second
+
+func newTestConfig(identity string) *Config {
+	config := NewConfig("group1")
+	config.Enabled = true
+	config.LeaseNamespace = "ns1"
+	config.LeaseDuration = time.Minute
+	config.RenewInterval = 10 * time.Millisecond
+	config.Identity = identity
+	return config
+}
+
+func newTestLease(identity string, renewTime time.Time) *coordination.Lease {
+	holder := identity
+	microTime := metav1.NewMicroTime(renewTime)
+	return &coordination.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "group1-" + identity,
+			Namespace: "ns1",
+			Labels:    map[string]string{labelShardGroup: "group1"},
+		},
+		Spec: coordination.LeaseSpec{HolderIdentity: &holder, RenewTime: &microTime},
+	}
+}
+
+// newTestShards returns shards with a clock controlled by the test which
+// joined at the given time.
+func newTestShards(t *testing.T, identity string, now *time.Time) *Shards {
+	t.Helper()
+	examinee, err := NewShards(fake.NewClientFactory().CoordinationV1beta1(), newTestConfig(identity), zap.NewNop().Sugar())
+	assert.NilError(t, err)
+	examinee.clock = func() time.Time { return *now }
+	examinee.joinedAt = *now
+	examinee.renewedAt = *now
+	return examinee
+}
+
+func waitFor(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_NewShards_Disabled_OwnsAll(t *testing.T) {
+	// SETUP
+	config := newTestConfig("member1")
+	config.Enabled = false
+
+	// EXERCISE
+	examinee, err := NewShards(fake.NewClientFactory().CoordinationV1beta1(), config, zap.NewNop().Sugar())
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Assert(t, examinee.HasSynced())
+	assert.Assert(t, examinee.Owns("ns1/run1"))
+}
+
+func Test_NewShards_InvalidIdentity(t *testing.T) {
+	// EXERCISE
+	_, err := NewShards(fake.NewClientFactory().CoordinationV1beta1(), newTestConfig("Member_1"), zap.NewNop().Sugar())
+
+	// VERIFY
+	assert.ErrorContains(t, err, "invalid shard member Lease name 'group1-Member_1'")
+}
+
+func Test_ownerOf_ReassignsOnlyKeysOfChangedMember(t *testing.T) {
+	// SETUP
+	members := []string{"member1", "member2", "member3"}
+	owners := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		namespace := fmt.Sprintf("ns%d", i)
+		owners[namespace] = ownerOf(namespace, members)
+		counts[owners[namespace]]++
+	}
+
+	// EXERCISE
+	remaining := []string{"member1", "member3"}
+
+	// VERIFY
+	for _, member := range members {
+		assert.Assert(t, counts[member] > 50, "member %s owns only %d keys", member, counts[member])
+	}
+	for namespace, owner := range owners {
+		if owner != "member2" {
+			assert.Equal(t, owner, ownerOf(namespace, remaining))
+		}
+	}
+	assert.Equal(t, "", ownerOf("ns1", nil))
+}
+
+func Test_Shards_updateMembers_JoinDelay(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	examinee := newTestShards(t, "member1", &now)
+	leases := []coordination.Lease{*newTestLease("member1", now), *newTestLease("member2", now)}
+	changes := 0
+	examinee.OnChange(func() { changes++ })
+
+	// EXERCISE
+	examinee.updateMembers(leases)
+
+	// VERIFY
+	assert.Assert(t, examinee.HasSynced())
+	assert.DeepEqual(t, []string{"member2"}, examinee.members)
+	assert.Assert(t, !examinee.Owns("ns1/run1"))
+	assert.Equal(t, 1, changes)
+
+	// EXERCISE
+	now = now.Add(2 * examinee.config.RenewInterval)
+	examinee.renewedAt = now
+	examinee.updateMembers(leases)
+
+	// VERIFY
+	assert.DeepEqual(t, []string{"member1", "member2"}, examinee.members)
+	assert.Equal(t, 2, changes)
+}
+
+func Test_Shards_updateMembers_ExpiresMembers(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	examinee := newTestShards(t, "member1", &now)
+	// the renew time of other members is not compared with the own clock
+	leases := []coordination.Lease{*newTestLease("member2", now.Add(-time.Hour))}
+	examinee.updateMembers(leases)
+	assert.DeepEqual(t, []string{"member2"}, examinee.members)
+
+	// EXERCISE
+	now = now.Add(examinee.config.LeaseDuration + time.Second)
+	examinee.renewedAt = now
+	examinee.updateMembers(leases)
+
+	// VERIFY
+	assert.DeepEqual(t, []string{"member1"}, examinee.members)
+	assert.Assert(t, examinee.Owns("ns1/run1"))
+}
+
+func Test_Shards_updateMembers_OwnsNothingIfNotRenewed(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	examinee := newTestShards(t, "member1", &now)
+
+	// EXERCISE
+	now = now.Add(examinee.config.LeaseDuration + time.Second)
+	examinee.updateMembers([]coordination.Lease{*newTestLease("member1", now)})
+
+	// VERIFY
+	assert.DeepEqual(t, []string{}, examinee.members)
+	assert.Assert(t, !examinee.Owns("ns1/run1"))
+}
+
+func Test_Shards_Run_JoinsAndLeaves(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory()
+	leases := cf.CoordinationV1beta1().Leases("ns1")
+	examinee, err := NewShards(cf.CoordinationV1beta1(), newTestConfig("member1"), zap.NewNop().Sugar())
+	assert.NilError(t, err)
+	stopCh := make(chan struct{})
+	result := make(chan error, 1)
+
+	// EXERCISE
+	go func() { result <- examinee.Run(stopCh) }()
+
+	// VERIFY
+	waitFor(t, func() bool { return examinee.Owns("ns1/run1") }, "shard not taken over")
+	lease, err := leases.Get("group1-member1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "member1", *lease.Spec.HolderIdentity)
+
+	// EXERCISE
+	close(stopCh)
+
+	// VERIFY
+	select {
+	case err = <-result:
+		assert.NilError(t, err)
+	case <-time.After(waitTimeout):
+		t.Fatal("shards not stopped")
+	}
+	assert.Assert(t, !examinee.Owns("ns1/run1"))
+	_, err = leases.Get("group1-member1", metav1.GetOptions{})
+	assert.Assert(t, k8serrors.IsNotFound(err))
+}
+
+func Test_Shards_update_DeletesStaleLeases(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	examinee := newTestShards(t, "member1", &now)
+	leases := examinee.client.Leases("ns1")
+	for _, identity := range []string{"member1", "member2", "member3"} {
+		_, err := leases.Create(newTestLease(identity, now))
+		assert.NilError(t, err)
+	}
+	examinee.update()
+
+	// member3 keeps renewing, member2 crashed
+	now = now.Add(staleLeaseFactor*examinee.config.LeaseDuration + time.Second)
+	lease, err := leases.Get("group1-member3", metav1.GetOptions{})
+	assert.NilError(t, err)
+	renewTime := metav1.NewMicroTime(now)
+	lease.Spec.RenewTime = &renewTime
+	_, err = leases.Update(lease)
+	assert.NilError(t, err)
+
+	// EXERCISE
+	examinee.update()
+
+	// VERIFY
+	_, err = leases.Get("group1-member2", metav1.GetOptions{})
+	assert.Assert(t, k8serrors.IsNotFound(err))
+	_, err = leases.Get("group1-member3", metav1.GetOptions{})
+	assert.NilError(t, err)
+	_, err = leases.Get("group1-member1", metav1.GetOptions{})
+	assert.NilError(t, err)
+}
+
+func Test_Shards_renew_LeaseDeleted_JoinsAgain(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	examinee := newTestShards(t, "member1", &now)
+	now = now.Add(time.Minute)
+
+	// EXERCISE
+	err := examinee.renew()
+
+	// VERIFY
+	assert.NilError(t, err)
+	lease, err := examinee.client.Leases("ns1").Get("group1-member1", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "member1", *lease.Spec.HolderIdentity)
+	assert.Equal(t, now, examinee.joinedAt)
+}
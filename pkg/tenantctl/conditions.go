@@ -0,0 +1,56 @@
+package tenantctl
+
+import (
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resultsByReason maps the reasons of the Ready condition to the legacy
+// results of a tenant
+var resultsByReason = map[string]api.TenantResult{
+	api.TenantReasonPreparing:    api.TenantResultUndefined,
+	api.TenantReasonPrepared:     api.TenantResultSuccess,
+	api.TenantReasonInfraError:   api.TenantResultErrorInfra,
+	api.TenantReasonContentError: api.TenantResultErrorContent,
+}
+
+// getReadyCondition returns the Ready condition of the tenant, or nil if
+// it is not set.
+func getReadyCondition(tenant *api.Tenant) *api.TenantCondition {
+	for i := range tenant.Status.Conditions {
+		if tenant.Status.Conditions[i].Type == api.TenantConditionReady {
+			return &tenant.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setReadyCondition sets the Ready condition of the tenant and marks the
+// generation of the tenant as observed. The legacy result and message
+// are derived from the condition. The transition time is only updated if
+// the status of the condition changes.
+// It returns whether the status of the tenant has changed.
+func setReadyCondition(tenant *api.Tenant, status v1.ConditionStatus, reason string, message string) bool {
+	result := resultsByReason[reason]
+	condition := getReadyCondition(tenant)
+	if condition != nil && condition.Status == status && condition.Reason == reason && condition.Message == message &&
+		tenant.Status.ObservedGeneration == tenant.GetGeneration() &&
+		tenant.Status.Result == result && tenant.Status.Message == message {
+		return false
+	}
+	if condition == nil {
+		tenant.Status.Conditions = append(tenant.Status.Conditions, api.TenantCondition{Type: api.TenantConditionReady})
+		condition = &tenant.Status.Conditions[len(tenant.Status.Conditions)-1]
+	}
+	if condition.Status != status {
+		condition.Status = status
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+	tenant.Status.ObservedGeneration = tenant.GetGeneration()
+	tenant.Status.Result = result
+	tenant.Status.Message = message
+	return true
+}
@@ -0,0 +1,83 @@
+package tenantctl
+
+import (
+	"testing"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	assert "gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_setReadyCondition_New(t *testing.T) {
+	// SETUP
+	tenant := fake.Tenant(tenantID1, "TenantName", "Description", ns1)
+	tenant.SetGeneration(3)
+
+	// EXERCISE
+	changed := setReadyCondition(tenant, v1.ConditionFalse, api.TenantReasonInfraError, "message1")
+
+	// VERIFY
+	assert.Assert(t, changed)
+	assert.Equal(t, 1, len(tenant.Status.Conditions))
+	condition := getReadyCondition(tenant)
+	assert.Equal(t, api.TenantConditionReady, condition.Type)
+	assert.Equal(t, v1.ConditionFalse, condition.Status)
+	assert.Equal(t, api.TenantReasonInfraError, condition.Reason)
+	assert.Equal(t, "message1", condition.Message)
+	assert.Assert(t, !condition.LastTransitionTime.IsZero())
+	assert.Equal(t, int64(3), tenant.Status.ObservedGeneration)
+	assert.Equal(t, api.TenantResultErrorInfra, tenant.Status.Result)
+	assert.Equal(t, "message1", tenant.Status.Message)
+}
+
+func Test_setReadyCondition_Unchanged(t *testing.T) {
+	// SETUP
+	tenant := fake.Tenant(tenantID1, "TenantName", "Description", ns1)
+	setReadyCondition(tenant, v1.ConditionTrue, api.TenantReasonPrepared, "message1")
+	expected := tenant.Status.DeepCopy()
+
+	// EXERCISE
+	changed := setReadyCondition(tenant, v1.ConditionTrue, api.TenantReasonPrepared, "message1")
+
+	// VERIFY
+	assert.Assert(t, !changed)
+	assert.DeepEqual(t, *expected, tenant.Status)
+}
+
+func Test_setReadyCondition_SameStatus_KeepsTransitionTime(t *testing.T) {
+	// SETUP
+	transitionTime := metav1.Unix(10, 0)
+	tenant := fake.Tenant(tenantID1, "TenantName", "Description", ns1)
+	tenant.Status.Conditions = []api.TenantCondition{{
+		Type:               api.TenantConditionReady,
+		Status:             v1.ConditionFalse,
+		Reason:             api.TenantReasonInfraError,
+		LastTransitionTime: transitionTime,
+	}}
+
+	// EXERCISE
+	changed := setReadyCondition(tenant, v1.ConditionFalse, api.TenantReasonContentError, "message1")
+
+	// VERIFY
+	assert.Assert(t, changed)
+	assert.Equal(t, 1, len(tenant.Status.Conditions))
+	assert.Equal(t, api.TenantReasonContentError, tenant.Status.Conditions[0].Reason)
+	assert.Equal(t, transitionTime, tenant.Status.Conditions[0].LastTransitionTime)
+	assert.Equal(t, api.TenantResultErrorContent, tenant.Status.Result)
+}
+
+func Test_setReadyCondition_GenerationChanged(t *testing.T) {
+	// SETUP
+	tenant := fake.Tenant(tenantID1, "TenantName", "Description", ns1)
+	setReadyCondition(tenant, v1.ConditionTrue, api.TenantReasonPrepared, "message1")
+	tenant.SetGeneration(2)
+
+	// EXERCISE
+	changed := setReadyCondition(tenant, v1.ConditionTrue, api.TenantReasonPrepared, "message1")
+
+	// VERIFY
+	assert.Assert(t, changed)
+	assert.Equal(t, int64(2), tenant.Status.ObservedGeneration)
+}
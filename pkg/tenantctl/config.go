@@ -14,6 +14,8 @@ type clientConfig interface {
 	GetTenantNamespacePrefix() string
 	GetTenantNamespaceSuffixLength() uint8
 	GetTenantRoleName() k8s.RoleName
+	GetTenantResourceQuotaTemplate() string
+	GetTenantLimitRangeTemplate() string
 }
 
 const (
@@ -25,6 +27,8 @@ type clientConfigImpl struct {
 	tenantNamespacePrefix       string
 	tenantNamespaceSuffixLength int64
 	tenantRoleName              k8s.RoleName
+	tenantResourceQuotaTemplate string
+	tenantLimitRangeTemplate    string
 }
 
 // getClientConfig returns the configurartion of the Steward client.
@@ -76,6 +80,10 @@ func getClientConfig(factory k8s.ClientFactory, clientNamespace string) (clientC
 		}
 		newConfig.tenantNamespaceSuffixLength = i
 	}
+
+	newConfig.tenantResourceQuotaTemplate = annotations[steward.AnnotationTenantResourceQuota]
+	newConfig.tenantLimitRangeTemplate = annotations[steward.AnnotationTenantLimitRange]
+
 	return &newConfig, nil
 }
 
@@ -96,3 +104,17 @@ func (c *clientConfigImpl) GetTenantNamespaceSuffixLength() uint8 {
 func (c *clientConfigImpl) GetTenantRoleName() k8s.RoleName {
 	return c.tenantRoleName
 }
+
+// GetTenantResourceQuotaTemplate returns the name of the resource quota in
+// the client namespace to be copied to tenant namespaces, or an empty
+// string if tenant namespaces should not get a resource quota.
+func (c *clientConfigImpl) GetTenantResourceQuotaTemplate() string {
+	return c.tenantResourceQuotaTemplate
+}
+
+// GetTenantLimitRangeTemplate returns the name of the limit range in
+// the client namespace to be copied to tenant namespaces, or an empty
+// string if tenant namespaces should not get a limit range.
+func (c *clientConfigImpl) GetTenantLimitRangeTemplate() string {
+	return c.tenantLimitRangeTemplate
+}
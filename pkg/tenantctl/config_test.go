@@ -79,6 +79,33 @@ func Test_getClientConfig_ReturnsValuesFromAnnotations(t *testing.T) {
 	assert.Equal(t, "testprefix", prefix)
 	tenantRole := config.GetTenantRoleName()
 	assert.Equal(t, "testrole", string(tenantRole))
+	assert.Equal(t, "", config.GetTenantResourceQuotaTemplate())
+	assert.Equal(t, "", config.GetTenantLimitRangeTemplate())
+}
+
+func Test_getClientConfig_ResourceLimitTemplates(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "Client1",
+				Annotations: map[string]string{
+					"steward.sap.com/tenant-namespace-prefix": "testprefix",
+					"steward.sap.com/tenant-role":             "testrole",
+					"steward.sap.com/tenant-resource-quota":   "quota1",
+					"steward.sap.com/tenant-limit-range":      "limits1",
+				},
+			},
+		},
+	)
+
+	// EXERCISE
+	config, err := getClientConfig(cf, "Client1")
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, "quota1", config.GetTenantResourceQuotaTemplate())
+	assert.Equal(t, "limits1", config.GetTenantLimitRangeTemplate())
 }
 
 func Test_getClientConfig_NamespaceParameterIsZeroLengthString(t *testing.T) {
@@ -5,39 +5,60 @@ based on sample-controller from https://github.com/kubernetes/sample-controller/
 package tenantctl
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	listers "github.com/SAP/stewardci-core/pkg/client/listers/steward/v1alpha1"
 	k8s "github.com/SAP/stewardci-core/pkg/k8s"
+	logging "github.com/SAP/stewardci-core/pkg/logging"
+	server "github.com/SAP/stewardci-core/pkg/server"
+	"github.com/SAP/stewardci-core/pkg/tracing"
 	utils "github.com/SAP/stewardci-core/pkg/utils"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
 	v1beta1 "k8s.io/api/rbac/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	wait "k8s.io/apimachinery/pkg/util/wait"
 	cache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 	workqueue "k8s.io/client-go/util/workqueue"
 )
 
 const kind = "Tenants"
 const defaultServiceAccountName = "default"
 
+// Resources of prepared tenants reported as drift if they are recreated
+const (
+	driftNamespace     = "namespace"
+	driftRoleBinding   = "rolebinding"
+	driftResourceQuota = "resourcequota"
+	driftLimitRange    = "limitrange"
+)
+
 // Controller for Steward
 type Controller struct {
-	factory      k8s.ClientFactory
-	fetcher      k8s.TenantFetcher
-	tenantSynced cache.InformerSynced
-	tenantLister listers.TenantLister
-	workqueue    workqueue.RateLimitingInterface
-	metrics      Metrics
-	syncCount    int64
+	factory        k8s.ClientFactory
+	fetcher        k8s.TenantFetcher
+	tenantSynced   cache.InformerSynced
+	tenantLister   listers.TenantLister
+	workqueue      workqueue.RateLimitingInterface
+	workqueueProbe *server.WorkqueueProbe
+	metrics        Metrics
+	loggers        *logging.Loggers
+	logger         *zap.SugaredLogger
+	scope          *k8s.Scope
+	syncCount      int64
 }
 
 // NewController creates new Controller
-func NewController(factory k8s.ClientFactory, fetcher k8s.TenantFetcher, metrics Metrics) *Controller {
+func NewController(factory k8s.ClientFactory, fetcher k8s.TenantFetcher, metrics Metrics, loggers *logging.Loggers, scope *k8s.Scope) *Controller {
 	informer := factory.StewardInformerFactory().Steward().V1alpha1().Tenants()
 	controller := &Controller{
 		factory:      factory,
@@ -46,7 +67,11 @@ func NewController(factory k8s.ClientFactory, fetcher k8s.TenantFetcher, metrics
 		tenantLister: informer.Lister(),
 		workqueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), kind),
 		metrics:      metrics,
+		loggers:      loggers,
+		logger:       loggers.Component(logging.ComponentTenantController),
+		scope:        scope,
 	}
+	controller.workqueueProbe = server.NewWorkqueueProbe(controller.workqueue, server.DefaultWorkqueueTimeout)
 	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    controller.addTenant,
 		UpdateFunc: controller.updateTenant,
@@ -59,14 +84,14 @@ func (c *Controller) getSyncCount() int64 {
 	return c.syncCount
 }
 
-func (c *Controller) getNamespaceManager(tenant *api.Tenant) (k8s.NamespaceManager, error) {
+func (c *Controller) getNamespaceManager(loggers *logging.Loggers, tenant *api.Tenant) (k8s.NamespaceManager, error) {
 	config, err := getClientConfig(c.factory, tenant.GetNamespace())
 	if err != nil {
 		return nil, err
 	}
 	tenantNamespacePrefix := config.GetTenantNamespacePrefix()
 	namespaceManager := k8s.NewNamespaceManager(c.factory, tenantNamespacePrefix,
-		config.GetTenantNamespaceSuffixLength())
+		config.GetTenantNamespaceSuffixLength(), loggers.Component(logging.ComponentNamespaceManager))
 	return namespaceManager, nil
 }
 
@@ -74,20 +99,31 @@ func (c *Controller) getNamespaceManager(tenant *api.Tenant) (k8s.NamespaceManag
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
-	log.Printf("Sync cache")
-	if ok := cache.WaitForCacheSync(stopCh, c.tenantSynced); !ok {
+	c.logger.Info("Sync cache")
+	if ok := cache.WaitForCacheSync(stopCh, c.tenantSynced, c.scope.HasSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
-	log.Printf("Start workers")
+	c.logger.Info("Start workers")
+	c.workqueueProbe.Activate()
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
-	log.Printf("Workers running [%v]", threadiness)
+	c.logger.Infow("Workers running", "threadiness", threadiness)
 	<-stopCh
-	log.Printf("Workers stopped")
+	c.logger.Info("Workers stopped")
 	return nil
 }
 
+// CheckReadiness returns an error if the informer cache is not synced
+func (c *Controller) CheckReadiness() error {
+	return server.InformersSynced(c.tenantSynced, c.scope.HasSynced)()
+}
+
+// CheckLiveness returns an error if the workqueue is not processed
+func (c *Controller) CheckLiveness() error {
+	return c.workqueueProbe.Check()
+}
+
 func (c *Controller) runWorker() {
 	for c.processNextWorkItem() {
 	}
@@ -103,7 +139,7 @@ func (c *Controller) processNextWorkItem() bool {
 
 	numRequeues := c.workqueue.NumRequeues(obj)
 	if numRequeues > 0 {
-		log.Printf("Requeued %v times '%s'", numRequeues, obj.(string))
+		c.logger.Infow("Requeued", logging.KeyTenant, obj, "requeues", numRequeues)
 	}
 
 	// We wrap this block in a func so we can defer c.workqueue.Done.
@@ -115,6 +151,7 @@ func (c *Controller) processNextWorkItem() bool {
 		// put back on the workqueue and attempted again after a back-off
 		// period.
 		defer c.workqueue.Done(obj)
+		defer c.workqueueProbe.Processed()
 		var key string
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -141,7 +178,7 @@ func (c *Controller) processNextWorkItem() bool {
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
-		log.Printf("Finished syncing '%s'", key)
+		c.logger.Debugw("Finished syncing", logging.KeyTenant, key)
 		return nil
 	}(obj)
 
@@ -156,7 +193,14 @@ func (c *Controller) processNextWorkItem() bool {
 // syncHandler compares the actual state with the desired, and attempts to
 // converge the two. It then updates the Status block of the tenant resource
 // with the current status of the resource.
-func (c *Controller) syncHandler(key string) error {
+func (c *Controller) syncHandler(key string) (err error) {
+	ctx, span := tracing.Start(context.Background(), "reconcile Tenant", tracing.KeyTenant.String(key))
+	defer func() { tracing.End(span, err) }()
+	loggers := c.loggers.With(logging.KeyTenant, key, logging.KeyReconcileID, logging.NewReconcileID())
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		loggers = loggers.With(logging.KeyTraceID, traceID)
+	}
+	logger := loggers.Component(logging.ComponentTenantController)
 	tenant, err := c.fetcher.ByKey(key)
 	if err != nil {
 		return err
@@ -165,93 +209,144 @@ func (c *Controller) syncHandler(key string) error {
 	if tenant == nil {
 		return nil
 	}
+	if tenant.Status.TenantNamespaceName != "" {
+		loggers = loggers.With(logging.KeyTenantNamespace, tenant.Status.TenantNamespaceName)
+		logger = loggers.Component(logging.ComponentTenantController)
+	}
 
 	// Check if object has deletion timestamp
 	// If not, try to add finalizer if missing
 	if tenant.ObjectMeta.DeletionTimestamp.IsZero() {
-		changed, finalizerList := utils.AddStringIfMissing(tenant.ObjectMeta.Finalizers, k8s.FinalizerName)
+		changed, _ := utils.AddStringIfMissing(tenant.ObjectMeta.Finalizers, k8s.FinalizerName)
 		if changed {
-			tenant.ObjectMeta.Finalizers = finalizerList
-			_, err = c.update(tenant)
+			_, err = c.update(logger, tenant, func(tenant *api.Tenant) {
+				_, tenant.ObjectMeta.Finalizers = utils.AddStringIfMissing(tenant.ObjectMeta.Finalizers, k8s.FinalizerName)
+			})
 			return err
 		}
 	} else {
-		err := c.rollback(tenant)
+		err := c.rollback(ctx, loggers, tenant)
 		if err != nil {
-			log.Printf("ERROR: Deletion of NS %s failed: %v", tenant.Status.TenantNamespaceName, err.Error())
+			logger.Errorw("Deletion of tenant namespace failed", "error", err)
 			return err
 		}
-		err = c.removeFinalizer(tenant)
+		err = c.removeFinalizer(logger, tenant)
 		if err == nil {
 			c.syncCount++
 		}
 		return err
 	}
 
-	if tenant.Status.Progress != api.TenantProgressUndefined && tenant.Status.Progress != api.TenantProgressFinished {
-		//TODO: We need to handle this resiliently, not exit
-		err := fmt.Errorf("Tenant '%s' in namespace '%s' seems to have failed previously in step '%s'", tenant.GetName(), tenant.GetNamespace(), tenant.Status.Progress)
-		log.Printf("ERROR: %s", err.Error())
-		return nil //err <- TODO: as long as we do not fix the error state it does not make sense to retry
+	// All steps are idempotent, so that the preparation can resume from
+	// any progress left by a previous attempt. If a step fails, the tenant
+	// namespace is kept and the tenant is retried with backoff.
+	// Prepared tenants are checked on every resync, and resources deleted
+	// in the meantime are recreated.
+	prepared := tenant.Status.Progress == api.TenantProgressFinished
+	if !prepared && tenant.Status.Progress != api.TenantProgressUndefined {
+		logger.Infow("Resume tenant preparation", "progress", tenant.Status.Progress)
+	}
+	if !prepared && getReadyCondition(tenant) == nil {
+		setReadyCondition(tenant, v1.ConditionFalse, api.TenantReasonPreparing, "Tenant namespace is being prepared")
+	}
+	var drift []string
+	reportDrift := func(resource string) {
+		logger.Warnw("Recreated missing resource of prepared tenant", "resource", resource)
+		c.metrics.CountDrift(resource)
+		drift = append(drift, resource)
+	}
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressInProcess); err != nil {
+		return err
 	}
 
-	defer c.rollbackIfRequired(key)
+	config, err := getClientConfig(c.factory, tenant.GetNamespace())
+	if err != nil {
+		logger.Errorw("Could not get config", "error", err)
+		return err
+	}
+	tenantRoleName := config.GetTenantRoleName()
 
-	// Check if tenant setup is completed
-	if tenant.Status.Progress != api.TenantProgressFinished {
-		tenant, _ = c.updateProgress(tenant, api.TenantProgressInProcess)
-		var err error
-		var namespaceName string
-		var account *k8s.ServiceAccountWrap
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressCreateNamespace); err != nil {
+		return err
+	}
+	tenant, namespaceCreated, err := c.ensureNamespace(ctx, loggers, tenant)
+	if err != nil {
+		return c.handleError(logger, tenant, err, api.TenantReasonContentError)
+	}
+	namespaceName := tenant.Status.TenantNamespaceName
+	logger = logger.With(logging.KeyTenantNamespace, namespaceName)
+	span.SetAttributes(tracing.KeyTenantNamespace.String(namespaceName))
+	// the resources in a recreated namespace are not reported on their own
+	if prepared && namespaceCreated {
+		reportDrift(driftNamespace)
+	}
+	checkDrift := prepared && !namespaceCreated
 
-		config, err := getClientConfig(c.factory, tenant.GetNamespace())
-		if err != nil {
-			log.Printf("ERROR: Could not get config: %s", err.Error())
-			return err
-		}
-		tenantRoleName := config.GetTenantRoleName()
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressGetServiceAccount); err != nil {
+		return err
+	}
+	account, err := c.getServiceAccount(ctx, logger, tenant, defaultServiceAccountName)
+	if err != nil {
+		return c.handleError(logger, tenant, err, api.TenantReasonInfraError)
+	}
 
-		//TODO: handle updateProgress errors
-		tenant, _ = c.updateProgress(tenant, api.TenantProgressCreateNamespace)
-		namespaceName, err = c.createNamespace(tenant)
-		if err != nil {
-			return c.handleError(tenant, err, api.TenantResultErrorContent)
-		}
-		log.Printf("Create namespace successful for %s", namespaceName)
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressAddRoleBinding); err != nil {
+		return err
+	}
+	roleBindingCreated, err := c.addRoleBinding(ctx, logger, account, tenant, tenantRoleName)
+	if err != nil {
+		return c.handleError(logger, tenant, err, api.TenantReasonInfraError)
+	}
+	if checkDrift && roleBindingCreated {
+		reportDrift(driftRoleBinding)
+	}
 
-		tenant, _ = c.updateProgress(tenant, api.TenantProgressGetServiceAccount)
-		account, err = c.getServiceAccount(tenant, defaultServiceAccountName)
-		if err != nil {
-			return c.handleError(tenant, err, api.TenantResultErrorInfra)
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressApplyResourceLimits); err != nil {
+		return err
+	}
+	limitsCreated, err := c.applyResourceLimits(ctx, logger, tenant, config)
+	if err != nil {
+		reason := api.TenantReasonInfraError
+		if isTemplateNotFound(err) {
+			reason = api.TenantReasonContentError
 		}
-
-		tenant, _ = c.updateProgress(tenant, api.TenantProgressAddRoleBinding)
-		var roleBinding *v1beta1.RoleBinding
-		roleBinding, err = c.addRoleBinding(account, tenant, tenantRoleName)
-		if err != nil {
-			return c.handleError(tenant, err, api.TenantResultErrorInfra)
+		return c.handleError(logger, tenant, err, reason)
+	}
+	if checkDrift {
+		for _, resource := range limitsCreated {
+			reportDrift(resource)
 		}
-		log.Printf("Created Role Binding '%s' in namespace '%s'", roleBinding.GetName(), namespaceName)
+	}
+	if err = validateRunSettings(tenant.Spec.Runs); err != nil {
+		return c.handleError(logger, tenant, err, api.TenantReasonContentError)
+	}
+	if err = c.applyNamespaceAnnotations(logger, tenant); err != nil {
+		return c.handleError(logger, tenant, err, api.TenantReasonInfraError)
+	}
 
-		tenant, _ = c.updateProgress(tenant, api.TenantProgressFinalize)
-		tenant.Status.Result = api.TenantResultSuccess
-		tenant.Status.Message = "Tenant namespace successfully prepared"
-		if tenant, err = c.updateStatus(tenant); err != nil {
-			return err
-		}
-		tenant, _ = c.updateProgress(tenant, api.TenantProgressFinished)
-		log.Printf("Tenant preparation successful for %s", tenant.GetName())
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressFinalize); err != nil {
+		return err
+	}
+	if tenant, err = c.updateResult(logger, tenant, drift); err != nil {
+		return err
+	}
+	if tenant, err = c.updateProgress(logger, tenant, api.TenantProgressFinished); err != nil {
+		return err
+	}
+	if !prepared {
+		logger.Info("Tenant preparation successful")
 	}
 	c.updateMetrics()
 	c.syncCount++
 	return nil
 }
 
-func (c *Controller) removeFinalizer(tenant *api.Tenant) error {
-	changed, finalizerList := utils.RemoveString(tenant.ObjectMeta.Finalizers, k8s.FinalizerName)
+func (c *Controller) removeFinalizer(logger *zap.SugaredLogger, tenant *api.Tenant) error {
+	changed, _ := utils.RemoveString(tenant.ObjectMeta.Finalizers, k8s.FinalizerName)
 	if changed {
-		tenant.ObjectMeta.Finalizers = finalizerList
-		_, err := c.update(tenant)
+		_, err := c.update(logger, tenant, func(tenant *api.Tenant) {
+			_, tenant.ObjectMeta.Finalizers = utils.RemoveString(tenant.ObjectMeta.Finalizers, k8s.FinalizerName)
+		})
 		if err != nil {
 			return err
 		}
@@ -259,86 +354,204 @@ func (c *Controller) removeFinalizer(tenant *api.Tenant) error {
 	return nil
 }
 
-func (c *Controller) updateProgress(tenant *api.Tenant, progress api.TenantCreationProgress) (*api.Tenant, error) {
+// progressSteps lists the progress values of the tenant preparation in
+// their order.
+var progressSteps = []api.TenantCreationProgress{
+	api.TenantProgressUndefined,
+	api.TenantProgressInProcess,
+	api.TenantProgressCreateNamespace,
+	api.TenantProgressGetServiceAccount,
+	api.TenantProgressAddRoleBinding,
+	api.TenantProgressApplyResourceLimits,
+	api.TenantProgressFinalize,
+	api.TenantProgressFinished,
+}
+
+// progressIndex returns the position of the given progress in the tenant
+// preparation, or -1 if the progress is unknown.
+func progressIndex(progress api.TenantCreationProgress) int {
+	for i, step := range progressSteps {
+		if step == progress {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateProgress advances the progress of the tenant to the given step.
+// Steps repeated while resuming a preparation do not move the progress
+// back, so that the status is only written if the preparation advances.
+func (c *Controller) updateProgress(logger *zap.SugaredLogger, tenant *api.Tenant, progress api.TenantCreationProgress) (*api.Tenant, error) {
+	if progressIndex(progress) <= progressIndex(tenant.Status.Progress) {
+		return tenant, nil
+	}
+	logger.Debugw("Update progress", "progress", progress)
 	tenant.Status.Progress = progress
-	return c.updateStatus(tenant)
+	return c.updateStatus(logger, tenant)
+}
+
+// updateResult sets the Ready condition of the prepared tenant. If
+// resources of a prepared tenant had to be recreated, they are listed in
+// the message. This message is kept by subsequent successful
+// reconciliations.
+func (c *Controller) updateResult(logger *zap.SugaredLogger, tenant *api.Tenant, drift []string) (*api.Tenant, error) {
+	message := "Tenant namespace successfully prepared"
+	if len(drift) > 0 {
+		message = fmt.Sprintf("Tenant namespace repaired, recreated %s", strings.Join(drift, ", "))
+	} else if ready := getReadyCondition(tenant); ready != nil && ready.Status == v1.ConditionTrue {
+		message = ready.Message
+	}
+	if !setReadyCondition(tenant, v1.ConditionTrue, api.TenantReasonPrepared, message) {
+		return tenant, nil
+	}
+	return c.updateStatus(logger, tenant)
 }
 
-func (c *Controller) updateStatus(tenant *api.Tenant) (*api.Tenant, error) {
+// updateStatus writes the status of the given tenant. If the tenant has
+// been modified concurrently or has been fetched from an outdated cache,
+// the status is written to the latest version of the tenant.
+func (c *Controller) updateStatus(logger *zap.SugaredLogger, tenant *api.Tenant) (*api.Tenant, error) {
 	client := c.factory.StewardV1alpha1().Tenants(tenant.GetNamespace())
-	updatedTenant, err := client.UpdateStatus(tenant)
+	status := tenant.Status
+	var updatedTenant *api.Tenant
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		var err error
+		tenant.Status = status
+		updatedTenant, err = client.UpdateStatus(tenant)
+		if k8serrors.IsConflict(err) {
+			latest, getErr := client.Get(tenant.GetName(), metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			tenant = latest
+		}
+		return err
+	})
 	if err != nil {
 		err = errors.WithMessagef(err, "Failed to update status of tenant '%s' in namespace '%s'", tenant.GetName(), tenant.GetNamespace())
-		log.Printf("ERROR: %s", err.Error())
+		logger.Errorw("Failed to update status of tenant", "error", err)
 		return nil, err
 	}
+	c.fetcher.Updated(updatedTenant)
 	return updatedTenant, nil
 }
 
-func (c *Controller) update(tenant *api.Tenant) (*api.Tenant, error) {
+// update applies the given change to the tenant and writes it. If the
+// tenant has been modified concurrently or has been fetched from an
+// outdated cache, the change is applied to the latest version of the
+// tenant.
+func (c *Controller) update(logger *zap.SugaredLogger, tenant *api.Tenant, change func(*api.Tenant)) (*api.Tenant, error) {
 	client := c.factory.StewardV1alpha1().Tenants(tenant.GetNamespace())
-	updatedTenant, err := client.Update(tenant)
+	var updatedTenant *api.Tenant
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		var err error
+		change(tenant)
+		updatedTenant, err = client.Update(tenant)
+		if k8serrors.IsConflict(err) {
+			latest, getErr := client.Get(tenant.GetName(), metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			tenant = latest
+		}
+		return err
+	})
 	if err != nil {
 		err = errors.WithMessagef(err, "Failed to update tenant '%s' in namespace '%s'", tenant.GetName(), tenant.GetNamespace())
-		log.Printf("ERROR: %s", err.Error())
+		logger.Errorw("Failed to update tenant", "error", err)
 		return nil, err
 	}
+	c.fetcher.Updated(updatedTenant)
 	return updatedTenant, nil
 }
 
-// An error is returned in cases to signalize processNextWorkItem() to retry processing the tenant.
-// If no error is returned this signalized OK, do not retry. This should be done in cases where retry will not help.
-func (c *Controller) handleError(tenant *api.Tenant, err error, result api.TenantResult) error {
-	log.Printf("ERROR: %s", err.Error())
-	tenant.Status.Result = result
-	tenant.Status.Message = utils.Trim(err.Error())
-	_, updateStatusErr := c.updateStatus(tenant)
-	return updateStatusErr
-}
-
-func (c *Controller) rollbackIfRequired(tenantKey string) {
-	tenant, err := c.fetcher.ByKey(tenantKey)
-	if err != nil {
-		log.Printf("ERROR: Could not get tenant during rollback: %s", err.Error())
-	}
-	if tenant.Status.Progress != api.TenantProgressFinished {
-		_ = c.rollback(tenant)
+// handleError sets the Ready condition of the tenant to the given error
+// and returns the error, so that processNextWorkItem() retries the
+// preparation with backoff. The status is only written if it changes, as
+// each write triggers another reconciliation without backoff.
+func (c *Controller) handleError(logger *zap.SugaredLogger, tenant *api.Tenant, err error, reason string) error {
+	logger.Errorw("Tenant preparation failed", "reason", reason, "error", err)
+	if setReadyCondition(tenant, v1.ConditionFalse, reason, utils.Trim(err.Error())) {
+		if _, updateStatusErr := c.updateStatus(logger, tenant); updateStatusErr != nil {
+			return updateStatusErr
+		}
 	}
+	return err
 }
 
-func (c *Controller) rollback(tenant *api.Tenant) error {
-	log.Printf("Rollback tenant %s", tenant.GetName())
+func (c *Controller) rollback(ctx context.Context, loggers *logging.Loggers, tenant *api.Tenant) (err error) {
+	ctx, span := tracing.Start(ctx, "rollback tenant")
+	defer func() { tracing.End(span, err) }()
+	logger := loggers.Component(logging.ComponentTenantController)
+	logger.Info("Rollback tenant")
 	if tenant.Status.TenantNamespaceName == "" {
-		log.Printf("Nothing to rollback for tenant %s", tenant.GetName())
+		logger.Info("Nothing to rollback for tenant")
 	} else {
-		err := c.deleteNamespace(tenant)
+		err = c.deleteNamespace(ctx, loggers, tenant)
 		if err != nil {
-			log.Printf("ERROR: Deletion of %s failed: %v", tenant.Status.TenantNamespaceName, err.Error())
+			logger.Errorw("Deletion of tenant namespace failed", "error", err)
 			return err
 		}
 	}
 	return nil
 }
 
-func (c *Controller) deleteNamespace(tenant *api.Tenant) error {
-	namespaceManager, err := c.getNamespaceManager(tenant)
+func (c *Controller) deleteNamespace(ctx context.Context, loggers *logging.Loggers, tenant *api.Tenant) error {
+	namespaceManager, err := c.getNamespaceManager(loggers, tenant)
 	if err != nil {
 		err = errors.WithMessage(err, "Could not delete namespace")
 		return err
 	}
-	return namespaceManager.Delete(tenant.Status.TenantNamespaceName)
+	return namespaceManager.Delete(ctx, tenant.Status.TenantNamespaceName)
 }
 
-func (c *Controller) createNamespace(tenant *api.Tenant) (string, error) {
-	log.Printf("Create namespace for: %s", tenant.GetName())
-	annotations := map[string]string{}
-	namespaceManager, err := c.getNamespaceManager(tenant)
+// ensureNamespace makes sure that the tenant namespace exists and returns
+// whether it has been created. The namespace created by a previous attempt
+// is reused. If there is none, a new namespace is created and stored in
+// the status immediately, so that subsequent attempts reuse it.
+func (c *Controller) ensureNamespace(ctx context.Context, loggers *logging.Loggers, tenant *api.Tenant) (*api.Tenant, bool, error) {
+	logger := loggers.Component(logging.ComponentTenantController)
+	if name := tenant.Status.TenantNamespaceName; name != "" {
+		namespace, err := c.factory.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+		if err == nil {
+			if namespace.GetDeletionTimestamp() != nil {
+				return tenant, false, errors.Errorf("Tenant namespace '%s' is being deleted", name)
+			}
+			return tenant, false, nil
+		}
+		if !k8serrors.IsNotFound(err) {
+			return tenant, false, errors.WithMessagef(err, "Get namespace failed for tenant %s:", tenant.GetName())
+		}
+		logger.Infow("Tenant namespace does not exist anymore", "namespace", name)
+	}
+	if _, err := c.createNamespace(ctx, loggers, tenant); err != nil {
+		return tenant, false, err
+	}
+	updatedTenant, err := c.updateStatus(logger, tenant)
+	if err != nil {
+		// a namespace not stored in the status would never be deleted
+		if deleteErr := c.deleteNamespace(ctx, loggers, tenant); deleteErr != nil {
+			logger.Errorw("Deletion of tenant namespace failed", "error", deleteErr)
+		}
+		tenant.Status.TenantNamespaceName = ""
+		return tenant, false, err
+	}
+	logger.Infow("Created tenant namespace", "namespace", updatedTenant.Status.TenantNamespaceName)
+	return updatedTenant, true, nil
+}
+
+func (c *Controller) createNamespace(ctx context.Context, loggers *logging.Loggers, tenant *api.Tenant) (string, error) {
+	loggers.Component(logging.ComponentTenantController).Info("Create namespace")
+	annotations := map[string]string{
+		api.AnnotationClientNamespace: tenant.GetNamespace(),
+	}
+	namespaceManager, err := c.getNamespaceManager(loggers, tenant)
 	if err != nil {
 		err = errors.WithMessage(err, "Could not get namespace manager")
 		return "", err
 	}
 
-	fullName, err := namespaceManager.Create(tenant.GetName(), annotations)
+	fullName, err := namespaceManager.Create(ctx, tenant.GetName(), annotations)
 	if err == nil {
 		tenant.Status.TenantNamespaceName = fullName
 	} else {
@@ -347,38 +560,111 @@ func (c *Controller) createNamespace(tenant *api.Tenant) (string, error) {
 	return fullName, err
 }
 
-func (c *Controller) getServiceAccount(tenant *api.Tenant, serviceAccountName string) (*k8s.ServiceAccountWrap, error) {
-	log.Printf("Get service account %s for: %s", serviceAccountName, tenant.GetName())
+func (c *Controller) getServiceAccount(ctx context.Context, logger *zap.SugaredLogger, tenant *api.Tenant, serviceAccountName string) (*k8s.ServiceAccountWrap, error) {
+	logger.Infow("Get service account", "serviceAccount", serviceAccountName)
 	accountManager := k8s.NewServiceAccountManager(c.factory, tenant.GetNamespace())
-	account, err := accountManager.GetServiceAccount(serviceAccountName)
+	account, err := accountManager.GetServiceAccount(ctx, serviceAccountName)
 	if err != nil {
 		err = errors.WithMessagef(err, "Fetch service account failed for %s", tenant.Status.TenantNamespaceName)
 	}
 	return account, err
 }
 
-func (c *Controller) addRoleBinding(account *k8s.ServiceAccountWrap, tenant *api.Tenant, role k8s.RoleName) (*v1beta1.RoleBinding, error) {
-	log.Printf("Add role binding to role %s in namespace %s", role, tenant.Status.TenantNamespaceName)
-	roleBinding, err := account.AddRoleBinding(role, tenant.Status.TenantNamespaceName)
-	if err != nil {
-		err = errors.WithMessagef(err, "Add Role Binding to service account failed for %s", tenant.Status.TenantNamespaceName)
-		tenant.Status.Result = api.TenantResultErrorInfra
-		tenant.Status.Message = utils.Trim(err.Error())
-		log.Printf("ERROR: %s", err.Error())
+// addRoleBinding makes sure that the role binding of the service account
+// exists in the tenant namespace and returns whether it has been created.
+func (c *Controller) addRoleBinding(ctx context.Context, logger *zap.SugaredLogger, account *k8s.ServiceAccountWrap, tenant *api.Tenant, role k8s.RoleName) (bool, error) {
+	namespace := tenant.Status.TenantNamespaceName
+	_, err := c.factory.RbacV1beta1().RoleBindings(namespace).Get(string(role), metav1.GetOptions{})
+	if err == nil {
+		return false, nil
+	}
+	if k8serrors.IsNotFound(err) {
+		logger.Infow("Add role binding", "role", role)
+		var roleBinding *v1beta1.RoleBinding
+		roleBinding, err = account.AddRoleBinding(ctx, role, namespace)
+		if err == nil {
+			logger.Infow("Created role binding", "roleBinding", roleBinding.GetName())
+			return true, nil
+		}
+		if k8serrors.IsAlreadyExists(err) {
+			// created concurrently
+			return false, nil
+		}
+	}
+	return false, errors.WithMessagef(err, "Add Role Binding to service account failed for %s", namespace)
+}
+
+// applyResourceLimits copies the resource quota and limit range templates
+// configured for the client to the tenant namespace, unless they exist
+// already. It returns the types of the resources created.
+func (c *Controller) applyResourceLimits(ctx context.Context, logger *zap.SugaredLogger, tenant *api.Tenant, config clientConfig) (created []string, err error) {
+	_, span := tracing.Start(ctx, "apply resource limits")
+	defer func() { tracing.End(span, err) }()
+	clientNamespace := tenant.GetNamespace()
+	tenantNamespace := tenant.Status.TenantNamespaceName
+	if name := config.GetTenantResourceQuotaTemplate(); name != "" {
+		_, err = c.factory.CoreV1().ResourceQuotas(tenantNamespace).Get(name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if err = k8s.CopyResourceQuota(c.factory, name, clientNamespace, tenantNamespace); err == nil {
+				logger.Infow("Created resource quota", "resourceQuota", name)
+				created = append(created, driftResourceQuota)
+			} else if k8serrors.IsAlreadyExists(errors.Cause(err)) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if name := config.GetTenantLimitRangeTemplate(); name != "" {
+		_, err = c.factory.CoreV1().LimitRanges(tenantNamespace).Get(name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if err = k8s.CopyLimitRange(c.factory, name, clientNamespace, tenantNamespace); err == nil {
+				logger.Infow("Created limit range", "limitRange", name)
+				created = append(created, driftLimitRange)
+			} else if k8serrors.IsAlreadyExists(errors.Cause(err)) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// isTemplateNotFound returns whether the given error of applying the
+// resource limits is caused by a missing template, which is an error in
+// the configuration of the client. Other errors are failures of the API
+// server.
+func isTemplateNotFound(err error) bool {
+	status, ok := errors.Cause(err).(k8serrors.APIStatus)
+	if !ok || !k8serrors.IsNotFound(errors.Cause(err)) {
+		return false
 	}
-	return roleBinding, err
+	// the resources in the tenant namespace are only copied if missing
+	details := status.Status().Details
+	return details != nil && (details.Kind == "resourcequotas" || details.Kind == "limitranges")
 }
 
 func (c *Controller) updateMetrics() {
 	list, err := c.tenantLister.List(labels.Everything())
 	if err != nil {
-		log.Printf("Cannot update tenant metrics: %s", err.Error())
+		c.logger.Warnw("Cannot update tenant metrics", "error", err)
+	}
+	count := 0
+	for _, tenant := range list {
+		if c.scope.Contains(tenant) {
+			count++
+		}
 	}
-	count := len(list)
 	c.metrics.SetTenantNumber(float64(count))
 }
 
 func (c *Controller) addTenant(obj interface{}) {
+	if !c.scope.Contains(obj.(*api.Tenant)) {
+		return
+	}
 	key := c.getKey(obj)
 	c.addToQueue(key, "Add")
 }
@@ -386,6 +672,9 @@ func (c *Controller) addTenant(obj interface{}) {
 func (c *Controller) updateTenant(old, new interface{}) {
 	oldVersion := old.(*api.Tenant).GetObjectMeta().GetResourceVersion()
 	newVersion := new.(*api.Tenant).GetObjectMeta().GetResourceVersion()
+	if !c.scope.Contains(new.(*api.Tenant)) {
+		return
+	}
 	key := c.getKey(new)
 	if oldVersion != newVersion {
 		//changed
@@ -399,9 +688,9 @@ func (c *Controller) updateTenant(old, new interface{}) {
 
 func (c *Controller) addToQueue(key string, eventType string) {
 	if key == "" {
-		log.Printf("WARN: '%s' event - key empty, skipping item", eventType)
+		c.logger.Warnw("Key empty, skipping item", "event", eventType)
 	} else {
-		log.Printf("'%s' event - Add to workqueue '%s'", eventType, key)
+		c.logger.Debugw("Add to workqueue", "event", eventType, logging.KeyTenant, key)
 		c.workqueue.Add(key)
 	}
 }
@@ -419,9 +708,9 @@ func (c *Controller) getKey(obj interface{}) string {
 func (c *Controller) deleteTenant(obj interface{}) {
 	key, err := cache.MetaNamespaceKeyFunc(obj)
 	if err != nil {
-		log.Printf("'Delete' event - could not identify key: %s", err.Error())
+		c.logger.Warnw("Could not identify key", "event", "Delete", "error", err)
 	} else {
-		log.Printf("'Delete' event - '%s'", key)
+		c.logger.Debugw("Tenant deleted", "event", "Delete", logging.KeyTenant, key)
 	}
 	c.updateMetrics()
 }
@@ -9,10 +9,15 @@ import (
 	steward "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
 	k8s "github.com/SAP/stewardci-core/pkg/k8s"
 	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	logging "github.com/SAP/stewardci-core/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	assert "gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
 	v1beta1 "k8s.io/api/rbac/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const ns1 = "clientNamespace1"
@@ -121,7 +126,7 @@ func Test_MissingServiceAccount(t *testing.T) {
 		result:          steward.TenantResultErrorInfra,
 		message:         `serviceaccounts "` + defaultServiceAccountName + `" not found`,
 		prefix:          prefix1,
-		namespaceExists: false,
+		namespaceExists: true,
 	})
 }
 
@@ -177,10 +182,98 @@ func Test_MissingClusterRole(t *testing.T) {
 		result:          steward.TenantResultErrorInfra,
 		message:         `clusterroles.rbac.authorization.k8s.io "` + defaultTenantRoleName + `" not found`,
 		prefix:          prefix1,
-		namespaceExists: false,
+		namespaceExists: true,
 	})
 }
 
+func Test_ResourceLimits(t *testing.T) {
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations(ns1, map[string]string{
+			steward.AnnotationTenantNamespacePrefix: prefix1,
+			steward.AnnotationTenantRole:            defaultTenantRoleName,
+			steward.AnnotationTenantResourceQuota:   "quota1",
+			steward.AnnotationTenantLimitRange:      "limits1",
+		}),
+		&v1.ResourceQuota{ObjectMeta: fake.ObjectMeta("quota1", ns1)},
+		&v1.LimitRange{ObjectMeta: fake.ObjectMeta("limits1", ns1)},
+		defaultServiceAccount,
+		fake.ClusterRole(defaultTenantRoleName),
+		fake.Tenant(tenantID1, "TenantName", "Description", ns1),
+	)
+
+	stopCh, _ := startController(t, cf)
+	defer stopController(t, stopCh)
+
+	assertTenant(t, cf, ns1, tenantID1, expect{
+		result:          steward.TenantResultSuccess,
+		prefix:          prefix1,
+		namespaceExists: true,
+	})
+	tenant, err := cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	_, err = cf.CoreV1().ResourceQuotas(tenant.Status.TenantNamespaceName).Get("quota1", optGet)
+	assert.NilError(t, err)
+	_, err = cf.CoreV1().LimitRanges(tenant.Status.TenantNamespaceName).Get("limits1", optGet)
+	assert.NilError(t, err)
+}
+
+func Test_MissingResourceQuotaTemplate(t *testing.T) {
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations(ns1, map[string]string{
+			steward.AnnotationTenantNamespacePrefix: prefix1,
+			steward.AnnotationTenantRole:            defaultTenantRoleName,
+			steward.AnnotationTenantResourceQuota:   "quota1",
+		}),
+		defaultServiceAccount,
+		fake.ClusterRole(defaultTenantRoleName),
+		fake.Tenant(tenantID1, "TenantName", "Description", ns1),
+	)
+
+	stopCh, _ := startController(t, cf)
+	defer stopController(t, stopCh)
+
+	assertTenant(t, cf, ns1, tenantID1, expect{
+		result:          steward.TenantResultErrorContent,
+		message:         `could not get resource quota template 'quota1' in namespace '` + ns1 + `'`,
+		prefix:          prefix1,
+		namespaceExists: true,
+	})
+}
+
+func Test_Controller_syncHandler_ResourceQuotaCreationFails_InfraError(t *testing.T) {
+	// SETUP
+	namespaceName := prefix1 + "-" + tenantID1
+	cf := fake.NewClientFactory(
+		fake.NamespaceWithAnnotations(ns1, map[string]string{
+			steward.AnnotationTenantNamespacePrefix: prefix1,
+			steward.AnnotationTenantRole:            defaultTenantRoleName,
+			steward.AnnotationTenantResourceQuota:   "quota1",
+		}),
+		&v1.ResourceQuota{ObjectMeta: fake.ObjectMeta("quota1", ns1)},
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		fake.Namespace(namespaceName),
+		newTenantWithStatus(steward.TenantStatus{
+			Progress:            steward.TenantProgressApplyResourceLimits,
+			TenantNamespaceName: namespaceName,
+		}),
+	)
+	cf.KubernetesClientset().PrependReactor("create", "resourcequotas", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewInternalError(fmt.Errorf("error1"))
+	})
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.ErrorContains(t, err, "error1")
+	tenant, err := cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, steward.TenantResultErrorInfra, tenant.Status.Result)
+	assert.Equal(t, steward.TenantReasonInfraError, getReadyCondition(tenant).Reason)
+}
+
 //Test for ERROR: Failed to update status of tenant '4e93d9d5-276e-47ca-a570-b3a763aaef3e' in namespace 'stu':
 //         Operation cannot be fulfilled on tenants.steward.sap.com "4e93d9d5-276e-47ca-a570-b3a763aaef3e":
 //         the object has been modified; please apply your changes to the latest version and try again
@@ -201,14 +294,293 @@ func Test_MultipleUpdateStatusCalls(t *testing.T) {
 	tenant, err := controller.fetcher.ByKey(tenantKey(ns1, tenantID1))
 	assert.Equal(t, tenantID1, tenant.GetName())
 	tenant.Status.Message = "Changed 1"
-	_, err = controller.updateStatus(tenant)
+	_, err = controller.updateStatus(controller.logger, tenant)
 	assert.NilError(t, err)
 	tenant.Status.Result = steward.TenantResultErrorInfra
 	//TODO: This one here should fail since the original object was updated and not the returned one - but it doesn't with the fakes.
-	_, err = controller.updateStatus(tenant)
+	_, err = controller.updateStatus(controller.logger, tenant)
 	//assert.Assert(t, err != nil)
 }
 
+func Test_Controller_updateStatus_Conflict_UpdatesLatestVersion(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(fake.Tenant(tenantID1, "TenantName", "Description", ns1))
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+	tenant, err := controller.fetcher.ByKey(tenantKey(ns1, tenantID1))
+	assert.NilError(t, err)
+	// concurrent modification of the spec
+	modified := tenant.DeepCopy()
+	modified.Spec.DisplayName = "Changed"
+	_, err = cf.StewardV1alpha1().Tenants(ns1).Update(modified)
+	assert.NilError(t, err)
+	conflicts := 0
+	cf.StewardClientset().PrependReactor("update", "tenants", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		return true, nil, k8serrors.NewConflict(steward.Resource("tenants"), tenantID1, fmt.Errorf("modified"))
+	})
+	tenant.Status.Message = "Changed 1"
+
+	// EXERCISE
+	result, err := controller.updateStatus(controller.logger, tenant)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, 1, conflicts)
+	assert.Equal(t, "Changed 1", result.Status.Message)
+	assert.Equal(t, "Changed", result.Spec.DisplayName)
+}
+
+func newClientNamespace() *v1.Namespace {
+	return fake.NamespaceWithAnnotations(ns1, map[string]string{
+		steward.AnnotationTenantNamespacePrefix:       prefix1,
+		steward.AnnotationTenantRole:                  defaultTenantRoleName,
+		steward.AnnotationTenantNamespaceSuffixLength: "0",
+	})
+}
+
+func newTenantWithStatus(status steward.TenantStatus) *steward.Tenant {
+	tenant := fake.Tenant(tenantID1, "TenantName", "Description", ns1)
+	tenant.SetFinalizers([]string{k8s.FinalizerName})
+	tenant.Status = status
+	return tenant
+}
+
+func Test_Controller_syncHandler_ResumesAfterError(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		newTenantWithStatus(steward.TenantStatus{}),
+	)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+	key := tenantKey(ns1, tenantID1)
+	err := controller.syncHandler(key)
+	assert.ErrorContains(t, err, "not found")
+	tenant, err := cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, steward.TenantProgressAddRoleBinding, tenant.Status.Progress)
+	assert.Equal(t, steward.TenantResultErrorInfra, tenant.Status.Result)
+	assert.Equal(t, v1.ConditionFalse, getReadyCondition(tenant).Status)
+	assert.Equal(t, steward.TenantReasonInfraError, getReadyCondition(tenant).Reason)
+	namespaceName := tenant.Status.TenantNamespaceName
+	_, err = cf.RbacV1beta1().ClusterRoles().Create(fakeClusterRole())
+	assert.NilError(t, err)
+
+	// EXERCISE
+	err = controller.syncHandler(key)
+
+	// VERIFY
+	assert.NilError(t, err)
+	tenant, err = cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, steward.TenantProgressFinished, tenant.Status.Progress)
+	assert.Equal(t, steward.TenantResultSuccess, tenant.Status.Result)
+	assert.Equal(t, v1.ConditionTrue, getReadyCondition(tenant).Status)
+	assert.Equal(t, steward.TenantReasonPrepared, getReadyCondition(tenant).Reason)
+	assert.Equal(t, namespaceName, tenant.Status.TenantNamespaceName)
+	_, err = cf.RbacV1beta1().RoleBindings(namespaceName).Get(defaultTenantRoleName, optGet)
+	assert.NilError(t, err)
+}
+
+func Test_Controller_syncHandler_ResumesWithExistingResources(t *testing.T) {
+	// SETUP
+	namespaceName := prefix1 + "-" + tenantID1
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		fake.Namespace(namespaceName),
+		&v1beta1.RoleBinding{ObjectMeta: fake.ObjectMeta(defaultTenantRoleName, namespaceName)},
+		newTenantWithStatus(steward.TenantStatus{
+			Progress:            steward.TenantProgressApplyResourceLimits,
+			TenantNamespaceName: namespaceName,
+		}),
+	)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	tenant, err := cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, steward.TenantProgressFinished, tenant.Status.Progress)
+	assert.Equal(t, steward.TenantResultSuccess, tenant.Status.Result)
+	assert.Equal(t, namespaceName, tenant.Status.TenantNamespaceName)
+}
+
+func Test_Controller_syncHandler_NamespaceMissing_CreatesNamespace(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		newTenantWithStatus(steward.TenantStatus{
+			Progress:            steward.TenantProgressGetServiceAccount,
+			TenantNamespaceName: "prefix1-deleted",
+		}),
+	)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	assertTenant(t, cf, ns1, tenantID1, expect{
+		result:              steward.TenantResultSuccess,
+		prefix:              prefix1,
+		namespaceExists:     true,
+		namespaceStartsWith: prefix1 + "-" + tenantID1,
+	})
+}
+
+func newPreparedTenant(namespaceName string) *steward.Tenant {
+	return newTenantWithStatus(steward.TenantStatus{
+		Conditions: []steward.TenantCondition{{
+			Type:    steward.TenantConditionReady,
+			Status:  v1.ConditionTrue,
+			Reason:  steward.TenantReasonPrepared,
+			Message: "Tenant namespace successfully prepared",
+		}},
+		Progress:            steward.TenantProgressFinished,
+		Result:              steward.TenantResultSuccess,
+		Message:             "Tenant namespace successfully prepared",
+		TenantNamespaceName: namespaceName,
+	})
+}
+
+func Test_Controller_syncHandler_PreparedTenantUnchanged_KeepsStatus(t *testing.T) {
+	// SETUP
+	namespaceName := prefix1 + "-" + tenantID1
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		fake.Namespace(namespaceName),
+		&v1beta1.RoleBinding{ObjectMeta: fake.ObjectMeta(defaultTenantRoleName, namespaceName)},
+		newPreparedTenant(namespaceName),
+	)
+	metrics := NewMetrics().(*metrics)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), metrics, logging.NewNop(), k8s.NewClusterScope())
+	actions := len(cf.StewardClientset().Actions())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	for _, action := range cf.StewardClientset().Actions()[actions:] {
+		assert.Assert(t, action.GetVerb() != "update", "unexpected update of %s", action.GetSubresource())
+	}
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.Drift.WithLabelValues(driftRoleBinding)))
+}
+
+func Test_Controller_syncHandler_SpecChanged_UpdatesObservedGeneration(t *testing.T) {
+	// SETUP
+	namespaceName := prefix1 + "-" + tenantID1
+	tenant := newPreparedTenant(namespaceName)
+	tenant.SetGeneration(2)
+	tenant.Status.ObservedGeneration = 1
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		fake.Namespace(namespaceName),
+		&v1beta1.RoleBinding{ObjectMeta: fake.ObjectMeta(defaultTenantRoleName, namespaceName)},
+		tenant,
+	)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	tenant, err = cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, int64(2), tenant.Status.ObservedGeneration)
+	assert.Equal(t, v1.ConditionTrue, getReadyCondition(tenant).Status)
+	assert.Equal(t, "Tenant namespace successfully prepared", tenant.Status.Message)
+}
+
+func Test_Controller_syncHandler_RoleBindingDeleted_RecreatesRoleBinding(t *testing.T) {
+	// SETUP
+	namespaceName := prefix1 + "-" + tenantID1
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		fake.Namespace(namespaceName),
+		newPreparedTenant(namespaceName),
+	)
+	metrics := NewMetrics().(*metrics)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), metrics, logging.NewNop(), k8s.NewClusterScope())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	_, err = cf.RbacV1beta1().RoleBindings(namespaceName).Get(defaultTenantRoleName, optGet)
+	assert.NilError(t, err)
+	tenant, err := cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, steward.TenantResultSuccess, tenant.Status.Result)
+	assert.Equal(t, "Tenant namespace repaired, recreated rolebinding", tenant.Status.Message)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Drift.WithLabelValues(driftRoleBinding)))
+}
+
+func Test_Controller_syncHandler_NamespaceDeleted_RecreatesNamespace(t *testing.T) {
+	// SETUP
+	namespaceName := prefix1 + "-" + tenantID1
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		newPreparedTenant(namespaceName),
+	)
+	metrics := NewMetrics().(*metrics)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), metrics, logging.NewNop(), k8s.NewClusterScope())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	assertTenant(t, cf, ns1, tenantID1, expect{
+		result:          steward.TenantResultSuccess,
+		message:         "Tenant namespace repaired, recreated namespace",
+		prefix:          prefix1,
+		namespaceExists: true,
+	})
+	_, err = cf.RbacV1beta1().RoleBindings(namespaceName).Get(defaultTenantRoleName, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Drift.WithLabelValues(driftNamespace)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.Drift.WithLabelValues(driftRoleBinding)))
+}
+
+func Test_Controller_updateProgress_DoesNotMoveBack(t *testing.T) {
+	// SETUP
+	cf := fake.NewClientFactory(newTenantWithStatus(steward.TenantStatus{Progress: steward.TenantProgressAddRoleBinding}))
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+	tenant, err := controller.fetcher.ByKey(tenantKey(ns1, tenantID1))
+	assert.NilError(t, err)
+	actions := len(cf.StewardClientset().Actions())
+
+	// EXERCISE
+	result, err := controller.updateProgress(controller.logger, tenant, steward.TenantProgressCreateNamespace)
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.Equal(t, steward.TenantProgressAddRoleBinding, result.Status.Progress)
+	assert.Equal(t, actions, len(cf.StewardClientset().Actions()))
+}
+
 func TestFullWorkflow(t *testing.T) {
 
 	const clientNamespace = "client1"
@@ -480,7 +852,7 @@ func fakeServiceAccount() *v1.ServiceAccount {
 func startController(t *testing.T, cf *fake.ClientFactory) (chan struct{}, *Controller) {
 	stopCh := make(chan struct{}, 0)
 	metrics := NewMetrics()
-	controller := NewController(cf, k8s.NewTenantFetcher(cf), metrics)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), metrics, logging.NewNop(), k8s.NewClusterScope())
 	cf.StewardInformerFactory().Start(stopCh)
 	go start(t, controller, stopCh)
 	cf.Sleep("Wait for controller")
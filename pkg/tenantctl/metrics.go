@@ -1,21 +1,19 @@
 package tenantctl
 
 import (
-	"log"
-	"net/http"
-
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Metrics provides metrics
 type Metrics interface {
 	SetTenantNumber(float64)
-	StartServer()
+	CountDrift(resource string)
+	Register(prometheus.Registerer) error
 }
 
 type metrics struct {
 	TenantCount prometheus.Gauge
+	Drift       *prometheus.CounterVec
 }
 
 // NewMetrics create metrics
@@ -25,24 +23,35 @@ func NewMetrics() Metrics {
 			Name: "steward_tenant_total_number",
 			Help: "total number of tenants",
 		}),
+		Drift: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "steward_tenant_drift_total_count",
+			Help: "resources of prepared tenants found missing and recreated by resource type",
+		},
+			[]string{"resource"}),
 	}
 }
 
-// StartServer registers metrics and start http listener
-func (metrics *metrics) StartServer() {
-	prometheus.MustRegister(metrics.TenantCount)
-	go provideMetrics()
-}
-
-func provideMetrics() {
-	http.Handle("/metrics", promhttp.Handler())
-	err := http.ListenAndServe(":9090", nil)
-	if err != nil {
-		log.Fatalf("Failed to start metrics server for tenant controller:%v", err)
+// Register registers the metrics at the given registry
+func (metrics *metrics) Register(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		metrics.TenantCount,
+		metrics.Drift,
 	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // SetTenantNumber sets the number of tenants
 func (metrics *metrics) SetTenantNumber(count float64) {
 	metrics.TenantCount.Set(count)
 }
+
+// CountDrift counts a resource of a prepared tenant which was found
+// missing and has been recreated
+func (metrics *metrics) CountDrift(resource string) {
+	metrics.Drift.With(prometheus.Labels{"resource": resource}).Inc()
+}
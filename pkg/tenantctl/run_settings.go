@@ -0,0 +1,96 @@
+package tenantctl
+
+import (
+	"encoding/json"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// validateRunSettings returns an error if the given run settings of a
+// tenant are invalid.
+func validateRunSettings(settings *api.TenantRunSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.MaxConcurrent < 0 {
+		return errors.Errorf("spec.runs.maxConcurrent must not be negative: %d", settings.MaxConcurrent)
+	}
+	if settings.DefaultTimeout != nil && settings.DefaultTimeout.Duration <= 0 {
+		return errors.Errorf("spec.runs.defaultTimeout must be positive: %s", settings.DefaultTimeout.Duration)
+	}
+	if settings.MaxTimeout != nil && settings.MaxTimeout.Duration <= 0 {
+		return errors.Errorf("spec.runs.maxTimeout must be positive: %s", settings.MaxTimeout.Duration)
+	}
+	if settings.DefaultTimeout != nil && settings.MaxTimeout != nil && settings.DefaultTimeout.Duration > settings.MaxTimeout.Duration {
+		return errors.Errorf("spec.runs.defaultTimeout must not exceed spec.runs.maxTimeout: %s > %s",
+			settings.DefaultTimeout.Duration, settings.MaxTimeout.Duration)
+	}
+	if resources := settings.DefaultResources; resources != nil {
+		for name, request := range resources.Requests {
+			if limit, hasLimit := resources.Limits[name]; hasLimit && request.Cmp(limit) > 0 {
+				return errors.Errorf("spec.runs.defaultResources.requests.%s must not exceed the limit: %s > %s",
+					name, request.String(), limit.String())
+			}
+		}
+	}
+	return nil
+}
+
+// applyNamespaceAnnotations ensures the annotations of the tenant namespace
+// the run controller reads from: the client namespace and the run settings
+// of the tenant. The client namespace annotation is set here as well because
+// tenant namespaces created by older versions lack it. The run settings
+// annotation is removed if the tenant has no run settings. The namespace is
+// only updated if an annotation changes.
+func (c *Controller) applyNamespaceAnnotations(logger *zap.SugaredLogger, tenant *api.Tenant) error {
+	runSettings := ""
+	if settings := tenant.Spec.Runs; settings != nil {
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return errors.WithMessage(err, "Could not serialize run settings")
+		}
+		runSettings = string(data)
+	}
+	expected := map[string]string{
+		api.AnnotationClientNamespace:   tenant.GetNamespace(),
+		api.AnnotationTenantRunSettings: runSettings,
+	}
+	client := c.factory.CoreV1().Namespaces()
+	name := tenant.Status.TenantNamespaceName
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		namespace, err := client.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		annotations := namespace.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		changed := false
+		for key, value := range expected {
+			current, hasKey := annotations[key]
+			if current == value && hasKey == (value != "") {
+				continue
+			}
+			if value == "" {
+				delete(annotations, key)
+			} else {
+				annotations[key] = value
+			}
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		namespace.SetAnnotations(annotations)
+		if _, err = client.Update(namespace); err == nil {
+			logger.Infow("Updated annotations of tenant namespace", "runSettings", runSettings)
+		}
+		return err
+	})
+	return errors.WithMessagef(err, "Could not update annotations of namespace %s", name)
+}
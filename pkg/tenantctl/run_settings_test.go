@@ -0,0 +1,159 @@
+package tenantctl
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	k8s "github.com/SAP/stewardci-core/pkg/k8s"
+	fake "github.com/SAP/stewardci-core/pkg/k8s/fake"
+	logging "github.com/SAP/stewardci-core/pkg/logging"
+	assert "gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	v1beta1 "k8s.io/api/rbac/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_validateRunSettings(t *testing.T) {
+	hour := &metav1.Duration{Duration: time.Hour}
+	minute := &metav1.Duration{Duration: time.Minute}
+	for _, tc := range []struct {
+		name          string
+		settings      *api.TenantRunSettings
+		expectedError string
+	}{
+		{"Nil", nil, ""},
+		{"Valid", &api.TenantRunSettings{
+			MaxConcurrent:  3,
+			DefaultTimeout: minute,
+			MaxTimeout:     hour,
+			DefaultResources: &v1.ResourceRequirements{
+				Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")},
+				Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi"), v1.ResourceCPU: resource.MustParse("1")},
+			},
+		}, ""},
+		{"NegativeMaxConcurrent", &api.TenantRunSettings{MaxConcurrent: -1}, "spec.runs.maxConcurrent must not be negative: -1"},
+		{"ZeroDefaultTimeout", &api.TenantRunSettings{DefaultTimeout: &metav1.Duration{}}, "spec.runs.defaultTimeout must be positive: 0s"},
+		{"NegativeMaxTimeout", &api.TenantRunSettings{MaxTimeout: &metav1.Duration{Duration: -time.Minute}}, "spec.runs.maxTimeout must be positive: -1m0s"},
+		{"DefaultTimeoutExceedsMax", &api.TenantRunSettings{DefaultTimeout: hour, MaxTimeout: minute},
+			"spec.runs.defaultTimeout must not exceed spec.runs.maxTimeout: 1h0m0s > 1m0s"},
+		{"RequestExceedsLimit", &api.TenantRunSettings{DefaultResources: &v1.ResourceRequirements{
+			Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+		}}, "spec.runs.defaultResources.requests.cpu must not exceed the limit: 2 > 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// EXERCISE
+			err := validateRunSettings(tc.settings)
+
+			// VERIFY
+			if tc.expectedError == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Error(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func newPreparedTenantWithRunSettings(t *testing.T, settings *api.TenantRunSettings, namespaceAnnotations map[string]string) (*fake.ClientFactory, *Controller, string) {
+	namespaceName := prefix1 + "-" + tenantID1
+	tenant := newPreparedTenant(namespaceName)
+	tenant.Spec.Runs = settings
+	cf := fake.NewClientFactory(
+		newClientNamespace(),
+		fakeServiceAccount(),
+		fakeClusterRole(),
+		fake.NamespaceWithAnnotations(namespaceName, namespaceAnnotations),
+		&v1beta1.RoleBinding{ObjectMeta: fake.ObjectMeta(defaultTenantRoleName, namespaceName)},
+		tenant,
+	)
+	controller := NewController(cf, k8s.NewTenantFetcher(cf), NewMetrics(), logging.NewNop(), k8s.NewClusterScope())
+	return cf, controller, namespaceName
+}
+
+func Test_Controller_syncHandler_RunSettings_StoredAtNamespace(t *testing.T) {
+	// SETUP
+	settings := &api.TenantRunSettings{MaxConcurrent: 2, MaxTimeout: &metav1.Duration{Duration: 2 * time.Hour}}
+	cf, controller, namespaceName := newPreparedTenantWithRunSettings(t, settings, map[string]string{})
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	namespace, err := cf.CoreV1().Namespaces().Get(namespaceName, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"maxConcurrent":2,"maxTimeout":"2h0m0s"}`, namespace.GetAnnotations()[api.AnnotationTenantRunSettings])
+}
+
+func Test_Controller_syncHandler_RunSettings_Removed_RemovesAnnotation(t *testing.T) {
+	// SETUP
+	cf, controller, namespaceName := newPreparedTenantWithRunSettings(t, nil, map[string]string{
+		api.AnnotationTenantRunSettings: `{"maxConcurrent":2}`,
+	})
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	namespace, err := cf.CoreV1().Namespaces().Get(namespaceName, optGet)
+	assert.NilError(t, err)
+	_, hasKey := namespace.GetAnnotations()[api.AnnotationTenantRunSettings]
+	assert.Assert(t, !hasKey)
+}
+
+func Test_Controller_syncHandler_RunSettings_Unchanged_DoesNotUpdateNamespace(t *testing.T) {
+	// SETUP
+	cf, controller, _ := newPreparedTenantWithRunSettings(t, &api.TenantRunSettings{MaxConcurrent: 2}, map[string]string{
+		api.AnnotationClientNamespace:   ns1,
+		api.AnnotationTenantRunSettings: `{"maxConcurrent":2}`,
+	})
+	actions := len(cf.KubernetesClientset().Actions())
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	for _, action := range cf.KubernetesClientset().Actions()[actions:] {
+		assert.Assert(t, action.GetVerb() != "update", "unexpected update of %s", action.GetResource().Resource)
+	}
+}
+
+func Test_Controller_syncHandler_ClientNamespaceAnnotationMissing_AddsAnnotation(t *testing.T) {
+	// SETUP
+	cf, controller, namespaceName := newPreparedTenantWithRunSettings(t, nil, map[string]string{})
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.NilError(t, err)
+	namespace, err := cf.CoreV1().Namespaces().Get(namespaceName, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, ns1, namespace.GetAnnotations()[api.AnnotationClientNamespace])
+}
+
+func Test_Controller_syncHandler_RunSettings_Invalid_ContentError(t *testing.T) {
+	// SETUP
+	cf, controller, namespaceName := newPreparedTenantWithRunSettings(t, &api.TenantRunSettings{MaxConcurrent: -1}, map[string]string{
+		api.AnnotationTenantRunSettings: `{"maxConcurrent":2}`,
+	})
+
+	// EXERCISE
+	err := controller.syncHandler(tenantKey(ns1, tenantID1))
+
+	// VERIFY
+	assert.Error(t, err, "spec.runs.maxConcurrent must not be negative: -1")
+	tenant, err := cf.StewardV1alpha1().Tenants(ns1).Get(tenantID1, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, v1.ConditionFalse, getReadyCondition(tenant).Status)
+	assert.Equal(t, api.TenantReasonContentError, getReadyCondition(tenant).Reason)
+	// the last valid settings are kept
+	namespace, err := cf.CoreV1().Namespaces().Get(namespaceName, optGet)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"maxConcurrent":2}`, namespace.GetAnnotations()[api.AnnotationTenantRunSettings])
+}
@@ -0,0 +1,213 @@
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracerName is the name of the tracer creating all spans of Steward.
+const tracerName = "github.com/SAP/stewardci-core"
+
+// Attribute keys of spans
+const (
+	KeyPipelineRun     = attribute.Key("steward.pipelinerun")
+	KeyTenant          = attribute.Key("steward.tenant")
+	KeyState           = attribute.Key("steward.state")
+	KeyRunNamespace    = attribute.Key("steward.run.namespace")
+	KeyTenantNamespace = attribute.Key("steward.tenant.namespace")
+	KeyNamespace       = attribute.Key("k8s.namespace.name")
+	KeyName            = attribute.Key("k8s.object.name")
+)
+
+// Config is the tracing configuration.
+type Config struct {
+	// Endpoint is the address ("<host>:<port>") of the OTLP gRPC receiver
+	// spans are exported to. Tracing is disabled if empty.
+	Endpoint string
+	// Insecure defines whether the connection to the endpoint is not
+	// secured with TLS
+	Insecure bool
+	// SampleRatio is the ratio of pipeline runs and tenant reconciliations
+	// to be traced, between 0 and 1
+	SampleRatio float64
+}
+
+// NewConfig returns the default tracing configuration with tracing
+// disabled.
+func NewConfig() *Config {
+	return &Config{SampleRatio: 1}
+}
+
+// AddFlags adds command line flags for the configuration to the given
+// flag set.
+func (c *Config) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&c.Endpoint, "tracing-endpoint", c.Endpoint, "address (host:port) of the OTLP gRPC receiver to export traces to, tracing is disabled if empty")
+	flagSet.BoolVar(&c.Insecure, "tracing-insecure", c.Insecure, "connect to the OTLP receiver without TLS")
+	flagSet.Float64Var(&c.SampleRatio, "tracing-sample-ratio", c.SampleRatio, "ratio of pipeline runs and tenant reconciliations to be traced, between 0 and 1")
+}
+
+// Setup configures the global tracer provider to export spans of the given
+// service according to the configuration. Export errors are logged to the
+// given logger. The returned function flushes and stops the export and
+// must be called before the process exits.
+// If tracing is disabled, spans are not recorded.
+func Setup(config *Config, serviceName string, logger *zap.SugaredLogger) (func(context.Context) error, error) {
+	if config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid tracing sample ratio %v: must be between 0 and 1", config.SampleRatio)
+	}
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	// the exporter connects in the background, so that an unavailable
+	// receiver does not prevent the controller from starting
+	exporter, err := otlptracegrpc.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(linkBased{root: sdktrace.TraceIDRatioBased(config.SampleRatio)})),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warnw("Failed to export traces", "error", err)
+	}))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a new span with the given name as child of the span in the
+// given context, if any.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartLinked starts a new trace with a span with the given name. The
+// span links to the span with the given W3C trace context
+// ("traceparent"), if valid, and is sampled like it.
+func StartLinked(traceParent string, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	options := []trace.SpanStartOption{trace.WithNewRoot(), trace.WithAttributes(attributes...)}
+	linked := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(),
+		&traceParentCarrier{value: traceParent}))
+	if linked.IsValid() {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: linked}))
+	}
+	return otel.Tracer(tracerName).Start(context.Background(), name, options...)
+}
+
+// End ends the given span and records the given error, if not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace of the span in the given context,
+// or an empty string if the span is not sampled.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// TraceParent returns the W3C trace context ("traceparent") of the span in
+// the given context, or an empty string if there is no span. Spans
+// started with StartLinked for the result are sampled like this span.
+func TraceParent(ctx context.Context) string {
+	carrier := &traceParentCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.value
+}
+
+// Annotations returns the annotations of a pipeline run storing the ID of
+// the trace of the span in the given context, or nil if the span is not
+// sampled.
+func Annotations(ctx context.Context) map[string]string {
+	traceID := TraceID(ctx)
+	if traceID == "" {
+		return nil
+	}
+	return map[string]string{api.AnnotationTraceID: traceID}
+}
+
+// traceParentCarrier holds the W3C trace context header. Other headers,
+// e.g. the trace state, are not stored.
+type traceParentCarrier struct {
+	value string
+}
+
+var _ propagation.TextMapCarrier = &traceParentCarrier{}
+
+const traceParentHeader = "traceparent"
+
+// Get implements propagation.TextMapCarrier
+func (c *traceParentCarrier) Get(key string) string {
+	if key != traceParentHeader {
+		return ""
+	}
+	return c.value
+}
+
+// Set implements propagation.TextMapCarrier
+func (c *traceParentCarrier) Set(key string, value string) {
+	if key == traceParentHeader {
+		c.value = value
+	}
+}
+
+// Keys implements propagation.TextMapCarrier
+func (c *traceParentCarrier) Keys() []string {
+	return []string{traceParentHeader}
+}
+
+// linkBased samples spans without parent like the spans they link to, so
+// that all reconciliations of a pipeline run are either sampled or not.
+// Spans without links are sampled by the root sampler.
+type linkBased struct {
+	root sdktrace.Sampler
+}
+
+var _ sdktrace.Sampler = linkBased{}
+
+// ShouldSample implements sdktrace.Sampler
+func (s linkBased) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if len(parameters.Links) == 0 {
+		return s.root.ShouldSample(parameters)
+	}
+	decision := sdktrace.Drop
+	for _, link := range parameters.Links {
+		if link.SpanContext.IsSampled() {
+			decision = sdktrace.RecordAndSample
+		}
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(parameters.ParentContext).TraceState(),
+	}
+}
+
+// Description implements sdktrace.Sampler
+func (s linkBased) Description() string {
+	return fmt.Sprintf("LinkBased{root:%s}", s.root.Description())
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	api "github.com/SAP/stewardci-core/pkg/apis/steward/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"gotest.tools/assert"
+)
+
+// recordSpans installs a tracer provider recording all spans until the
+// test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func Test_Annotations_NotRecorded(t *testing.T) {
+	// SETUP
+	ctx, span := Start(context.Background(), "span1")
+	defer span.End()
+
+	// EXERCISE
+	result := Annotations(ctx)
+
+	// VERIFY
+	assert.Assert(t, result == nil)
+	assert.Equal(t, "", TraceID(ctx))
+	assert.Equal(t, "", TraceParent(ctx))
+}
+
+func Test_StartLinked_LinksToTraceParent(t *testing.T) {
+	// SETUP
+	recorder := recordSpans(t)
+	ctx, span1 := Start(context.Background(), "span1")
+	annotations := Annotations(ctx)
+	traceParent := TraceParent(ctx)
+	span1.End()
+
+	// EXERCISE
+	ctx, span2 := StartLinked(traceParent, "span2")
+	span2.End()
+
+	// VERIFY
+	spanContext1 := span1.SpanContext()
+	assert.DeepEqual(t, map[string]string{api.AnnotationTraceID: spanContext1.TraceID().String()}, annotations)
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", spanContext1.TraceID(), spanContext1.SpanID()), traceParent)
+	assert.Assert(t, spanContext1.TraceID().String() != TraceID(ctx))
+	ended := recorder.Ended()
+	assert.Equal(t, 2, len(ended))
+	assert.Assert(t, !ended[1].Parent().IsValid())
+	assert.Equal(t, 1, len(ended[1].Links()))
+	assert.Equal(t, spanContext1.TraceID(), ended[1].Links()[0].SpanContext.TraceID())
+	assert.Equal(t, spanContext1.SpanID(), ended[1].Links()[0].SpanContext.SpanID())
+}
+
+func Test_StartLinked_InvalidTraceParent(t *testing.T) {
+	// SETUP
+	recorder := recordSpans(t)
+
+	// EXERCISE
+	ctx, span := StartLinked("foo", "span1")
+	span.End()
+
+	// VERIFY
+	assert.Equal(t, span.SpanContext().TraceID().String(), TraceID(ctx))
+	ended := recorder.Ended()
+	assert.Equal(t, 1, len(ended))
+	assert.Assert(t, !ended[0].Parent().IsValid())
+	assert.Equal(t, 0, len(ended[0].Links()))
+}
+
+func Test_StartLinked_SampledLikeLinkedSpan(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		first, next sdktrace.Sampler
+		sampled     bool
+	}{
+		{name: "Sampled", first: sdktrace.AlwaysSample(), next: sdktrace.NeverSample(), sampled: true},
+		{name: "NotSampled", first: sdktrace.NeverSample(), next: sdktrace.AlwaysSample(), sampled: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// SETUP
+			previous := otel.GetTracerProvider()
+			defer otel.SetTracerProvider(previous)
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.ParentBased(linkBased{root: test.first}))))
+			ctx, span1 := Start(context.Background(), "span1")
+			span1.End()
+			traceParent := TraceParent(ctx)
+			// the root sampler would decide contrarily
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.ParentBased(linkBased{root: test.next}))))
+
+			// EXERCISE
+			ctx, span2 := StartLinked(traceParent, "span2")
+			span2.End()
+
+			// VERIFY
+			assert.Assert(t, traceParent != "")
+			assert.Equal(t, test.sampled, span2.SpanContext().IsSampled())
+			assert.Equal(t, test.sampled, TraceID(ctx) != "")
+		})
+	}
+}
+
+func Test_End_RecordsError(t *testing.T) {
+	// SETUP
+	recorder := recordSpans(t)
+	_, span := Start(context.Background(), "span1", KeyPipelineRun.String("ns1/run1"))
+
+	// EXERCISE
+	End(span, fmt.Errorf("error1"))
+
+	// VERIFY
+	ended := recorder.Ended()
+	assert.Equal(t, 1, len(ended))
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Equal(t, "error1", ended[0].Status().Description)
+	assert.Equal(t, 1, len(ended[0].Events()))
+	assert.Equal(t, KeyPipelineRun.String("ns1/run1"), ended[0].Attributes()[0])
+}
+
+func Test_Setup_Disabled(t *testing.T) {
+	// SETUP
+	previous := otel.GetTracerProvider()
+
+	// EXERCISE
+	shutdown, err := Setup(NewConfig(), "service1", zap.NewNop().Sugar())
+
+	// VERIFY
+	assert.NilError(t, err)
+	assert.NilError(t, shutdown(context.Background()))
+	assert.Equal(t, previous, otel.GetTracerProvider())
+}
+
+func Test_Setup_InvalidSampleRatio(t *testing.T) {
+	// EXERCISE
+	_, err := Setup(&Config{Endpoint: "localhost:4317", SampleRatio: 1.5}, "service1", zap.NewNop().Sugar())
+
+	// VERIFY
+	assert.Error(t, err, "invalid tracing sample ratio 1.5: must be between 0 and 1")
+}